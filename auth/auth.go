@@ -0,0 +1,442 @@
+// sermersys/auth/auth.go
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Роли ключей
+const (
+	RoleRead = "read" // скачивание результатов и отчёты
+	RoleRun  = "run"  // всё, что доступно read, и запуск анализов
+)
+
+// tokenPrefix - префикс выдаваемых ключей, упрощает их поиск в логах и конфигурациях
+const tokenPrefix = "smk_"
+
+// usageDays - сколько последних дней хранится статистика использования
+const usageDays = 31
+
+var (
+	ErrUnknownKey    = errors.New("неизвестный ключ API")
+	ErrRevoked       = errors.New("ключ API отозван")
+	ErrForbidden     = errors.New("недостаточно прав для операции")
+	ErrRateLimited   = errors.New("превышен лимит запросов")
+	ErrQuotaExceeded = errors.New("исчерпана дневная квота анализов")
+)
+
+// Key - ключ API. Сам ключ не хранится, только его SHA-256.
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"hash"`
+	Prefix     string     `json:"prefix"` // первые символы ключа для отображения
+	Role       string     `json:"role"`
+	Workspace  string     `json:"workspace,omitempty"` // рабочее пространство; пусто - пространство по умолчанию
+	RatePerMin float64    `json:"rate_per_min"`        // пополнение корзины токенов, запросов в минуту (0 - без ограничения)
+	Burst      int        `json:"burst"`               // ёмкость корзины токенов
+	DailyQuota int        `json:"daily_quota"`         // анализов в сутки по UTC (0 - без ограничения)
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Usage      Usage      `json:"usage"`
+}
+
+// Usage - статистика использования ключа
+type Usage struct {
+	Requests int64               `json:"requests"`
+	Analyses int64               `json:"analyses"`
+	LastUsed *time.Time          `json:"last_used,omitempty"`
+	Days     map[string]DayUsage `json:"days,omitempty"` // по датам UTC в формате 2006-01-02
+}
+
+// DayUsage - использование ключа за сутки
+type DayUsage struct {
+	Requests int64 `json:"requests"`
+	Analyses int64 `json:"analyses"`
+}
+
+// Allows сообщает, разрешена ли ключу операция, требующая роли role
+func (k *Key) Allows(role string) bool {
+	return k.Role == RoleRun || k.Role == role
+}
+
+// Revoked сообщает, отозван ли ключ
+func (k *Key) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// CreateOptions - параметры нового ключа
+type CreateOptions struct {
+	Name       string
+	Role       string
+	Workspace  string
+	RatePerMin float64
+	Burst      int
+	DailyQuota int
+}
+
+// Store - хранилище ключей в JSON-файле с ограничением частоты запросов в памяти
+type Store struct {
+	path string
+	now  func() time.Time
+
+	mu      sync.Mutex
+	keys    []*Key
+	byHash  map[string]*Key
+	buckets map[string]*bucket
+	modTime time.Time // время изменения файла при последнем чтении или записи
+	dirty   bool      // статистика использования изменена в памяти и не записана (см. Flush)
+}
+
+// Open загружает хранилище из файла; отсутствующий файл означает пустое хранилище
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		now:     time.Now,
+		byHash:  make(map[string]*Key),
+		buckets: make(map[string]*bucket),
+	}
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Check проверяет, что файл ключей доступен и читается (для проверки готовности сервера)
+func (s *Store) Check() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := os.Stat(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("файл ключей недоступен: %v", err)
+	}
+	return s.refreshLocked()
+}
+
+// loadLocked перечитывает файл ключей; вызывается под s.mu (или до начала использования)
+func (s *Store) loadLocked() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла ключей: %v", err)
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла ключей: %v", err)
+	}
+	var keys []*Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("ошибка парсинга файла ключей %s: %v", s.path, err)
+	}
+	if s.dirty {
+		// Статистику меняет только этот процесс: незаписанные счётчики переносятся в перечитанные ключи
+		usage := make(map[string]Usage, len(s.keys))
+		for _, k := range s.keys {
+			usage[k.ID] = k.Usage
+		}
+		for _, k := range keys {
+			if u, ok := usage[k.ID]; ok {
+				k.Usage = u
+			}
+		}
+	}
+	s.keys = keys
+	s.byHash = make(map[string]*Key, len(keys))
+	for _, k := range keys {
+		s.byHash[k.Hash] = k
+	}
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// refreshLocked перечитывает файл, если его изменил другой процесс
+// (например, команда keys revoke при работающем сервере); вызывается под s.mu
+func (s *Store) refreshLocked() error {
+	info, err := os.Stat(s.path)
+	if err != nil || info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+	return s.loadLocked()
+}
+
+// Create создаёт ключ и возвращает его значение. Значение показывается один раз и нигде не сохраняется.
+func (s *Store) Create(opts CreateOptions) (string, *Key, error) {
+	if opts.Role != RoleRead && opts.Role != RoleRun {
+		return "", nil, fmt.Errorf("неизвестная роль %q, допустимые: %s, %s", opts.Role, RoleRead, RoleRun)
+	}
+	if opts.RatePerMin < 0 || opts.Burst < 0 || opts.DailyQuota < 0 {
+		return "", nil, fmt.Errorf("лимиты не могут быть отрицательными")
+	}
+	if opts.RatePerMin > 0 && opts.Burst == 0 {
+		opts.Burst = max(int(opts.RatePerMin), 1)
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("ошибка генерации ключа: %v", err)
+	}
+	token := tokenPrefix + hex.EncodeToString(secret)
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("ошибка генерации ID: %v", err)
+	}
+
+	key := &Key{
+		ID:         hex.EncodeToString(id),
+		Name:       opts.Name,
+		Hash:       hashToken(token),
+		Prefix:     token[:len(tokenPrefix)+6],
+		Role:       opts.Role,
+		Workspace:  opts.Workspace,
+		RatePerMin: opts.RatePerMin,
+		Burst:      opts.Burst,
+		DailyQuota: opts.DailyQuota,
+		CreatedAt:  s.now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refreshLocked(); err != nil {
+		return "", nil, err
+	}
+	s.keys = append(s.keys, key)
+	s.byHash[key.Hash] = key
+	if err := s.saveLocked(); err != nil {
+		return "", nil, err
+	}
+	return token, key.clone(), nil
+}
+
+// List возвращает копии всех ключей, упорядоченные по дате создания
+func (s *Store) List() []*Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k.clone())
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+// Revoke отзывает ключ по ID
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refreshLocked(); err != nil {
+		return err
+	}
+	for _, k := range s.keys {
+		if k.ID != id {
+			continue
+		}
+		if k.Revoked() {
+			return nil
+		}
+		now := s.now().UTC()
+		k.RevokedAt = &now
+		return s.saveLocked()
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownKey, id)
+}
+
+// Empty сообщает, что в хранилище нет ни одного действующего ключа
+func (s *Store) Empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.keys {
+		if !k.Revoked() {
+			return false
+		}
+	}
+	return true
+}
+
+// Authorize проверяет ключ, роль и лимит частоты запросов и учитывает запрос в статистике.
+// Если analysis = true, проверяется, что дневная квота анализов не исчерпана; сами анализы
+// списываются ChargeAnalyses, когда запрос проверен и анализ принят.
+// При превышении лимита частоты вторым значением возвращается время до следующей попытки.
+// Статистика хранится в памяти и записывается в файл Flush.
+func (s *Store) Authorize(token, role string, analysis bool) (*Key, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refreshLocked(); err != nil {
+		return nil, 0, err
+	}
+
+	key, ok := s.byHash[hashToken(token)]
+	if !ok {
+		return nil, 0, ErrUnknownKey
+	}
+	if key.Revoked() {
+		return nil, 0, ErrRevoked
+	}
+	if !key.Allows(role) {
+		return nil, 0, ErrForbidden
+	}
+
+	now := s.now()
+	day := now.UTC().Format("2006-01-02")
+	if analysis && key.DailyQuota > 0 && key.Usage.Days[day].Analyses >= int64(key.DailyQuota) {
+		return nil, 0, ErrQuotaExceeded
+	}
+	if key.RatePerMin > 0 {
+		b, ok := s.buckets[key.ID]
+		if !ok {
+			b = newBucket(key.RatePerMin/60, float64(key.Burst), now)
+			s.buckets[key.ID] = b
+		}
+		if wait := b.take(now); wait > 0 {
+			return nil, wait, ErrRateLimited
+		}
+	}
+
+	key.Usage.record(now, day)
+	s.dirty = true
+	return key.clone(), 0, nil
+}
+
+// ChargeAnalyses списывает с дневной квоты ключа id n анализов (для пакета - по анализу на объект).
+// Если квоты не хватает, ничего не списывается и возвращается ErrQuotaExceeded.
+// Отрицательное n возвращает анализы в квоту, например если их не удалось поставить в очередь.
+func (s *Store) ChargeAnalyses(id string, n int) error {
+	if n == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refreshLocked(); err != nil {
+		return err
+	}
+	var key *Key
+	for _, k := range s.keys {
+		if k.ID == id {
+			key = k
+		}
+	}
+	if key == nil {
+		return ErrUnknownKey
+	}
+
+	day := s.now().UTC().Format("2006-01-02")
+	d := key.Usage.Days[day]
+	if n > 0 && key.DailyQuota > 0 && d.Analyses+int64(n) > int64(key.DailyQuota) {
+		return ErrQuotaExceeded
+	}
+	n = int(max(int64(n), -d.Analyses))
+	if key.Usage.Days == nil {
+		key.Usage.Days = make(map[string]DayUsage)
+	}
+	d.Analyses += int64(n)
+	key.Usage.Days[day] = d
+	key.Usage.Analyses += int64(n)
+	s.dirty = true
+	return nil
+}
+
+// Flush записывает в файл статистику использования, накопленную в памяти
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	if err := s.refreshLocked(); err != nil {
+		return err
+	}
+	return s.saveLocked()
+}
+
+// FlushEvery записывает статистику каждые interval до отмены ctx и ещё раз после неё
+func (s *Store) FlushEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				slog.Error("не удалось записать статистику ключей", "error", err)
+			}
+			return
+		}
+		if err := s.Flush(); err != nil {
+			slog.Warn("не удалось записать статистику ключей", "error", err)
+		}
+	}
+}
+
+func (u *Usage) record(now time.Time, day string) {
+	if u.Days == nil {
+		u.Days = make(map[string]DayUsage)
+	}
+	d := u.Days[day]
+	d.Requests++
+	u.Requests++
+	u.Days[day] = d
+	used := now.UTC()
+	u.LastUsed = &used
+
+	// Старые дни не нужны для квот и только увеличивают файл
+	oldest := now.UTC().AddDate(0, 0, -usageDays).Format("2006-01-02")
+	for date := range u.Days {
+		if date < oldest {
+			delete(u.Days, date)
+		}
+	}
+}
+
+// saveLocked записывает ключи в файл; вызывается под s.mu
+func (s *Store) saveLocked() error {
+	data, err := json.MarshalIndent(s.keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return fmt.Errorf("ошибка создания каталога файла ключей: %v", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("ошибка записи файла ключей: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("ошибка записи файла ключей: %v", err)
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	s.dirty = false
+	return nil
+}
+
+func (k *Key) clone() *Key {
+	c := *k
+	if k.Usage.Days != nil {
+		c.Usage.Days = make(map[string]DayUsage, len(k.Usage.Days))
+		for day, usage := range k.Usage.Days {
+			c.Usage.Days[day] = usage
+		}
+	}
+	return &c
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
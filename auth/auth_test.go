@@ -0,0 +1,134 @@
+// sermersys/auth/auth_test.go
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "api_keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestAuthorizeKeepsUsageInMemory(t *testing.T) {
+	s := newTestStore(t)
+	token, key, err := s.Create(CreateOptions{Name: "ci", Role: RoleRun})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := s.Authorize(token, RoleRead, false); err != nil {
+			t.Fatalf("Authorize: %v", err)
+		}
+	}
+	if after, _ := os.ReadFile(s.path); string(after) != string(before) {
+		t.Fatalf("Authorize записал файл ключей на каждый запрос")
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	reopened, err := Open(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.List()[0].Usage.Requests; got != 3 {
+		t.Errorf("после Flush в файле %d запросов ключа %s, ожидалось 3", got, key.ID)
+	}
+}
+
+func TestChargeAnalysesQuota(t *testing.T) {
+	s := newTestStore(t)
+	token, key, err := s.Create(CreateOptions{Name: "ci", Role: RoleRun, DailyQuota: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Authorize только проверяет квоту, не списывая её
+	for i := 0; i < 3; i++ {
+		if _, _, err := s.Authorize(token, RoleRun, true); err != nil {
+			t.Fatalf("Authorize до списания: %v", err)
+		}
+	}
+	if err := s.ChargeAnalyses(key.ID, 3); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("пакет больше квоты: %v, ожидалось ErrQuotaExceeded", err)
+	}
+	if err := s.ChargeAnalyses(key.ID, 2); err != nil {
+		t.Fatalf("ChargeAnalyses: %v", err)
+	}
+	if _, _, err := s.Authorize(token, RoleRun, true); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("квота исчерпана: %v, ожидалось ErrQuotaExceeded", err)
+	}
+	// Запросы без анализа квотой не ограничены
+	if _, _, err := s.Authorize(token, RoleRead, false); err != nil {
+		t.Fatalf("Authorize без анализа: %v", err)
+	}
+
+	// Возврат анализа, который не удалось поставить в очередь
+	if err := s.ChargeAnalyses(key.ID, -1); err != nil {
+		t.Fatalf("возврат в квоту: %v", err)
+	}
+	if _, _, err := s.Authorize(token, RoleRun, true); err != nil {
+		t.Fatalf("Authorize после возврата: %v", err)
+	}
+	if got := s.List()[0].Usage.Analyses; got != 1 {
+		t.Errorf("анализов %d, ожидался 1", got)
+	}
+}
+
+func TestRefreshKeepsUnflushedUsage(t *testing.T) {
+	server := newTestStore(t)
+	token, key, err := server.Create(CreateOptions{Name: "ci", Role: RoleRun})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := server.Authorize(token, RoleRead, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.ChargeAnalyses(key.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Другой процесс (команда keys) меняет файл, пока статистика сервера не записана
+	cli, err := Open(server.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := cli.Create(CreateOptions{Name: "other", Role: RoleRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.Revoke(other.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := server.Authorize(token, RoleRead, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(server.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := reopened.List()
+	if len(keys) != 2 || !keys[1].Revoked() {
+		t.Fatalf("изменения другого процесса потеряны: %+v", keys)
+	}
+	if u := keys[0].Usage; u.Requests != 2 || u.Analyses != 1 {
+		t.Errorf("статистика %+v, ожидалось 2 запроса и 1 анализ", u)
+	}
+}
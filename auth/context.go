@@ -0,0 +1,17 @@
+// sermersys/auth/context.go
+package auth
+
+import "context"
+
+type contextKey struct{}
+
+// WithKey возвращает контекст с ключом, которым авторизован запрос
+func WithKey(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext возвращает ключ запроса или nil, если авторизация выключена
+func FromContext(ctx context.Context) *Key {
+	key, _ := ctx.Value(contextKey{}).(*Key)
+	return key
+}
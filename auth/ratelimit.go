@@ -0,0 +1,32 @@
+// sermersys/auth/ratelimit.go
+package auth
+
+import (
+	"math"
+	"time"
+)
+
+// bucket - корзина токенов: пополняется со скоростью rate токенов в секунду до capacity
+type bucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newBucket(rate, capacity float64, now time.Time) *bucket {
+	return &bucket{rate: rate, capacity: capacity, tokens: capacity, last: now}
+}
+
+// take забирает токен; если токенов нет, возвращает время до появления следующего
+func (b *bucket) take(now time.Time) time.Duration {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration(math.Ceil((1 - b.tokens) / b.rate * float64(time.Second)))
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"sermersys/config"
+	"sermersys/export"
+	"sermersys/googlesearch"
+	"sermersys/i18n"
+	"sermersys/logging"
+	"sermersys/mailer"
+	"sermersys/mapsearchg"
+	"sermersys/model"
+	"sermersys/pipeline"
+	"sermersys/server"
+	"sermersys/workspace"
+)
+
+// =================== serve ===================
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var common commonFlags
+	common.register(fs)
+	fs.Parse(args)
+	cfg, err := common.load(nil)
+	if err != nil {
+		return err
+	}
+	srv, err := server.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer srv.Close()
+	// По SIGINT или SIGTERM сервер дожидается начатых запросов и анализов и только затем выходит
+	ctx, stop := signalContext()
+	defer stop()
+	return srv.ListenAndServe(ctx)
+}
+
+// =================== resolve ===================
+
+func runResolve(args []string) error {
+	fs := flag.NewFlagSet("resolve", flag.ExitOnError)
+	var common commonFlags
+	var object objectFlags
+	common.register(fs)
+	object.register(fs)
+	fs.Parse(args)
+	cfg, err := common.load(func(cfg *config.Config) {
+		cfg.Providers = config.Providers{Places: true}
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := object.request(cfg)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signalContext()
+	defer stop()
+	places, err := mapsearchg.SearchGooglePlacesContext(ctx, cfg, request)
+	if err != nil {
+		return err
+	}
+	return withOutput(&common, func(w io.Writer) error {
+		return writePlaces(w, common.format, places)
+	})
+}
+
+// =================== search ===================
+
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	var common commonFlags
+	var object objectFlags
+	common.register(fs)
+	object.register(fs)
+	fs.Parse(args)
+	cfg, err := common.load(func(cfg *config.Config) {
+		cfg.Providers.CSE = true
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := object.request(cfg)
+	if err != nil {
+		return err
+	}
+	// Без уточнения через mapsearchg ищем по названию и адресу как есть
+	searchRequest := pipeline.SearchRequest(request, model.Place{
+		Name:             request.ObjectName,
+		FormattedAddress: request.Address,
+	})
+	ctx, stop := signalContext()
+	defer stop()
+	report, err := googlesearch.FetchDataExplainContext(ctx, cfg, searchRequest)
+	if err != nil {
+		return err
+	}
+	return withOutput(&common, func(w io.Writer) error {
+		if common.format == "json" {
+			return writeJSON(w, report)
+		}
+		return writeListings(w, common.format, report.Results)
+	})
+}
+
+// =================== analyze ===================
+
+func runAnalyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	var common commonFlags
+	common.reports = true
+	var object objectFlags
+	common.register(fs)
+	object.register(fs)
+	emailTo := fs.String("email-to", "", "отправить PDF-отчёт на адреса через запятую (нужна настройка mail)")
+	fs.Parse(args)
+	cfg, err := common.load(nil)
+	if err != nil {
+		return err
+	}
+
+	request, err := object.request(cfg)
+	if err != nil {
+		return err
+	}
+	var to []string
+	if *emailTo != "" {
+		if !cfg.Mail.Enabled() {
+			return fmt.Errorf("для -email-to нужен SMTP-сервер (mail.host)")
+		}
+		if to, err = mailer.ParseRecipients(strings.Split(*emailTo, ",")); err != nil {
+			return fmt.Errorf("флаг -email-to: %v", err)
+		}
+	}
+	ctx, stop := signalContext()
+	defer stop()
+	result, err := pipeline.AnalyzeContext(ctx, cfg, request)
+	if err != nil {
+		return err
+	}
+	lang := i18n.Resolve(request.Locale, i18n.EN)
+	err = withOutput(&common, func(w io.Writer) error {
+		return writeResults(w, common.format, lang, []*pipeline.Result{result})
+	})
+	if err != nil || len(to) == 0 {
+		return err
+	}
+	return mailReport(ctx, cfg, lang, result, to)
+}
+
+// mailReport отправляет PDF-отчёт по результату анализа на адреса to
+func mailReport(ctx context.Context, cfg *config.Config, lang i18n.Lang, result *pipeline.Result, to []string) error {
+	var pdf bytes.Buffer
+	if err := export.WritePDF(&pdf, lang, result); err != nil {
+		return fmt.Errorf("ошибка построения PDF-отчёта: %v", err)
+	}
+	filename := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, result.RefinedHotelName) + ".pdf"
+	if err := mailer.Send(ctx, cfg.Mail, mailer.Report(lang, to, result.RefinedHotelName, result.RefinedAddress, filename, pdf.Bytes())); err != nil {
+		return fmt.Errorf("отчёт не отправлен: %v", err)
+	}
+	slog.Info("отчёт отправлен по почте", "recipients", len(to))
+	return nil
+}
+
+// =================== batch ===================
+
+// batchItem - результат анализа одного объекта из пакета
+type batchItem struct {
+	Request mapsearchg.RequestData `json:"request"`
+	Result  *pipeline.Result       `json:"result,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+func runBatch(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	var common commonFlags
+	common.reports = true
+	common.register(fs)
+	input := fs.String("input", "", "CSV (с заголовком object_name,address,city,country) или JSON Lines с запросами")
+	watched := fs.Bool("watched", false, "анализировать отслеживаемые объекты рабочего пространства вместо -input")
+	fs.Parse(args)
+	cfg, err := common.load(nil)
+	if err != nil {
+		return err
+	}
+	if (*input == "") == !*watched {
+		return fmt.Errorf("укажите либо -input, либо -watched")
+	}
+
+	var requests []mapsearchg.RequestData
+	if *watched {
+		requests = watchedRequests(common.workspace)
+	} else if requests, err = readBatchRequests(*input); err != nil {
+		return err
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	// В jsonl строки объекта выводятся сразу после его анализа, а не после всего пакета,
+	// поэтому файл можно читать, пока пакет выполняется
+	if common.format == "jsonl" {
+		return withOutput(&common, func(w io.Writer) error {
+			stream := export.NewJSONLWriter(w, "")
+			return analyzeBatch(ctx, cfg, requests, func(i int, item batchItem) error {
+				if item.Result == nil {
+					return stream.WriteError(i+1, item.Request, item.Error)
+				}
+				return stream.WriteResult(i+1, item.Request, item.Result)
+			})
+		})
+	}
+
+	var items []batchItem
+	analyzeBatch(ctx, cfg, requests, func(i int, item batchItem) error {
+		items = append(items, item)
+		return nil
+	})
+
+	return withOutput(&common, func(w io.Writer) error {
+		if common.format == "json" {
+			return writeJSON(w, items)
+		}
+		var results []*pipeline.Result
+		for _, item := range items {
+			if item.Result != nil {
+				results = append(results, item.Result)
+			}
+		}
+		return writeResults(w, common.format, i18n.EN, results)
+	})
+}
+
+// analyzeBatch анализирует объекты пакета по очереди и передаёт каждый результат в done.
+// Ошибка done прерывает пакет.
+func analyzeBatch(ctx context.Context, cfg *config.Config, requests []mapsearchg.RequestData, done func(i int, item batchItem) error) error {
+	for i, request := range requests {
+		// При Ctrl+C оставшиеся объекты не анализируются, уже готовые результаты выводятся
+		if ctx.Err() != nil {
+			slog.Warn("пакет прерван", "done", i, "total", len(requests))
+			return nil
+		}
+		slog.Info("анализ", "item", i+1, "total", len(requests), "object_name", request.ObjectName, "city", request.City)
+		item := batchItem{Request: request}
+		// Записи журнала о запросах к Google получают номер объекта в пакете
+		itemCtx := logging.With(ctx, "item", i+1)
+		result, err := pipeline.AnalyzeContext(itemCtx, cfg, request)
+		if err != nil {
+			slog.ErrorContext(itemCtx, "ошибка анализа", "object_name", request.ObjectName, "error", err)
+			item.Error = err.Error()
+		} else {
+			item.Result = result
+		}
+		if err := done(i, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBatchRequests читает запросы из CSV или JSON Lines (по расширению файла)
+func readBatchRequests(filename string) ([]mapsearchg.RequestData, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла запросов: %v", err)
+	}
+	defer file.Close()
+
+	var requests []mapsearchg.RequestData
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jsonl", ".ndjson", ".json":
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var request mapsearchg.RequestData
+			if err := json.Unmarshal([]byte(text), &request); err != nil {
+				return nil, fmt.Errorf("строка %d: неверный формат JSON: %v", line, err)
+			}
+			requests = append(requests, request)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("ошибка чтения файла запросов: %v", err)
+		}
+	default:
+		records, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения CSV: %v", err)
+		}
+		if len(records) < 2 {
+			return nil, nil
+		}
+		columns := make(map[string]int)
+		for i, name := range records[0] {
+			columns[strings.TrimSpace(strings.ToLower(name))] = i
+		}
+		get := func(record []string, name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		for _, record := range records[1:] {
+			threshold, _ := strconv.ParseFloat(get(record, "match_threshold"), 64)
+			requests = append(requests, mapsearchg.RequestData{
+				ObjectName:     get(record, "object_name"),
+				Address:        get(record, "address"),
+				City:           get(record, "city"),
+				Country:        get(record, "country"),
+				PlatformsFile:  get(record, "platforms_file"),
+				Language:       get(record, "language"),
+				Locale:         get(record, "locale"),
+				MatchThreshold: threshold,
+			})
+		}
+	}
+	return requests, nil
+}
+
+// watchedRequests строит запросы по отслеживаемым объектам рабочего пространства
+func watchedRequests(ws *workspace.Workspace) []mapsearchg.RequestData {
+	var requests []mapsearchg.RequestData
+	for _, p := range ws.Properties {
+		requests = append(requests, mapsearchg.RequestData{
+			ObjectName:         p.ObjectName,
+			Address:            p.Address,
+			City:               p.City,
+			Country:            p.Country,
+			PlatformsFile:      p.PlatformsFile,
+			QueryTemplatesFile: p.QueryTemplatesFile,
+			Language:           p.Language,
+		})
+	}
+	return requests
+}
+
+// =================== export ===================
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var common commonFlags
+	common.reports = true
+	common.register(fs)
+	input := fs.String("input", "", "JSON-файл, сохранённый командой analyze или batch с -format json")
+	locale := fs.String("locale", "en", "язык отчётов: ru, en, de")
+	fs.Parse(args)
+	// Экспорт не обращается к Google API, поэтому конфигурация не загружается
+	if err := common.checkFormat(); err != nil {
+		return err
+	}
+	if *input == "" {
+		return fmt.Errorf("флаг -input обязателен")
+	}
+	lang, ok := i18n.Parse(*locale)
+	if !ok {
+		return fmt.Errorf("неподдерживаемый язык -locale: %s", *locale)
+	}
+
+	results, err := readSavedResults(*input)
+	if err != nil {
+		return err
+	}
+	return withOutput(&common, func(w io.Writer) error {
+		return writeResults(w, common.format, lang, results)
+	})
+}
+
+// readSavedResults читает результат analyze (объект или массив) или batch (массив элементов с полем result)
+func readSavedResults(filename string) ([]*pipeline.Result, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+
+	var items []struct {
+		pipeline.Result
+		Nested *pipeline.Result `json:"result"`
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		var single pipeline.Result
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, fmt.Errorf("неверный формат JSON: %v", err)
+		}
+		return []*pipeline.Result{&single}, nil
+	}
+
+	var results []*pipeline.Result
+	for i := range items {
+		if items[i].Nested != nil {
+			results = append(results, items[i].Nested)
+		} else if items[i].RefinedHotelName != "" {
+			results = append(results, &items[i].Result)
+		}
+	}
+	return results, nil
+}
+
+// signalContext возвращает контекст, который отменяется по SIGINT или SIGTERM
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// withOutput открывает вывод, передаёт его в write и закрывает
+func withOutput(common *commonFlags, write func(w io.Writer) error) error {
+	w, closeOutput, err := common.openOutput()
+	if err != nil {
+		return err
+	}
+	if err := write(w); err != nil {
+		closeOutput()
+		return err
+	}
+	return closeOutput()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"sermersys/config"
+	"sermersys/i18n"
+	"sermersys/logging"
+	"sermersys/mapsearchg"
+	"sermersys/tracing"
+	"sermersys/workspace"
+)
+
+// commonFlags - флаги, общие для всех подкоманд: -config, -platforms и
+// остальные настройки конфигурации, а также формат и файл вывода
+type commonFlags struct {
+	config      *config.Flags
+	format      string
+	output      string
+	workspaceID string
+	workspace   *workspace.Workspace // заполняется в load
+	reports     bool                 // команда выводит результаты анализа: доступны и форматы отчётов
+}
+
+func (c *commonFlags) register(fs *flag.FlagSet) {
+	c.config = config.RegisterFlags(fs)
+	fs.StringVar(&c.workspaceID, "workspace", "", "рабочее пространство (ключи Google, каталоги и результаты клиента)")
+	fs.StringVar(&c.format, "format", "json", "формат вывода: "+strings.Join(c.formats(), ", "))
+	fs.StringVar(&c.output, "o", "", "файл для вывода (по умолчанию stdout)")
+}
+
+// load проверяет формат вывода и загружает конфигурацию (файл, окружение, флаги)
+// с настройками рабочего пространства -workspace.
+// adjust позволяет подкоманде сузить набор провайдеров перед проверкой.
+func (c *commonFlags) load(adjust func(*config.Config)) (*config.Config, error) {
+	if err := c.checkFormat(); err != nil {
+		return nil, err
+	}
+	cfg, err := c.config.Load()
+	if err != nil {
+		return nil, err
+	}
+	workspaces, err := workspace.Open(cfg.WorkspacesFile)
+	if err != nil {
+		return nil, err
+	}
+	if c.workspace, err = workspaces.Get(c.workspaceID); err != nil {
+		return nil, err
+	}
+	cfg = c.workspace.Config(cfg)
+	if adjust != nil {
+		adjust(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := logging.Setup(os.Stderr, cfg.Log.Level, cfg.Log.Format); err != nil {
+		return nil, err
+	}
+	if err := tracing.Setup(cfg.Tracing.Exporter, cfg.Tracing.Endpoint, cfg.Tracing.SampleRatio); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// formats возвращает форматы вывода, доступные команде
+func (c *commonFlags) formats() []string {
+	if c.reports {
+		return append(append([]string(nil), outputFormats...), reportFormats...)
+	}
+	return outputFormats
+}
+
+// checkFormat проверяет, что формат вывода поддерживается
+func (c *commonFlags) checkFormat() error {
+	for _, f := range c.formats() {
+		if f == c.format {
+			return nil
+		}
+	}
+	return fmt.Errorf("неизвестный формат %q, допустимые: %s", c.format, strings.Join(c.formats(), ", "))
+}
+
+// openOutput возвращает writer для вывода и функцию его закрытия
+func (c *commonFlags) openOutput() (io.Writer, func() error, error) {
+	if c.output == "" || c.output == "-" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	file, err := os.Create(c.output)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка создания файла вывода: %v", err)
+	}
+	return file, file.Close, nil
+}
+
+// objectFlags - описание объекта и параметры поиска
+type objectFlags struct {
+	name          string
+	address       string
+	city          string
+	country       string
+	language      string
+	templates     string
+	threshold     float64
+	transliterate bool
+	schemes       string
+	explain       bool
+	locale        string
+}
+
+func (o *objectFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.name, "name", "", "название объекта (обязательно)")
+	fs.StringVar(&o.address, "address", "", "адрес объекта")
+	fs.StringVar(&o.city, "city", "", "город (обязательно)")
+	fs.StringVar(&o.country, "country", "", "страна")
+	fs.StringVar(&o.language, "language", "", "язык для шаблонов запросов и стоп-слов")
+	fs.StringVar(&o.templates, "templates", "", "файл шаблонов поисковых запросов")
+	fs.Float64Var(&o.threshold, "threshold", 0, "порог совпадения заголовка (0..1)")
+	fs.BoolVar(&o.transliterate, "translit", false, "пробовать транслитерированные варианты названия")
+	fs.StringVar(&o.schemes, "translit-schemes", "", "схемы транслитерации через запятую: gost, iso9, icao")
+	fs.BoolVar(&o.explain, "explain", false, "включить разбор принятых и отклонённых результатов")
+	fs.StringVar(&o.locale, "locale", "", "язык шагов анализа и заголовков CSV: ru, en, de")
+}
+
+// request собирает запрос к mapsearchg
+func (o *objectFlags) request(cfg *config.Config) (mapsearchg.RequestData, error) {
+	if o.name == "" || o.city == "" {
+		return mapsearchg.RequestData{}, fmt.Errorf("флаги -name и -city обязательны")
+	}
+	if _, ok := i18n.Parse(o.locale); o.locale != "" && !ok {
+		return mapsearchg.RequestData{}, fmt.Errorf("неподдерживаемый язык -locale: %s", o.locale)
+	}
+	var schemes []string
+	if o.schemes != "" {
+		schemes = strings.Split(o.schemes, ",")
+	}
+	return mapsearchg.RequestData{
+		ObjectName:         o.name,
+		Address:            o.address,
+		City:               o.city,
+		Country:            o.country,
+		PlatformsFile:      cfg.PlatformsFile,
+		Language:           o.language,
+		QueryTemplatesFile: o.templates,
+		MatchThreshold:     o.threshold,
+		Transliterate:      o.transliterate,
+		TranslitSchemes:    schemes,
+		Explain:            o.explain,
+		Locale:             o.locale,
+	}, nil
+}
@@ -0,0 +1,159 @@
+// sermersys/cmd/sermersys/keys.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"sermersys/auth"
+	"sermersys/config"
+)
+
+// =================== keys ===================
+
+func runKeys(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("укажите действие: create, list или revoke")
+	}
+	switch args[0] {
+	case "create":
+		return runKeysCreate(args[1:])
+	case "list":
+		return runKeysList(args[1:])
+	case "revoke":
+		return runKeysRevoke(args[1:])
+	default:
+		return fmt.Errorf("неизвестное действие %q, допустимые: create, list, revoke", args[0])
+	}
+}
+
+// openKeys загружает конфигурацию и хранилище ключей. Ключи Google для работы с ключами API не нужны.
+func openKeys(flags *config.Flags) (*auth.Store, error) {
+	cfg, err := flags.Load()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Auth.KeysFile == "" {
+		return nil, fmt.Errorf("не задан auth.keys_file")
+	}
+	return auth.Open(cfg.Auth.KeysFile)
+}
+
+func runKeysCreate(args []string) error {
+	fs := flag.NewFlagSet("keys create", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	var opts auth.CreateOptions
+	fs.StringVar(&opts.Name, "name", "", "название ключа (обязательно)")
+	fs.StringVar(&opts.Role, "role", auth.RoleRead, "роль: read (результаты и отчёты) или run (также запуск анализов)")
+	fs.Float64Var(&opts.RatePerMin, "rate", 0, "запросов в минуту (0 - без ограничения)")
+	fs.IntVar(&opts.Burst, "burst", 0, "допустимый всплеск запросов (по умолчанию равен -rate)")
+	fs.IntVar(&opts.DailyQuota, "quota", 0, "анализов в сутки (0 - без ограничения)")
+	fs.StringVar(&opts.Workspace, "workspace", "", "рабочее пространство ключа (по умолчанию - общее)")
+	fs.Parse(args)
+	if opts.Name == "" {
+		return fmt.Errorf("флаг -name обязателен")
+	}
+
+	store, err := openKeys(flags)
+	if err != nil {
+		return err
+	}
+	if opts.Workspace != "" {
+		workspaces, err := openWorkspaces(flags)
+		if err != nil {
+			return err
+		}
+		if _, err := workspaces.Get(opts.Workspace); err != nil {
+			return err
+		}
+	}
+	token, key, err := store.Create(opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Ключ %s (%s) создан. Сохраните его - повторно он не показывается:\n", key.ID, key.Role)
+	fmt.Println(token)
+	return nil
+}
+
+func runKeysList(args []string) error {
+	fs := flag.NewFlagSet("keys list", flag.ExitOnError)
+	var common commonFlags
+	common.register(fs)
+	fs.Parse(args)
+	if err := common.checkFormat(); err != nil {
+		return err
+	}
+
+	store, err := openKeys(common.config)
+	if err != nil {
+		return err
+	}
+	keys := store.List()
+	return withOutput(&common, func(w io.Writer) error {
+		return writeKeys(w, common.format, keys)
+	})
+}
+
+func runKeysRevoke(args []string) error {
+	fs := flag.NewFlagSet("keys revoke", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	id := fs.String("id", "", "ID ключа (обязательно)")
+	fs.Parse(args)
+	if *id == "" {
+		return fmt.Errorf("флаг -id обязателен")
+	}
+
+	store, err := openKeys(flags)
+	if err != nil {
+		return err
+	}
+	if err := store.Revoke(*id); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Ключ %s отозван\n", *id)
+	return nil
+}
+
+// writeKeys выводит ключи без хэшей; статистика за сутки - по текущей дате UTC
+func writeKeys(w io.Writer, format string, keys []*auth.Key) error {
+	if format == "json" {
+		for _, key := range keys {
+			key.Hash = ""
+		}
+		return writeJSON(w, keys)
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	header := []string{"id", "name", "prefix", "role", "workspace", "rate_per_min", "burst", "daily_quota", "analyses_today", "requests", "analyses", "last_used", "status"}
+	var rows [][]string
+	for _, key := range keys {
+		status := "active"
+		if key.Revoked() {
+			status = "revoked " + key.RevokedAt.Format(time.RFC3339)
+		}
+		lastUsed := ""
+		if key.Usage.LastUsed != nil {
+			lastUsed = key.Usage.LastUsed.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			key.ID,
+			key.Name,
+			key.Prefix + "…",
+			key.Role,
+			key.Workspace,
+			strconv.FormatFloat(key.RatePerMin, 'f', -1, 64),
+			strconv.Itoa(key.Burst),
+			strconv.Itoa(key.DailyQuota),
+			strconv.FormatInt(key.Usage.Days[today].Analyses, 10),
+			strconv.FormatInt(key.Usage.Requests, 10),
+			strconv.FormatInt(key.Usage.Analyses, 10),
+			lastUsed,
+			status,
+		})
+	}
+	return writeRows(w, format, header, rows)
+}
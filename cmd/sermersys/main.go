@@ -0,0 +1,77 @@
+// sermersys/cmd/sermersys/main.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"sermersys/tracing"
+)
+
+// command - подкоманда CLI
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "запустить HTTP-сервер с веб-интерфейсом и API", runServe},
+	{"resolve", "уточнить объект через Google Places (только mapsearchg)", runResolve},
+	{"search", "найти объект на платформах через Google CSE (только googlesearch)", runSearch},
+	{"analyze", "полный анализ: уточнение объекта и поиск по платформам", runAnalyze},
+	{"batch", "полный анализ для списка объектов из CSV или JSON Lines", runBatch},
+	{"export", "преобразовать сохранённый результат анализа в другой формат", runExport},
+	{"keys", "управление ключами HTTP API: create, list, revoke", runKeys},
+	{"workspaces", "рабочие пространства клиентов и отслеживаемые объекты", runWorkspaces},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			err := cmd.run(os.Args[2:])
+			shutdownTracing()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "sermersys %s: %v\n", name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "sermersys: неизвестная команда %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// shutdownTracing отправляет спаны, накопленные за время работы команды
+func shutdownTracing() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracing.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "sermersys: ошибка отправки трассировки: %v\n", err)
+	}
+}
+
+// usage выводит список подкоманд
+func usage() {
+	fmt.Fprintln(os.Stderr, "Использование: sermersys <команда> [флаги]")
+	fmt.Fprintln(os.Stderr, "\nКоманды:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nФлаги команды: sermersys <команда> -h")
+}
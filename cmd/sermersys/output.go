@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"sermersys/export"
+	"sermersys/i18n"
+	"sermersys/model"
+	"sermersys/pipeline"
+)
+
+// outputFormats - поддерживаемые форматы вывода
+var outputFormats = []string{"json", "csv", "table"}
+
+// reportFormats - форматы отчётов, доступные командам с результатами анализа (analyze, batch, export)
+var reportFormats = []string{"xlsx", "geojson", "kml", "html", "pdf", "jsonl"}
+
+// listingColumns - колонки строк googlesearch в порядке вывода
+var listingColumns = []string{
+	"platform", "title", "link", "page", "position", "match_score",
+	"rating", "user_ratings", "review_author", "review_rating", "review_text",
+}
+
+// writeJSON выводит значение в JSON с отступами
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeRows выводит таблицу в CSV или в виде выровненной таблицы
+func writeRows(w io.Writer, format string, header []string, rows [][]string) error {
+	if format == "csv" {
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		writer.WriteAll(rows)
+		return writer.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	writeTabLine(tw, header)
+	for _, row := range rows {
+		writeTabLine(tw, row)
+	}
+	return tw.Flush()
+}
+
+func writeTabLine(w io.Writer, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		// Длинные тексты отзывов обрезаем, чтобы таблица оставалась читаемой
+		if runes := []rune(cell); len(runes) > 60 {
+			cell = string(runes[:57]) + "..."
+		}
+		fmt.Fprint(w, cell)
+	}
+	fmt.Fprintln(w)
+}
+
+// writePlaces выводит объекты из mapsearchg
+func writePlaces(w io.Writer, format string, places []model.Place) error {
+	if format == "json" {
+		return writeJSON(w, places)
+	}
+	header := []string{"name", "formatted_address", "lat", "lng", "place_id", "website", "phone", "rating", "user_ratings_total"}
+	var rows [][]string
+	for _, p := range places {
+		rows = append(rows, []string{
+			p.Name,
+			p.FormattedAddress,
+			fmt.Sprintf("%f", p.Lat),
+			fmt.Sprintf("%f", p.Lng),
+			p.PlaceID,
+			p.Website,
+			p.Phone,
+			fmt.Sprintf("%.2f", p.Rating),
+			strconv.Itoa(p.UserRatingsTotal),
+		})
+	}
+	return writeRows(w, format, header, rows)
+}
+
+// writeListings выводит найденные на платформах ссылки
+func writeListings(w io.Writer, format string, listings []model.Listing) error {
+	if format == "json" {
+		return writeJSON(w, listings)
+	}
+	var rows [][]string
+	for _, listing := range listings {
+		values := listing.Map()
+		row := make([]string, len(listingColumns))
+		for i, column := range listingColumns {
+			row[i] = values[column]
+		}
+		rows = append(rows, row)
+	}
+	return writeRows(w, format, listingColumns, rows)
+}
+
+// writeResults выводит результаты анализа; в табличных форматах - по строке на ссылку платформы.
+// Отчёты (xlsx, kml, html, pdf) оформляются на языке lang.
+func writeResults(w io.Writer, format string, lang i18n.Lang, results []*pipeline.Result) error {
+	switch format {
+	case "json":
+		if len(results) == 1 {
+			return writeJSON(w, results[0])
+		}
+		return writeJSON(w, results)
+	case "xlsx":
+		return export.WriteXLSX(w, lang, results...)
+	case "geojson":
+		return export.WriteGeoJSON(w, lang, results...)
+	case "kml":
+		return export.WriteKML(w, lang, results...)
+	case "html":
+		return export.WriteHTML(w, lang, results...)
+	case "pdf":
+		return export.WritePDF(w, lang, results...)
+	case "jsonl":
+		return export.WriteJSONL(w, lang, results...)
+	}
+
+	header := append([]string{"refined_hotel_name", "refined_address"}, listingColumns...)
+	var rows [][]string
+	for _, result := range results {
+		for _, listing := range result.SearchResults {
+			values := listing.Map()
+			row := []string{result.RefinedHotelName, result.RefinedAddress}
+			for _, column := range listingColumns {
+				row = append(row, values[column])
+			}
+			rows = append(rows, row)
+		}
+	}
+	return writeRows(w, format, header, rows)
+}
@@ -0,0 +1,243 @@
+// sermersys/cmd/sermersys/workspaces.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"sermersys/config"
+	"sermersys/workspace"
+)
+
+// =================== workspaces ===================
+
+func runWorkspaces(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("укажите действие: create, list, update, delete, watch или unwatch")
+	}
+	switch args[0] {
+	case "create":
+		return runWorkspacesCreate(args[1:])
+	case "list":
+		return runWorkspacesList(args[1:])
+	case "update":
+		return runWorkspacesUpdate(args[1:])
+	case "delete":
+		return runWorkspacesDelete(args[1:])
+	case "watch":
+		return runWorkspacesWatch(args[1:])
+	case "unwatch":
+		return runWorkspacesUnwatch(args[1:])
+	default:
+		return fmt.Errorf("неизвестное действие %q, допустимые: create, list, update, delete, watch, unwatch", args[0])
+	}
+}
+
+// openWorkspaces загружает конфигурацию и хранилище рабочих пространств
+func openWorkspaces(flags *config.Flags) (*workspace.Store, error) {
+	cfg, err := flags.Load()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.WorkspacesFile == "" {
+		return nil, fmt.Errorf("не задан workspaces_file")
+	}
+	return workspace.Open(cfg.WorkspacesFile)
+}
+
+// workspaceFlags - настройки пространства, общие для create и update
+type workspaceFlags struct {
+	name      string
+	apiKey    string
+	cx        string
+	catalog   string
+	catalogs  string
+	templates string
+	inherit   bool
+}
+
+func (f *workspaceFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.name, "name", "", "название (например, бренд клиента)")
+	fs.StringVar(&f.apiKey, "api-key", "", "ключ Google API клиента (обязательно без -inherit-google-keys)")
+	fs.StringVar(&f.cx, "cx", "", "идентификатор Google CSE клиента")
+	fs.StringVar(&f.catalog, "catalog", "", "каталог платформ по умолчанию")
+	fs.StringVar(&f.catalogs, "catalogs", "", "другие разрешённые каталоги платформ через запятую")
+	fs.StringVar(&f.templates, "templates", "", "файл шаблонов поисковых запросов")
+	fs.BoolVar(&f.inherit, "inherit-google-keys", false, "работать на ключах Google сервиса вместо своих (их квота общая)")
+}
+
+// apply переносит в пространство флаги, явно указанные в командной строке
+func (f *workspaceFlags) apply(fs *flag.FlagSet, w *workspace.Workspace) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "name":
+			w.Name = f.name
+		case "api-key":
+			w.GoogleAPIKey = f.apiKey
+		case "cx":
+			w.GoogleCX = f.cx
+		case "catalog":
+			w.PlatformsFile = f.catalog
+		case "catalogs":
+			w.PlatformFiles = nil
+			for _, file := range strings.Split(f.catalogs, ",") {
+				if file = strings.TrimSpace(file); file != "" {
+					w.PlatformFiles = append(w.PlatformFiles, file)
+				}
+			}
+		case "templates":
+			w.QueryTemplatesFile = f.templates
+		case "inherit-google-keys":
+			w.InheritGoogleKeys = f.inherit
+		}
+	})
+}
+
+func runWorkspacesCreate(args []string) error {
+	fs := flag.NewFlagSet("workspaces create", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	id := fs.String("id", "", "ID пространства: строчные латинские буквы, цифры, - и _ (обязательно)")
+	var settings workspaceFlags
+	settings.register(fs)
+	fs.Parse(args)
+
+	store, err := openWorkspaces(flags)
+	if err != nil {
+		return err
+	}
+	w := workspace.Workspace{ID: *id}
+	settings.apply(fs, &w)
+	created, err := store.Create(w)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Рабочее пространство %s создано\n", created.ID)
+	return nil
+}
+
+func runWorkspacesUpdate(args []string) error {
+	fs := flag.NewFlagSet("workspaces update", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	id := fs.String("id", "", "ID пространства (обязательно)")
+	var settings workspaceFlags
+	settings.register(fs)
+	fs.Parse(args)
+
+	store, err := openWorkspaces(flags)
+	if err != nil {
+		return err
+	}
+	_, err = store.Update(*id, func(w *workspace.Workspace) error {
+		settings.apply(fs, w)
+		return nil
+	})
+	return err
+}
+
+func runWorkspacesList(args []string) error {
+	fs := flag.NewFlagSet("workspaces list", flag.ExitOnError)
+	var common commonFlags
+	common.register(fs)
+	fs.Parse(args)
+	if err := common.checkFormat(); err != nil {
+		return err
+	}
+
+	store, err := openWorkspaces(common.config)
+	if err != nil {
+		return err
+	}
+	// Ключи Google клиентов не выводятся
+	var list []*workspace.Workspace
+	for _, w := range store.List() {
+		list = append(list, w.Public())
+	}
+	return withOutput(&common, func(w io.Writer) error {
+		if common.format == "json" {
+			return writeJSON(w, list)
+		}
+		header := []string{"id", "name", "own_google_key", "inherit_google_keys", "platforms_file", "platform_files", "query_templates_file", "properties"}
+		var rows [][]string
+		for _, ws := range store.List() {
+			rows = append(rows, []string{
+				ws.ID,
+				ws.Name,
+				strconv.FormatBool(ws.GoogleAPIKey != ""),
+				strconv.FormatBool(ws.InheritGoogleKeys),
+				ws.PlatformsFile,
+				strings.Join(ws.PlatformFiles, ","),
+				ws.QueryTemplatesFile,
+				strconv.Itoa(len(ws.Properties)),
+			})
+		}
+		return writeRows(w, common.format, header, rows)
+	})
+}
+
+func runWorkspacesDelete(args []string) error {
+	fs := flag.NewFlagSet("workspaces delete", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	id := fs.String("id", "", "ID пространства (обязательно)")
+	fs.Parse(args)
+
+	store, err := openWorkspaces(flags)
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(*id); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Рабочее пространство %s удалено, файлы результатов сохранены\n", *id)
+	return nil
+}
+
+func runWorkspacesWatch(args []string) error {
+	fs := flag.NewFlagSet("workspaces watch", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	id := fs.String("id", "", "ID пространства (обязательно)")
+	var p workspace.Property
+	fs.StringVar(&p.ObjectName, "name", "", "название объекта (обязательно)")
+	fs.StringVar(&p.Address, "address", "", "адрес объекта")
+	fs.StringVar(&p.City, "city", "", "город (обязательно)")
+	fs.StringVar(&p.Country, "country", "", "страна")
+	fs.StringVar(&p.Language, "language", "", "язык для шаблонов запросов и стоп-слов")
+	fs.StringVar(&p.PlatformsFile, "catalog", "", "каталог платформ (по умолчанию - каталог пространства)")
+	fs.StringVar(&p.QueryTemplatesFile, "templates", "", "файл шаблонов запросов (только файл пространства)")
+	fs.Parse(args)
+
+	store, err := openWorkspaces(flags)
+	if err != nil {
+		return err
+	}
+	var added workspace.Property
+	_, err = store.Update(*id, func(w *workspace.Workspace) error {
+		added, err = workspace.AddProperty(w, p)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Объект %s добавлен в %s\n", added.ID, *id)
+	return nil
+}
+
+func runWorkspacesUnwatch(args []string) error {
+	fs := flag.NewFlagSet("workspaces unwatch", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	id := fs.String("id", "", "ID пространства (обязательно)")
+	property := fs.String("property", "", "ID отслеживаемого объекта (обязательно)")
+	fs.Parse(args)
+
+	store, err := openWorkspaces(flags)
+	if err != nil {
+		return err
+	}
+	_, err = store.Update(*id, func(w *workspace.Workspace) error {
+		return workspace.RemoveProperty(w, *property)
+	})
+	return err
+}
@@ -0,0 +1,584 @@
+// sermersys/config/config.go
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"sermersys/fixture"
+	"sermersys/logging"
+	"sermersys/tracing"
+)
+
+// DefaultFile - файл конфигурации по умолчанию
+const DefaultFile = "./config.json"
+
+// EnvPrefix - префикс переменных окружения, переопределяющих файл конфигурации
+const EnvPrefix = "SERMERSYS_"
+
+// =================== Структуры ===================
+
+// Config - общая конфигурация сервиса и пакетов поиска
+type Config struct {
+	Port               int       `json:"port"`
+	ResultsDir         string    `json:"results_dir"`
+	GoogleAPIKey       string    `json:"google_api_key"`
+	GoogleCX           string    `json:"google_cx"`
+	PlatformsFile      string    `json:"platforms_file"`
+	PlatformFiles      []string  `json:"platform_files,omitempty"` // другие каталоги платформ, которые можно выбрать в запросе
+	QueryTemplatesFile string    `json:"query_templates_file,omitempty"`
+	QueryTemplateFiles []string  `json:"query_template_files,omitempty"` // другие файлы шаблонов, которые можно выбрать в запросе
+	Concurrency        int       `json:"concurrency"`
+	Timeouts           Timeouts  `json:"timeouts"`
+	Providers          Providers `json:"providers"`
+	Endpoints          Endpoints `json:"endpoints"`
+	Fixtures           Fixtures  `json:"fixtures"`
+	Downloads          Downloads `json:"downloads"`
+	Auth               Auth      `json:"auth"`
+	WorkspacesFile     string    `json:"workspaces_file"` // рабочие пространства клиентов (см. пакет workspace)
+	Jobs               Jobs      `json:"jobs"`
+	Metrics            Metrics   `json:"metrics"`
+	Log                Log       `json:"log"`
+	Tracing            Tracing   `json:"tracing"`
+	Mail               Mail      `json:"mail"`
+}
+
+// Timeouts - ограничения времени
+type Timeouts struct {
+	Upstream Duration `json:"upstream"` // один запрос к Google API
+	Analysis Duration `json:"analysis"` // весь анализ одного объекта
+	Read     Duration `json:"read"`     // чтение запроса к серверу
+	Write    Duration `json:"write"`    // ответ сервера; 0 - analysis плюс минута (см. WriteTimeout)
+	Idle     Duration `json:"idle"`     // простаивающее keep-alive соединение
+	Shutdown Duration `json:"shutdown"` // завершение запросов и анализов при остановке сервера
+}
+
+// Providers - включённые внешние сервисы
+type Providers struct {
+	Places bool `json:"places"` // Google Places (mapsearchg и рейтинг в googlesearch)
+	CSE    bool `json:"cse"`    // Google Custom Search (googlesearch)
+}
+
+// Endpoints - адреса Google API; переопределяются для тестовых и поддельных серверов
+type Endpoints struct {
+	PlacesTextSearch string `json:"places_text_search"`
+	PlacesDetails    string `json:"places_details"`
+	PlacesFindPlace  string `json:"places_find_place"`
+	CustomSearch     string `json:"custom_search"`
+}
+
+// Fixtures - запись и воспроизведение ответов Google API (см. пакет fixture)
+type Fixtures struct {
+	Mode string `json:"mode,omitempty"` // off (по умолчанию), record или replay
+	Dir  string `json:"dir,omitempty"`  // каталог с записями
+}
+
+// Downloads - ссылки на скачивание файлов результатов
+type Downloads struct {
+	SigningKey string   `json:"signing_key,omitempty"` // если задан, ссылки подписываются и истекают через LinkTTL
+	LinkTTL    Duration `json:"link_ttl"`
+}
+
+// Jobs - очередь асинхронных анализов API v1
+type Jobs struct {
+	Workers   int `json:"workers"`    // анализов, выполняемых одновременно
+	QueueSize int `json:"queue_size"` // анализов, ожидающих в очереди; при заполнении API отвечает 503
+}
+
+// Metrics - метрики Prometheus на /metrics (см. пакет metrics)
+type Metrics struct {
+	Enabled bool `json:"enabled"` // при включённой авторизации нужен ключ с ролью read
+}
+
+// Log - журнал (см. пакет logging)
+type Log struct {
+	Level  string `json:"level"`  // debug, info, warn или error
+	Format string `json:"format"` // json или text
+}
+
+// Tracing - трассировка OpenTelemetry (см. пакет tracing)
+type Tracing struct {
+	Exporter    string  `json:"exporter"`           // off (по умолчанию), otlp или stdout
+	Endpoint    string  `json:"endpoint,omitempty"` // адрес OTLP/HTTP-коллектора, например http://localhost:4318
+	SampleRatio float64 `json:"sample_ratio"`       // доля записываемых трассировок, 0..1
+}
+
+// Mail - отправка отчётов по почте через SMTP (см. пакет mailer)
+type Mail struct {
+	Host     string `json:"host,omitempty"` // SMTP-сервер; пусто - отправка выключена
+	Port     int    `json:"port"`           // 587 - STARTTLS, 465 - TLS с самого начала
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from,omitempty"` // адрес отправителя, например "Sermersys <reports@example.com>"
+	// AllowedDomains - домены получателей email_to в запросах к серверу (поддомены тоже разрешены);
+	// пусто - сервер не отправляет отчёты по запросам, остаётся только команда analyze
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+}
+
+// Enabled сообщает, настроена ли отправка почты
+func (m Mail) Enabled() bool {
+	return m.Host != ""
+}
+
+// AllowsRecipient сообщает, входит ли домен адреса в AllowedDomains
+func (m Mail) AllowsRecipient(address string) bool {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(address[at+1:])
+	for _, allowed := range m.AllowedDomains {
+		allowed = strings.ToLower(strings.TrimPrefix(allowed, "@"))
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Auth - доступ к HTTP API по ключам (см. пакет auth)
+type Auth struct {
+	Enabled  bool   `json:"enabled"`
+	KeysFile string `json:"keys_file"` // хэши ключей, роли, лимиты и статистика использования
+}
+
+// Duration - time.Duration, который в JSON записывается строкой ("15s") или числом секунд
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return fmt.Errorf("ожидается длительность вида \"15s\" или число секунд")
+	}
+	*d = Duration(seconds * float64(time.Second))
+	return nil
+}
+
+// Std возвращает значение как time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// WriteTimeout возвращает таймаут ответа сервера: Write, а если он не задан - Analysis плюс минута
+// на запись файлов и отправку ответа
+func (t Timeouts) WriteTimeout() time.Duration {
+	if t.Write > 0 {
+		return t.Write.Std()
+	}
+	return t.Analysis.Std() + time.Minute
+}
+
+// Default возвращает конфигурацию со значениями по умолчанию
+func Default() *Config {
+	return &Config{
+		Port:           7001,
+		ResultsDir:     "./results",
+		PlatformsFile:  "platform2.txt",
+		Concurrency:    4,
+		WorkspacesFile: "./workspaces.json",
+		Timeouts: Timeouts{
+			Upstream: Duration(15 * time.Second),
+			Analysis: Duration(2 * time.Minute),
+			Read:     Duration(30 * time.Second),
+			Idle:     Duration(2 * time.Minute),
+			Shutdown: Duration(5 * time.Minute),
+		},
+		Providers: Providers{Places: true, CSE: true},
+		Fixtures:  Fixtures{Dir: "./fixtures"},
+		Downloads: Downloads{LinkTTL: Duration(24 * time.Hour)},
+		Auth:      Auth{KeysFile: "./api_keys.json"},
+		Jobs:      Jobs{Workers: 2, QueueSize: 100},
+		Metrics:   Metrics{Enabled: true},
+		Log:       Log{Level: "info", Format: logging.FormatJSON},
+		Tracing:   Tracing{Exporter: tracing.ExporterOff, SampleRatio: 1},
+		Mail:      Mail{Port: 587},
+		Endpoints: Endpoints{
+			PlacesTextSearch: "https://maps.googleapis.com/maps/api/place/textsearch/json",
+			PlacesDetails:    "https://maps.googleapis.com/maps/api/place/details/json",
+			PlacesFindPlace:  "https://maps.googleapis.com/maps/api/place/findplacefromtext/json",
+			CustomSearch:     "https://www.googleapis.com/customsearch/v1",
+		},
+	}
+}
+
+// =================== Загрузка ===================
+
+// Load загружает конфигурацию: значения по умолчанию, затем файл, затем переменные окружения.
+// Отсутствие файла по умолчанию не считается ошибкой - ключи могут прийти из окружения.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv(EnvPrefix + "CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		path = DefaultFile
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга конфигурационного файла %s: %v", path, err)
+		}
+	case explicit || !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("ошибка чтения конфигурационного файла: %v", err)
+	}
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv переопределяет значения из переменных окружения SERMERSYS_*
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	for _, o := range c.overrides() {
+		value, ok := lookup(EnvPrefix + o.env)
+		if !ok {
+			continue
+		}
+		if err := o.set(value); err != nil {
+			return fmt.Errorf("переменная %s%s: %v", EnvPrefix, o.env, err)
+		}
+	}
+	return nil
+}
+
+// override - настройка, которую можно переопределить из окружения и флагом
+type override struct {
+	env   string
+	flag  string
+	usage string
+	set   func(string) error
+}
+
+// overrides перечисляет переопределяемые настройки
+func (c *Config) overrides() []override {
+	return []override{
+		{"PORT", "port", "порт HTTP-сервера", intSetter(&c.Port)},
+		{"RESULTS_DIR", "results-dir", "директория для файлов с результатами", stringSetter(&c.ResultsDir)},
+		{"GOOGLE_API_KEY", "google-api-key", "ключ Google API", stringSetter(&c.GoogleAPIKey)},
+		{"GOOGLE_CX", "google-cx", "идентификатор поисковой системы Google CSE", stringSetter(&c.GoogleCX)},
+		{"PLATFORMS_FILE", "platforms", "файл со списком платформ", stringSetter(&c.PlatformsFile)},
+		{"PLATFORM_FILES", "platform-files", "другие каталоги платформ, которые можно выбрать в запросе, через запятую", listSetter(&c.PlatformFiles)},
+		{"QUERY_TEMPLATES_FILE", "query-templates", "файл шаблонов поисковых запросов", stringSetter(&c.QueryTemplatesFile)},
+		{"QUERY_TEMPLATE_FILES", "query-template-files", "другие файлы шаблонов, которые можно выбрать в запросе, через запятую", listSetter(&c.QueryTemplateFiles)},
+		{"CONCURRENCY", "concurrency", "число параллельных запросов к Google API", intSetter(&c.Concurrency)},
+		{"UPSTREAM_TIMEOUT", "upstream-timeout", "таймаут одного запроса к Google API (например 15s)", durationSetter(&c.Timeouts.Upstream)},
+		{"ANALYSIS_TIMEOUT", "analysis-timeout", "таймаут анализа одного объекта (например 2m)", durationSetter(&c.Timeouts.Analysis)},
+		{"READ_TIMEOUT", "read-timeout", "таймаут чтения запроса к серверу", durationSetter(&c.Timeouts.Read)},
+		{"WRITE_TIMEOUT", "write-timeout", "таймаут ответа сервера (не меньше analysis-timeout; 0 - analysis-timeout плюс минута)", durationSetter(&c.Timeouts.Write)},
+		{"IDLE_TIMEOUT", "idle-timeout", "таймаут простаивающего keep-alive соединения", durationSetter(&c.Timeouts.Idle)},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "сколько ждать завершения запросов и анализов при остановке сервера", durationSetter(&c.Timeouts.Shutdown)},
+		{"PROVIDERS", "providers", "включённые провайдеры через запятую: places, cse", c.setProviders},
+		{"FIXTURE_MODE", "fixture-mode", "запись или воспроизведение ответов Google API: off, record, replay", stringSetter(&c.Fixtures.Mode)},
+		{"FIXTURE_DIR", "fixture-dir", "каталог записанных ответов Google API", stringSetter(&c.Fixtures.Dir)},
+		{"DOWNLOAD_SIGNING_KEY", "download-signing-key", "ключ подписи ссылок на скачивание (пусто - без подписи)", stringSetter(&c.Downloads.SigningKey)},
+		{"DOWNLOAD_LINK_TTL", "download-link-ttl", "срок действия подписанных ссылок (например 24h)", durationSetter(&c.Downloads.LinkTTL)},
+		{"AUTH_ENABLED", "auth", "требовать ключ API для HTTP API (true/false)", boolSetter(&c.Auth.Enabled)},
+		{"AUTH_KEYS_FILE", "auth-keys-file", "файл ключей API", stringSetter(&c.Auth.KeysFile)},
+		{"JOB_WORKERS", "job-workers", "число одновременно выполняемых анализов API v1", intSetter(&c.Jobs.Workers)},
+		{"JOB_QUEUE_SIZE", "job-queue-size", "размер очереди анализов API v1", intSetter(&c.Jobs.QueueSize)},
+		{"METRICS_ENABLED", "metrics", "отдавать метрики Prometheus на /metrics (true/false)", boolSetter(&c.Metrics.Enabled)},
+		{"LOG_LEVEL", "log-level", "уровень журнала: debug, info, warn, error", stringSetter(&c.Log.Level)},
+		{"LOG_FORMAT", "log-format", "формат журнала: json, text", stringSetter(&c.Log.Format)},
+		{"TRACING_EXPORTER", "tracing-exporter", "экспортёр трассировки: off, otlp, stdout", stringSetter(&c.Tracing.Exporter)},
+		{"TRACING_ENDPOINT", "tracing-endpoint", "адрес OTLP/HTTP-коллектора (например http://localhost:4318)", stringSetter(&c.Tracing.Endpoint)},
+		{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "доля записываемых трассировок от 0 до 1", floatSetter(&c.Tracing.SampleRatio)},
+		{"WORKSPACES_FILE", "workspaces-file", "файл рабочих пространств", stringSetter(&c.WorkspacesFile)},
+		{"MAIL_HOST", "mail-host", "SMTP-сервер для отправки отчётов (пусто - без почты)", stringSetter(&c.Mail.Host)},
+		{"MAIL_PORT", "mail-port", "порт SMTP-сервера: 587 (STARTTLS) или 465 (TLS)", intSetter(&c.Mail.Port)},
+		{"MAIL_USERNAME", "mail-username", "имя пользователя SMTP", stringSetter(&c.Mail.Username)},
+		{"MAIL_PASSWORD", "mail-password", "пароль SMTP", stringSetter(&c.Mail.Password)},
+		{"MAIL_FROM", "mail-from", "адрес отправителя отчётов", stringSetter(&c.Mail.From)},
+		{"MAIL_ALLOWED_DOMAINS", "mail-allowed-domains", "домены получателей email_to в запросах к серверу через запятую", listSetter(&c.Mail.AllowedDomains)},
+	}
+}
+
+func stringSetter(p *string) func(string) error {
+	return func(v string) error {
+		*p = v
+		return nil
+	}
+}
+
+func listSetter(p *[]string) func(string) error {
+	return func(v string) error {
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*p = list
+		return nil
+	}
+}
+
+func intSetter(p *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("ожидается целое число: %q", v)
+		}
+		*p = n
+		return nil
+	}
+}
+
+func floatSetter(p *float64) func(string) error {
+	return func(v string) error {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return fmt.Errorf("ожидается число: %q", v)
+		}
+		*p = f
+		return nil
+	}
+}
+
+func boolSetter(p *bool) func(string) error {
+	return func(v string) error {
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("ожидается true или false: %q", v)
+		}
+		*p = b
+		return nil
+	}
+}
+
+func durationSetter(p *Duration) func(string) error {
+	return func(v string) error {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("ожидается длительность вида 15s: %q", v)
+		}
+		*p = Duration(d)
+		return nil
+	}
+}
+
+// setProviders включает перечисленные провайдеры и выключает остальные
+func (c *Config) setProviders(v string) error {
+	var providers Providers
+	for _, name := range strings.Split(v, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "places":
+			providers.Places = true
+		case "cse":
+			providers.CSE = true
+		case "":
+		default:
+			return fmt.Errorf("неизвестный провайдер %q", name)
+		}
+	}
+	c.Providers = providers
+	return nil
+}
+
+// =================== Флаги ===================
+
+// Flags - флаги командной строки, переопределяющие файл и окружение
+type Flags struct {
+	fs     *flag.FlagSet
+	path   string
+	values map[string]*string
+}
+
+// RegisterFlags регистрирует флаг -config и флаги для всех переопределяемых настроек
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs, values: make(map[string]*string)}
+	fs.StringVar(&f.path, "config", "", "путь к файлу конфигурации (по умолчанию "+DefaultFile+" или $"+EnvPrefix+"CONFIG)")
+	for _, o := range Default().overrides() {
+		f.values[o.flag] = fs.String(o.flag, "", o.usage)
+	}
+	return f
+}
+
+// Load загружает конфигурацию и применяет флаги, явно указанные в командной строке.
+// Вызывается после fs.Parse. Проверку (Validate) выполняет вызывающий код,
+// после того как он скорректирует набор провайдеров под свою задачу.
+func (f *Flags) Load() (*Config, error) {
+	cfg, err := Load(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool)
+	f.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	for _, o := range cfg.overrides() {
+		if !set[o.flag] {
+			continue
+		}
+		if err := o.set(*f.values[o.flag]); err != nil {
+			return nil, fmt.Errorf("флаг -%s: %v", o.flag, err)
+		}
+	}
+	return cfg, nil
+}
+
+// =================== Проверка ===================
+
+// Validate проверяет обязательные ключи включённых провайдеров и допустимость значений
+func (c *Config) Validate() error {
+	var problems []string
+	// При воспроизведении записей запросы в Google не отправляются, ключ не нужен
+	replay := c.Fixtures.Mode == fixture.ModeReplay
+	if c.Providers.Places && c.GoogleAPIKey == "" && !replay {
+		problems = append(problems, "для провайдера places нужен google_api_key")
+	}
+	if c.Providers.CSE {
+		if c.GoogleAPIKey == "" && !replay {
+			problems = append(problems, "для провайдера cse нужен google_api_key")
+		}
+		if c.GoogleCX == "" {
+			problems = append(problems, "для провайдера cse нужен google_cx")
+		}
+	}
+	if !c.Providers.Places && !c.Providers.CSE {
+		problems = append(problems, "не включён ни один провайдер")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("недопустимый порт %d", c.Port))
+	}
+	if c.ResultsDir == "" {
+		problems = append(problems, "не задан results_dir")
+	}
+	if c.Concurrency < 1 {
+		problems = append(problems, "concurrency должен быть не меньше 1")
+	}
+	if c.Timeouts.Upstream <= 0 || c.Timeouts.Analysis <= 0 || c.Timeouts.Read <= 0 ||
+		c.Timeouts.Idle <= 0 || c.Timeouts.Shutdown <= 0 {
+		problems = append(problems, "таймауты должны быть больше нуля")
+	}
+	if c.Timeouts.Write != 0 && c.Timeouts.Write < c.Timeouts.Analysis {
+		// Синхронный /process не успеет вернуть результат анализа
+		problems = append(problems, "timeouts.write должен быть не меньше timeouts.analysis")
+	}
+	for _, e := range []struct{ name, url string }{
+		{"places_text_search", c.Endpoints.PlacesTextSearch},
+		{"places_details", c.Endpoints.PlacesDetails},
+		{"places_find_place", c.Endpoints.PlacesFindPlace},
+		{"custom_search", c.Endpoints.CustomSearch},
+	} {
+		if u, err := url.Parse(e.url); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("недопустимый адрес endpoints.%s: %q", e.name, e.url))
+		}
+	}
+	if c.Jobs.Workers < 1 || c.Jobs.QueueSize < 1 {
+		problems = append(problems, "jobs.workers и jobs.queue_size должны быть не меньше 1")
+	}
+	if c.Auth.Enabled && c.Auth.KeysFile == "" {
+		problems = append(problems, "не задан auth.keys_file")
+	}
+	if c.Auth.Enabled && c.Downloads.SigningKey == "" {
+		// Веб-интерфейс скачивает файлы обычными ссылками без заголовка X-API-Key
+		problems = append(problems, "при включённой авторизации нужен downloads.signing_key")
+	}
+	if c.Downloads.SigningKey != "" && c.Downloads.LinkTTL <= 0 {
+		problems = append(problems, "downloads.link_ttl должен быть больше нуля")
+	}
+	if !fixture.ValidMode(c.Fixtures.Mode) {
+		problems = append(problems, fmt.Sprintf("неизвестный режим fixtures.mode %q", c.Fixtures.Mode))
+	} else if fixture.Enabled(c.Fixtures.Mode) && c.Fixtures.Dir == "" {
+		problems = append(problems, "не задан fixtures.dir")
+	}
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		problems = append(problems, fmt.Sprintf("неизвестный log.level %q", c.Log.Level))
+	}
+	if !logging.ValidFormat(c.Log.Format) {
+		problems = append(problems, fmt.Sprintf("неизвестный log.format %q", c.Log.Format))
+	}
+	if !tracing.ValidExporter(c.Tracing.Exporter) {
+		problems = append(problems, fmt.Sprintf("неизвестный tracing.exporter %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sample_ratio должен быть от 0 до 1")
+	}
+	if c.Mail.Enabled() {
+		if c.Mail.Port <= 0 || c.Mail.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("недопустимый mail.port %d", c.Mail.Port))
+		}
+		if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+			problems = append(problems, fmt.Sprintf("недопустимый mail.from %q", c.Mail.From))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("ошибка конфигурации: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// =================== Вспомогательные методы ===================
+
+// Addr возвращает адрес HTTP-сервера
+func (c *Config) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// HTTPClient возвращает HTTP-клиент для запросов к Google API с таймаутом upstream.
+// В режимах fixtures record и replay ответы записываются в каталог или читаются из него.
+func (c *Config) HTTPClient() *http.Client {
+	client := &http.Client{Timeout: c.Timeouts.Upstream.Std()}
+	if fixture.Enabled(c.Fixtures.Mode) {
+		client.Transport = &fixture.Transport{Mode: c.Fixtures.Mode, Dir: c.Fixtures.Dir}
+	}
+	return client
+}
+
+// =================== Разрешённые файлы ===================
+
+// AllowsPlatformsFile сообщает, можно ли выбрать каталог платформ в запросе к HTTP API:
+// пустое значение (каталог по умолчанию), platforms_file или один из platform_files.
+// Другие пути не открываются, иначе запрос мог бы прочитать любой файл сервера.
+func (c *Config) AllowsPlatformsFile(file string) bool {
+	return file == "" || AllowedFile(file, c.PlatformsFile, c.PlatformFiles)
+}
+
+// AllowsQueryTemplatesFile - как AllowsPlatformsFile, для файла шаблонов запросов:
+// пустое значение, query_templates_file или один из query_template_files
+func (c *Config) AllowsQueryTemplatesFile(file string) bool {
+	return file == "" || AllowedFile(file, c.QueryTemplatesFile, c.QueryTemplateFiles)
+}
+
+// AllowedFile сообщает, совпадает ли file с defaultFile или одним из files после очистки путей.
+// Общая проверка каталогов платформ и файлов шаблонов для конфигурации и рабочих пространств.
+func AllowedFile(file, defaultFile string, files []string) bool {
+	file = filepath.Clean(file)
+	if defaultFile != "" && file == filepath.Clean(defaultFile) {
+		return true
+	}
+	for _, allowed := range files {
+		if allowed != "" && file == filepath.Clean(allowed) {
+			return true
+		}
+	}
+	return false
+}
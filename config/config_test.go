@@ -0,0 +1,93 @@
+// sermersys/config/config_test.go
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllowsPlatformsFile(t *testing.T) {
+	cfg := &Config{PlatformsFile: "platform2.txt", PlatformFiles: []string{"catalogs/cafes.txt"}}
+	cases := []struct {
+		file string
+		want bool
+	}{
+		{"", true},
+		{"platform2.txt", true},
+		{"./platform2.txt", true},
+		{"catalogs/cafes.txt", true},
+		{"catalogs/../catalogs/cafes.txt", true},
+		{"config.json", false},
+		{"/etc/passwd", false},
+		{"../platform2.txt", false},
+		{"catalogs/hotels.txt", false},
+	}
+	for _, c := range cases {
+		if got := cfg.AllowsPlatformsFile(c.file); got != c.want {
+			t.Errorf("AllowsPlatformsFile(%q) = %v, ожидалось %v", c.file, got, c.want)
+		}
+	}
+}
+
+func TestAllowsQueryTemplatesFile(t *testing.T) {
+	cfg := &Config{QueryTemplateFiles: []string{"templates/cafes.json"}}
+	cases := []struct {
+		file string
+		want bool
+	}{
+		{"", true},
+		{"templates/cafes.json", true},
+		{"./templates/cafes.json", true},
+		{"query_templates.json", false},
+		{"config.json", false},
+		{"/etc/passwd", false},
+	}
+	for _, c := range cases {
+		if got := cfg.AllowsQueryTemplatesFile(c.file); got != c.want {
+			t.Errorf("AllowsQueryTemplatesFile(%q) = %v, ожидалось %v", c.file, got, c.want)
+		}
+	}
+}
+
+func TestValidateAuthRequiresSigningKey(t *testing.T) {
+	cfg := Default()
+	cfg.GoogleAPIKey = "key"
+	cfg.GoogleCX = "cx"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("конфигурация по умолчанию с ключами: %v", err)
+	}
+
+	cfg.Auth.Enabled = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "downloads.signing_key") {
+		t.Fatalf("авторизация без ключа подписи: %v, ожидалась ошибка про downloads.signing_key", err)
+	}
+	cfg.Downloads.SigningKey = "secret"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("авторизация с ключом подписи: %v", err)
+	}
+}
+
+func TestMailAllowsRecipient(t *testing.T) {
+	m := Mail{AllowedDomains: []string{"example.com", "@Hotels.ME"}}
+	cases := []struct {
+		address string
+		want    bool
+	}{
+		{"owner@example.com", true},
+		{"Owner@EXAMPLE.com", true},
+		{"sales@eu.example.com", true},
+		{"gm@hotels.me", true},
+		{"owner@example.com.evil.org", false},
+		{"owner@notexample.com", false},
+		{"victim@gmail.com", false},
+		{"example.com", false},
+	}
+	for _, c := range cases {
+		if got := m.AllowsRecipient(c.address); got != c.want {
+			t.Errorf("AllowsRecipient(%q) = %v, ожидалось %v", c.address, got, c.want)
+		}
+	}
+	if (Mail{}).AllowsRecipient("owner@example.com") {
+		t.Errorf("без allowed_domains отправка по запросам должна быть запрещена")
+	}
+}
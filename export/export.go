@@ -0,0 +1,44 @@
+// sermersys/export/export.go
+package export
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Отчёты по результатам анализа в форматах для аналитиков и клиентов: книги Excel (XLSX),
+// слои для ГИС (GeoJSON, KML), HTML-отчёт для печати, PDF-отчёт и поток JSON Lines для загрузки в хранилища.
+// Отчёты не обращаются к Google API и строятся из pipeline.Result, поэтому годятся
+// и для только что выполненного анализа, и для сохранённого (команда export).
+
+// MIME-типы файлов результата
+const (
+	ContentTypeCSV     = "text/csv; charset=utf-8"
+	ContentTypeXLSX    = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	ContentTypeGeoJSON = "application/geo+json"
+	ContentTypeKML     = "application/vnd.google-earth.kml+xml"
+	ContentTypeHTML    = "text/html; charset=utf-8"
+	ContentTypePDF     = "application/pdf"
+	ContentTypeJSONL   = "application/x-ndjson"
+)
+
+// ContentType возвращает MIME-тип файла результата по расширению
+func ContentType(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return ContentTypeCSV
+	case ".xlsx":
+		return ContentTypeXLSX
+	case ".geojson":
+		return ContentTypeGeoJSON
+	case ".kml":
+		return ContentTypeKML
+	case ".html":
+		return ContentTypeHTML
+	case ".pdf":
+		return ContentTypePDF
+	case ".jsonl":
+		return ContentTypeJSONL
+	}
+	return "application/octet-stream"
+}
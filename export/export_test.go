@@ -0,0 +1,59 @@
+// sermersys/export/export_test.go
+package export
+
+import (
+	"testing"
+	"time"
+
+	"sermersys/model"
+	"sermersys/pipeline"
+)
+
+// sampleResult - анализ с символами, которые нужно экранировать в XML и HTML
+func sampleResult() *pipeline.Result {
+	rating := &model.RatingSnapshot{Source: model.SourceGooglePlaces, Rating: 4.6, UserRatingsTotal: 812}
+	review := &model.Review{Author: "Анна", Rating: 5, Text: `Вид на море & "тишина" <рядом пляж>`}
+	return &pipeline.Result{
+		RefinedHotelName: "Hotel Adriatic & Spa",
+		RefinedAddress:   "Slovenska obala 1, Budva, Montenegro",
+		Places: []model.Place{{
+			Name:             "Hotel Adriatic & Spa",
+			FormattedAddress: "Slovenska obala 1, Budva, Montenegro",
+			Lat:              42.2864,
+			Lng:              18.8400,
+			PlaceID:          "ChIJ-adriatic",
+			Website:          "https://adriatic.example.com/?a=1&b=2",
+			Phone:            "+382 33 000 000",
+			Rating:           4.6,
+			UserRatingsTotal: 812,
+		}},
+		SearchResults: []model.Listing{
+			{Platform: "booking.com", Title: "Hotel Adriatic & Spa <Budva>", Link: "https://www.booking.com/hotel/me/adriatic.html?aid=1&lang=en",
+				Page: 1, Position: 2, MatchScore: 0.93, Rating: rating, Review: review},
+			{Platform: "tripadvisor.com", Title: "Hotel Adriatic - Tripadvisor", Link: "https://www.tripadvisor.com/Hotel_Review-adriatic",
+				Page: 1, Position: 1, MatchScore: 0.88, Rating: rating, Review: review},
+		},
+		Platforms:      []string{"booking.com", "tripadvisor.com", "expedia.com"},
+		ExecutionSteps: []string{"Уточнение объекта", "Поиск по платформам"},
+		AnalyzedAt:     time.Date(2025, 2, 14, 10, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestContentType(t *testing.T) {
+	cases := map[string]string{
+		"results.CSV":     ContentTypeCSV,
+		"book.xlsx":       ContentTypeXLSX,
+		"places.geojson":  ContentTypeGeoJSON,
+		"places.kml":      ContentTypeKML,
+		"report.html":     ContentTypeHTML,
+		"report.pdf":      ContentTypePDF,
+		"batch.jsonl":     ContentTypeJSONL,
+		"archive.tar.gz":  "application/octet-stream",
+		"no-extension-at": "application/octet-stream",
+	}
+	for filename, want := range cases {
+		if got := ContentType(filename); got != want {
+			t.Errorf("ContentType(%q) = %q, ожидалось %q", filename, got, want)
+		}
+	}
+}
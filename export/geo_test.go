@@ -0,0 +1,124 @@
+// sermersys/export/geo_test.go
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"sermersys/i18n"
+	"sermersys/model"
+	"sermersys/pipeline"
+)
+
+// geoResult - анализ с конкурентом и кандидатом без координат
+func geoResult() *pipeline.Result {
+	r := sampleResult()
+	r.Places = append(r.Places,
+		model.Place{Name: "Hotel Mogren", FormattedAddress: "Mediteranska 2, Budva", Lat: 42.2790, Lng: 18.8350, PlaceID: "ChIJ-mogren", Rating: 4.1, UserRatingsTotal: 120},
+		model.Place{Name: "Без координат", PlaceID: "ChIJ-nowhere"},
+	)
+	return r
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGeoJSON(&buf, i18n.EN, geoResult()); err != nil {
+		t.Fatal(err)
+	}
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Type     string `json:"type"`
+			ID       string `json:"id"`
+			Geometry struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatalf("неверный JSON: %v", err)
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Fatalf("ожидалась FeatureCollection из 2 точек: %s", buf.String())
+	}
+	for _, f := range collection.Features {
+		if f.Type != "Feature" || f.Geometry.Type != "Point" || len(f.Geometry.Coordinates) != 2 {
+			t.Errorf("неверная точка %s: %+v", f.ID, f)
+		}
+	}
+	resolved, competitor := collection.Features[0], collection.Features[1]
+	// RFC 7946: сначала долгота, затем широта
+	if resolved.Geometry.Coordinates[0] != 18.84 || resolved.Geometry.Coordinates[1] != 42.2864 {
+		t.Errorf("координаты: %v", resolved.Geometry.Coordinates)
+	}
+	if resolved.Properties["role"] != roleResolved || resolved.Properties["platforms_found"] != 2.0 || resolved.Properties["platforms_total"] != 3.0 {
+		t.Errorf("свойства объекта анализа: %v", resolved.Properties)
+	}
+	if competitor.Properties["role"] != roleCompetitor || competitor.Properties["coverage"] != nil {
+		t.Errorf("свойства конкурента: %v", competitor.Properties)
+	}
+}
+
+func TestWriteGeoJSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGeoJSON(&buf, i18n.EN, &pipeline.Result{}); err != nil {
+		t.Fatal(err)
+	}
+	// Пустой слой - массив, а не null
+	var collection map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatal(err)
+	}
+	if features, ok := collection["features"].([]interface{}); !ok || len(features) != 0 {
+		t.Errorf("пустой слой: %s", buf.String())
+	}
+}
+
+func TestWriteKML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteKML(&buf, i18n.DE, geoResult()); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.opengis.net/kml/2.2 kml"`
+		Folders []struct {
+			Name       string `xml:"name"`
+			Placemarks []struct {
+				Name     string `xml:"name"`
+				StyleURL string `xml:"styleUrl"`
+				Data     []struct {
+					Name  string `xml:"name,attr"`
+					Value string `xml:"value"`
+				} `xml:"ExtendedData>Data"`
+				Coordinates string `xml:"Point>coordinates"`
+			} `xml:"Placemark"`
+		} `xml:"Document>Folder"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("неверный XML: %v\n%s", err, buf.String())
+	}
+	if len(doc.Folders) != 1 || len(doc.Folders[0].Placemarks) != 2 {
+		t.Fatalf("ожидалась одна папка с 2 метками: %s", buf.String())
+	}
+	resolved := doc.Folders[0].Placemarks[0]
+	if resolved.Name != "Hotel Adriatic & Spa" || resolved.StyleURL != "#"+roleResolved {
+		t.Errorf("метка объекта анализа: %+v", resolved)
+	}
+	if resolved.Coordinates != "18.84,42.2864" {
+		t.Errorf("координаты: %q", resolved.Coordinates)
+	}
+	values := make(map[string]string)
+	for _, d := range resolved.Data {
+		values[d.Name] = d.Value
+	}
+	if values["website"] != "https://adriatic.example.com/?a=1&b=2" || values["found_on"] != "booking.com, tripadvisor.com" {
+		t.Errorf("ExtendedData: %v", values)
+	}
+	if doc.Folders[0].Placemarks[1].StyleURL != "#"+roleCompetitor {
+		t.Errorf("метка конкурента: %+v", doc.Folders[0].Placemarks[1])
+	}
+}
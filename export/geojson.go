@@ -0,0 +1,87 @@
+// sermersys/export/geojson.go
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"sermersys/i18n"
+	"sermersys/pipeline"
+)
+
+// GeoJSON (RFC 7946) для QGIS и других ГИС: по точке на объект Google Places с координатами.
+// Свойства - машинные ключи без перевода, чтобы схема слоя не зависела от языка.
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string            `json:"type"`
+	ID         string            `json:"id,omitempty"`
+	Geometry   point             `json:"geometry"`
+	Properties featureProperties `json:"properties"`
+}
+
+type point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"` // долгота, широта
+}
+
+// featureProperties - свойства точки. Покрытие известно только для объекта анализа,
+// у конкурентов поля покрытия - null.
+type featureProperties struct {
+	Object           string   `json:"object"`
+	Role             string   `json:"role"` // resolved или competitor
+	Name             string   `json:"name"`
+	FormattedAddress string   `json:"formatted_address"`
+	PlaceID          string   `json:"place_id"`
+	Website          string   `json:"website"`
+	Phone            string   `json:"phone"`
+	Rating           float64  `json:"rating"`
+	UserRatingsTotal int      `json:"user_ratings_total"`
+	Coverage         *float64 `json:"coverage"` // доля платформ, на которых найден объект
+	PlatformsFound   *int     `json:"platforms_found"`
+	PlatformsTotal   *int     `json:"platforms_total"`
+	FoundOn          []string `json:"found_on"`
+}
+
+// WriteGeoJSON записывает объекты результатов анализа как FeatureCollection: объект анализа
+// (role = resolved) с рейтингом, числом оценок и покрытием платформ и остальных кандидатов
+// Google Places (role = competitor). Объекты без координат пропускаются. lang не используется
+// и нужен для единой сигнатуры отчётов.
+func WriteGeoJSON(w io.Writer, lang i18n.Lang, results ...*pipeline.Result) error {
+	collection := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+	for _, r := range results {
+		for _, p := range placePoints(r) {
+			properties := featureProperties{
+				Object:           p.Object,
+				Role:             p.Role,
+				Name:             p.Place.Name,
+				FormattedAddress: p.Place.FormattedAddress,
+				PlaceID:          p.Place.PlaceID,
+				Website:          p.Place.Website,
+				Phone:            p.Place.Phone,
+				Rating:           p.Place.Rating,
+				UserRatingsTotal: p.Place.UserRatingsTotal,
+			}
+			if p.Role == roleResolved {
+				ratio, found, total := p.coverageRatio(), len(p.Found), p.Platforms
+				properties.Coverage = &ratio
+				properties.PlatformsFound = &found
+				properties.PlatformsTotal = &total
+				properties.FoundOn = p.Found
+			}
+			collection.Features = append(collection.Features, feature{
+				Type:       "Feature",
+				ID:         p.Place.PlaceID,
+				Geometry:   point{Type: "Point", Coordinates: [2]float64{p.Place.Lng, p.Place.Lat}},
+				Properties: properties,
+			})
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(collection)
+}
@@ -0,0 +1,174 @@
+// sermersys/export/html.go
+package export
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"sermersys/i18n"
+	"sermersys/model"
+	"sermersys/pipeline"
+)
+
+// Отчёт для клиента одним HTML-файлом: стили и графики (SVG) встроены, внешних ресурсов нет,
+// поэтому файл открывается без сети и печатается в PDF из браузера.
+
+//go:embed report.html.tmpl
+var htmlTemplate string
+
+// htmlReport - данные шаблона отчёта
+type htmlReport struct {
+	Lang      i18n.Lang
+	Generated string
+	Matrix    *htmlMatrix // сводное покрытие, если объектов несколько
+	Objects   []htmlObject
+}
+
+// htmlMatrix - покрытие платформ по всем объектам пакета
+type htmlMatrix struct {
+	Platforms []string
+	Rows      []htmlMatrixRow
+}
+
+type htmlMatrixRow struct {
+	Object string
+	Found  []bool // по столбцам Platforms
+}
+
+// htmlObject - раздел отчёта об одном объекте
+type htmlObject struct {
+	Name        string
+	Place       *model.Place
+	Competitors []model.Place
+	Coverage    []coverageRow
+	Found       int
+	Total       int
+	RatingChart template.HTML
+	TrendChart  template.HTML
+	Reviews     []model.Review
+	AnalyzedAt  string
+	Duration    string
+	Listings    int
+	Steps       []string
+}
+
+// WriteHTML записывает отчёт о результатах анализа на языке lang: данные объекта и конкурентов,
+// покрытие платформ, рейтинги, динамику видимости в выдаче, отзывы и сведения о запуске.
+// Для пакета результатов в начале отчёта - сводная матрица покрытия, каждый объект - с новой страницы.
+func WriteHTML(w io.Writer, lang i18n.Lang, results ...*pipeline.Result) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"t": func(key string, args ...interface{}) string { return i18n.T(lang, key, args...) },
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return err
+	}
+
+	report := htmlReport{Lang: lang, Generated: formatTime(time.Now())}
+	for _, r := range results {
+		report.Objects = append(report.Objects, newHTMLObject(lang, r))
+	}
+	if len(results) > 1 {
+		report.Matrix = coverageMatrix(results)
+	}
+	return tmpl.Execute(w, report)
+}
+
+func newHTMLObject(lang i18n.Lang, r *pipeline.Result) htmlObject {
+	object := htmlObject{
+		Name:     objectName(r),
+		Coverage: coverage(r),
+		Reviews:  r.Reviews(),
+		Listings: len(r.SearchResults),
+		Steps:    r.ExecutionSteps,
+	}
+	if len(r.Places) > 0 {
+		object.Place = &r.Places[0]
+		object.Competitors = r.Places[1:]
+	}
+	object.Total = len(object.Coverage)
+	for _, c := range object.Coverage {
+		if c.Found {
+			object.Found++
+		}
+	}
+	if !r.AnalyzedAt.IsZero() {
+		object.AnalyzedAt = formatTime(r.AnalyzedAt)
+	}
+	if r.Duration > 0 {
+		object.Duration = r.Duration.Round(time.Millisecond).String()
+	}
+
+	if bars := platformRatings(lang, r); len(bars) > 0 {
+		object.RatingChart = template.HTML(barChart(bars, 5))
+	}
+
+	if labels, rows := visibilitySeries(r); len(labels) > 0 {
+		object.TrendChart = template.HTML(lineChart(labels, rows))
+	}
+	return object
+}
+
+// visibilitySeries раскладывает историю видимости платформ по общей шкале ISO-недель
+func visibilitySeries(r *pipeline.Result) ([]string, []series) {
+	weeks := make(map[string]bool)
+	for _, platform := range r.Visibility {
+		for _, week := range platform.Weeks {
+			weeks[week.Week] = true
+		}
+	}
+	labels := make([]string, 0, len(weeks))
+	for week := range weeks {
+		labels = append(labels, week)
+	}
+	sort.Strings(labels)
+	index := make(map[string]int, len(labels))
+	for i, week := range labels {
+		index[week] = i
+	}
+
+	rows := make([]series, 0, len(r.Visibility))
+	for _, platform := range r.Visibility {
+		row := series{Name: platform.Platform, Values: make([]float64, len(labels))}
+		for i := range row.Values {
+			row.Values[i] = math.NaN()
+		}
+		for _, week := range platform.Weeks {
+			row.Values[index[week.Week]] = week.Visibility
+		}
+		rows = append(rows, row)
+	}
+	return labels, rows
+}
+
+// coverageMatrix строит сводное покрытие: объекты по строкам, платформы всех результатов по столбцам
+func coverageMatrix(results []*pipeline.Result) *htmlMatrix {
+	matrix := &htmlMatrix{}
+	seen := make(map[string]bool)
+	found := make([]map[string]bool, len(results))
+	for i, r := range results {
+		found[i] = make(map[string]bool)
+		for _, c := range coverage(r) {
+			if !seen[c.Platform] {
+				seen[c.Platform] = true
+				matrix.Platforms = append(matrix.Platforms, c.Platform)
+			}
+			found[i][c.Platform] = c.Found
+		}
+	}
+	for i, r := range results {
+		row := htmlMatrixRow{Object: objectName(r)}
+		for _, platform := range matrix.Platforms {
+			row.Found = append(row.Found, found[i][platform])
+		}
+		matrix.Rows = append(matrix.Rows, row)
+	}
+	return matrix
+}
+
+func formatTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04 MST")
+}
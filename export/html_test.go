@@ -0,0 +1,58 @@
+// sermersys/export/html_test.go
+package export
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"sermersys/i18n"
+)
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, i18n.EN, sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	if !strings.Contains(page, `<html lang="en"`) {
+		t.Errorf("нет языка отчёта")
+	}
+	// Отчёт открывается без сети: ни стилей, ни скриптов, ни картинок извне
+	external := regexp.MustCompile(`(?i)<(link|script|img|iframe)\b|src=|@import|url\(`)
+	if m := external.FindString(page); m != "" {
+		t.Errorf("отчёт ссылается на внешний ресурс: %s", m)
+	}
+	if !strings.Contains(page, "<svg") {
+		t.Errorf("в отчёте нет графиков SVG")
+	}
+	if strings.Contains(page, "<Budva>") || strings.Contains(page, "<рядом пляж>") {
+		t.Errorf("данные анализа не экранированы")
+	}
+	if !strings.Contains(page, "Hotel Adriatic &amp; Spa") || !strings.Contains(page, "&lt;рядом пляж&gt;") {
+		t.Errorf("нет названия объекта или отзыва")
+	}
+}
+
+func TestWriteHTMLBatchMatrix(t *testing.T) {
+	second := sampleResult()
+	second.RefinedHotelName = "Hotel Mogren"
+	second.SearchResults = second.SearchResults[:1]
+
+	var single, batch bytes.Buffer
+	if err := WriteHTML(&single, i18n.RU, sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteHTML(&batch, i18n.RU, sampleResult(), second); err != nil {
+		t.Fatal(err)
+	}
+	// Сводная матрица покрытия есть только у пакета
+	heading := i18n.T(i18n.RU, "report.html.matrix")
+	if strings.Contains(single.String(), heading) {
+		t.Errorf("матрица покрытия в отчёте об одном объекте")
+	}
+	if !strings.Contains(batch.String(), heading) || !strings.Contains(batch.String(), "Hotel Mogren") {
+		t.Errorf("нет матрицы покрытия пакета")
+	}
+}
@@ -0,0 +1,173 @@
+// sermersys/export/jsonl.go
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"sermersys/i18n"
+	"sermersys/mapsearchg"
+	"sermersys/pipeline"
+)
+
+// Поток JSON Lines для загрузки в хранилища данных: по строке на объект (property) и на ссылку
+// платформы (listing). Строки пишутся по мере готовности результатов, поэтому файл пакета можно
+// читать, пока пакет выполняется. Ключи - машинные, без перевода; поля не удаляются и не меняют
+// смысл без увеличения JSONLSchemaVersion.
+
+// JSONLSchemaVersion - версия схемы строк потока, поле schema_version каждой строки
+const JSONLSchemaVersion = 1
+
+// Типы строк потока, поле type
+const (
+	RecordProperty = "property"
+	RecordListing  = "listing"
+)
+
+// jsonlProperty - строка об объекте анализа. Если анализ не удался, заполнены только поля
+// запроса и error, а строк listing у объекта нет.
+type jsonlProperty struct {
+	SchemaVersion    int        `json:"schema_version"`
+	Type             string     `json:"type"`
+	Batch            string     `json:"batch,omitempty"`
+	Item             int        `json:"item"` // номер объекта в пакете, с 1
+	ObjectName       string     `json:"object_name"`
+	City             string     `json:"city"`
+	Country          string     `json:"country"`
+	Name             string     `json:"name"`
+	FormattedAddress string     `json:"formatted_address"`
+	PlaceID          string     `json:"place_id"`
+	Lat              *float64   `json:"lat"`
+	Lng              *float64   `json:"lng"`
+	Phone            string     `json:"phone"`
+	Website          string     `json:"website"`
+	Rating           *float64   `json:"rating"`
+	UserRatingsTotal *int       `json:"user_ratings_total"`
+	PlatformsFound   int        `json:"platforms_found"`
+	PlatformsTotal   int        `json:"platforms_total"`
+	Listings         int        `json:"listings"`
+	AnalyzedAt       *time.Time `json:"analyzed_at"`
+	Error            string     `json:"error,omitempty"`
+}
+
+// jsonlListing - строка о ссылке на объект на платформе
+type jsonlListing struct {
+	SchemaVersion int      `json:"schema_version"`
+	Type          string   `json:"type"`
+	Batch         string   `json:"batch,omitempty"`
+	Item          int      `json:"item"`
+	PlaceID       string   `json:"place_id"`
+	Platform      string   `json:"platform"`
+	Title         string   `json:"title"`
+	Link          string   `json:"link"`
+	Page          int      `json:"page"`
+	Position      int      `json:"position"`
+	MatchScore    float64  `json:"match_score"`
+	Rating        *float64 `json:"rating"`
+	UserRatings   *int     `json:"user_ratings"`
+}
+
+// JSONLWriter пишет результаты анализа в поток JSON Lines. Каждая строка записывается одним
+// вызовом Write, поэтому при записи в файл читатель видит только целые строки (кроме, возможно,
+// последней, которая ещё пишется). JSONLWriter не потокобезопасен.
+type JSONLWriter struct {
+	encoder *json.Encoder
+	batch   string
+}
+
+// NewJSONLWriter создаёт поток; batch (ID пакета) попадает в каждую строку, если не пуст
+func NewJSONLWriter(w io.Writer, batch string) *JSONLWriter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &JSONLWriter{encoder: encoder, batch: batch}
+}
+
+// WriteResult записывает строку объекта item и строки его ссылок на платформах
+func (s *JSONLWriter) WriteResult(item int, request mapsearchg.RequestData, r *pipeline.Result) error {
+	property := s.property(item, request)
+	if property.ObjectName == "" {
+		property.ObjectName = objectName(r)
+	}
+	property.Name = r.RefinedHotelName
+	property.FormattedAddress = r.RefinedAddress
+	if len(r.Places) > 0 {
+		place := r.Places[0]
+		property.PlaceID = place.PlaceID
+		property.Lat, property.Lng = &place.Lat, &place.Lng
+		property.Phone = place.Phone
+		property.Website = place.Website
+		property.Rating = &place.Rating
+		property.UserRatingsTotal = &place.UserRatingsTotal
+	}
+	for _, c := range coverage(r) {
+		property.PlatformsTotal++
+		if c.Found {
+			property.PlatformsFound++
+		}
+	}
+	property.Listings = len(r.SearchResults)
+	if !r.AnalyzedAt.IsZero() {
+		analyzedAt := r.AnalyzedAt.UTC()
+		property.AnalyzedAt = &analyzedAt
+	}
+	if err := s.encoder.Encode(property); err != nil {
+		return err
+	}
+
+	for _, listing := range r.SearchResults {
+		record := jsonlListing{
+			SchemaVersion: JSONLSchemaVersion,
+			Type:          RecordListing,
+			Batch:         s.batch,
+			Item:          item,
+			PlaceID:       property.PlaceID,
+			Platform:      listing.Platform,
+			Title:         listing.Title,
+			Link:          listing.Link,
+			Page:          listing.Page,
+			Position:      listing.Position,
+			MatchScore:    listing.MatchScore,
+		}
+		if listing.Rating != nil {
+			record.Rating = &listing.Rating.Rating
+			record.UserRatings = &listing.Rating.UserRatingsTotal
+		}
+		if err := s.encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteError записывает строку объекта item, анализ которого не удался
+func (s *JSONLWriter) WriteError(item int, request mapsearchg.RequestData, message string) error {
+	property := s.property(item, request)
+	property.Error = message
+	return s.encoder.Encode(property)
+}
+
+func (s *JSONLWriter) property(item int, request mapsearchg.RequestData) jsonlProperty {
+	return jsonlProperty{
+		SchemaVersion: JSONLSchemaVersion,
+		Type:          RecordProperty,
+		Batch:         s.batch,
+		Item:          item,
+		ObjectName:    request.ObjectName,
+		City:          request.City,
+		Country:       request.Country,
+	}
+}
+
+// WriteJSONL записывает результаты анализа потоком JSON Lines; item - порядковый номер результата.
+// Поля запроса (город, страна) в сохранённом результате неизвестны и остаются пустыми.
+// lang не используется и нужен для единой сигнатуры отчётов.
+func WriteJSONL(w io.Writer, lang i18n.Lang, results ...*pipeline.Result) error {
+	stream := NewJSONLWriter(w, "")
+	for i, r := range results {
+		if err := stream.WriteResult(i+1, mapsearchg.RequestData{}, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
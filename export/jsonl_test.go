@@ -0,0 +1,84 @@
+// sermersys/export/jsonl_test.go
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"sermersys/i18n"
+	"sermersys/mapsearchg"
+)
+
+// readJSONL разбирает поток: каждая строка - отдельный JSON-объект
+func readJSONL(t *testing.T, data []byte) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("строка %d: неверный JSON: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestJSONLWriter(t *testing.T) {
+	var buf bytes.Buffer
+	stream := NewJSONLWriter(&buf, "b-1")
+	request := mapsearchg.RequestData{ObjectName: "Adriatic", City: "Budva", Country: "Montenegro"}
+	if err := stream.WriteResult(1, request, sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.WriteError(2, mapsearchg.RequestData{ObjectName: "Mogren", City: "Budva"}, "нет результатов"); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readJSONL(t, buf.Bytes())
+	if len(records) != 4 {
+		t.Fatalf("строк %d, ожидалось 4 (объект, 2 ссылки, ошибка)", len(records))
+	}
+	wantTypes := []string{RecordProperty, RecordListing, RecordListing, RecordProperty}
+	for i, record := range records {
+		if record["schema_version"] != float64(JSONLSchemaVersion) {
+			t.Errorf("строка %d: schema_version = %v", i+1, record["schema_version"])
+		}
+		if record["type"] != wantTypes[i] || record["batch"] != "b-1" {
+			t.Errorf("строка %d: type %v, batch %v", i+1, record["type"], record["batch"])
+		}
+	}
+
+	property := records[0]
+	if property["object_name"] != "Adriatic" || property["place_id"] != "ChIJ-adriatic" || property["platforms_found"] != 2.0 {
+		t.Errorf("строка объекта: %v", property)
+	}
+	listing := records[1]
+	if listing["item"] != 1.0 || listing["place_id"] != "ChIJ-adriatic" || listing["link"] != "https://www.booking.com/hotel/me/adriatic.html?aid=1&lang=en" {
+		t.Errorf("строка ссылки: %v", listing)
+	}
+	failed := records[3]
+	if failed["item"] != 2.0 || failed["error"] != "нет результатов" || failed["rating"] != nil {
+		t.Errorf("строка ошибки: %v", failed)
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, i18n.EN, sampleResult(), sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	records := readJSONL(t, buf.Bytes())
+	if len(records) != 6 {
+		t.Fatalf("строк %d, ожидалось 6", len(records))
+	}
+	// Без пакета поле batch не пишется, объекты нумеруются по порядку
+	if _, ok := records[0]["batch"]; ok {
+		t.Errorf("batch без пакета: %v", records[0])
+	}
+	if records[3]["type"] != RecordProperty || records[3]["item"] != 2.0 || records[3]["object_name"] != "Hotel Adriatic & Spa" {
+		t.Errorf("второй объект: %v", records[3])
+	}
+}
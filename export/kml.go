@@ -0,0 +1,79 @@
+// sermersys/export/kml.go
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"sermersys/i18n"
+	"sermersys/pipeline"
+)
+
+// KML 2.2 для Google Earth и Google My Maps: папка на каждый результат анализа, метка на объект
+// Google Places. Объект анализа и конкуренты различаются цветом метки.
+
+// Цвета меток в KML записываются как aabbggrr
+const kmlStyles = `<Style id="resolved"><IconStyle><color>ff0000ff</color><scale>1.2</scale></IconStyle></Style>
+<Style id="competitor"><IconStyle><color>ffb0b0b0</color></IconStyle></Style>
+`
+
+// kmlData - поле ExtendedData метки
+type kmlData struct {
+	name, label, value string
+}
+
+// WriteKML записывает объекты результатов анализа как документ KML. Свойства меток
+// (рейтинг, число оценок, покрытие платформ) - в ExtendedData с подписями на языке lang.
+// Объекты без координат пропускаются.
+func WriteKML(w io.Writer, lang i18n.Lang, results ...*pipeline.Result) error {
+	t := func(key string) string { return i18n.T(lang, key) }
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	bw.WriteString(`<kml xmlns="http://www.opengis.net/kml/2.2"><Document>` + "\n")
+	bw.WriteString("<name>sermersys</name>\n")
+	bw.WriteString(kmlStyles)
+	for _, r := range results {
+		points := placePoints(r)
+		if len(points) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "<Folder><name>%s</name>\n", escapeXML(objectName(r)))
+		for _, p := range points {
+			data := []kmlData{
+				{"role", t("csv.role"), t("report.role." + p.Role)},
+				{"place_id", t("csv.place_id"), p.Place.PlaceID},
+				{"website", t("csv.website"), p.Place.Website},
+				{"phone", t("csv.phone"), p.Place.Phone},
+				{"rating", t("csv.rating"), strconv.FormatFloat(p.Place.Rating, 'f', -1, 64)},
+				{"user_ratings_total", t("csv.user_ratings_total"), strconv.Itoa(p.Place.UserRatingsTotal)},
+			}
+			if p.Role == roleResolved {
+				data = append(data,
+					kmlData{"coverage", t("csv.coverage"), i18n.T(lang, "report.coverage", len(p.Found), p.Platforms)},
+					kmlData{"found_on", t("csv.found_on"), strings.Join(p.Found, ", ")},
+				)
+			}
+
+			bw.WriteString("<Placemark>")
+			fmt.Fprintf(bw, "<name>%s</name>", escapeXML(p.Place.Name))
+			fmt.Fprintf(bw, "<address>%s</address>", escapeXML(p.Place.FormattedAddress))
+			fmt.Fprintf(bw, "<styleUrl>#%s</styleUrl>", p.Role)
+			bw.WriteString("<ExtendedData>")
+			for _, d := range data {
+				fmt.Fprintf(bw, `<Data name="%s"><displayName>%s</displayName><value>%s</value></Data>`,
+					d.name, escapeXML(d.label), escapeXML(d.value))
+			}
+			bw.WriteString("</ExtendedData>")
+			fmt.Fprintf(bw, "<Point><coordinates>%s,%s</coordinates></Point>",
+				strconv.FormatFloat(p.Place.Lng, 'f', -1, 64), strconv.FormatFloat(p.Place.Lat, 'f', -1, 64))
+			bw.WriteString("</Placemark>\n")
+		}
+		bw.WriteString("</Folder>\n")
+	}
+	bw.WriteString("</Document></kml>\n")
+	return bw.Flush()
+}
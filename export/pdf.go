@@ -0,0 +1,294 @@
+// sermersys/export/pdf.go
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"sermersys/i18n"
+	"sermersys/pipeline"
+)
+
+// PDF-отчёт для менеджеров: строится целиком на Go, без браузера и внешних программ.
+// Шрифты Go встроены в программу и покрывают латиницу, кириллицу и греческий.
+
+const (
+	pdfFont       = "go"
+	pdfMargin     = 15.0 // поля страницы, мм
+	pdfMaxReviews = 10   // отзывов в отчёте об одном объекте
+	pdfExcerpt    = 500  // символов в выдержке из отзыва
+)
+
+// Фирменный цвет отчёта - тот же, что в веб-интерфейсе
+var pdfBrand = [3]int{0, 123, 255}
+
+// WritePDF записывает отчёт о результатах анализа на языке lang. Для каждого объекта -
+// титульная страница с названием и адресом из Google Places, затем таблица покрытия платформ,
+// сравнение рейтингов с конкурентами и по платформам и выдержки из отзывов.
+func WritePDF(w io.Writer, lang i18n.Lang, results ...*pipeline.Result) error {
+	t := func(key string, args ...interface{}) string { return i18n.T(lang, key, args...) }
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(pdfFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", gobold.TTF)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin+5)
+	title := t("report.html.title")
+	if len(results) == 1 {
+		title += ": " + objectName(results[0])
+	}
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("sermersys", true)
+
+	covers := make(map[int]bool)
+	pdf.SetFooterFunc(func() {
+		if covers[pdf.PageNo()] {
+			return
+		}
+		pageW, _ := pdf.GetPageSize()
+		half := (pageW - 2*pdfMargin) / 2
+		pdf.SetY(-pdfMargin)
+		pdf.SetFont(pdfFont, "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(half, 5, "sermersys · "+t("report.html.title"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(half, 5, strconv.Itoa(pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	generated := formatTime(time.Now())
+	for _, r := range results {
+		pdf.AddPage()
+		covers[pdf.PageNo()] = true
+		pdfCover(pdf, t, r, generated)
+
+		pdf.AddPage()
+		pdfHeading(pdf, t("report.html.coverage"))
+		pdfCoverage(pdf, t, r)
+
+		if bars := competitorRatings(lang, r); len(bars) > 1 {
+			pdfHeading(pdf, t("report.pdf.competitors"))
+			pdfBars(pdf, bars, 0)
+		}
+		if bars := platformRatings(lang, r); len(bars) > 0 {
+			pdfHeading(pdf, t("report.html.ratings"))
+			pdfBars(pdf, bars, -1)
+		}
+
+		pdfHeading(pdf, t("report.html.reviews"))
+		pdfReviews(pdf, t, r)
+	}
+	if len(results) == 0 {
+		pdf.AddPage()
+	}
+	return pdf.Output(w)
+}
+
+// pdfCover рисует титульную страницу объекта
+func pdfCover(pdf *fpdf.Fpdf, t func(string, ...interface{}) string, r *pipeline.Result, generated string) {
+	pageW, _ := pdf.GetPageSize()
+	pdf.SetFillColor(pdfBrand[0], pdfBrand[1], pdfBrand[2])
+	pdf.Rect(0, 0, pageW, 40, "F")
+	pdf.SetXY(pdfMargin, 14)
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetFont(pdfFont, "B", 20)
+	pdf.CellFormat(0, 10, "sermersys", "", 1, "L", false, 0, "")
+
+	pdf.SetY(80)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.SetFont(pdfFont, "", 14)
+	pdf.CellFormat(0, 8, t("report.html.title"), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+	pdf.SetTextColor(30, 30, 30)
+	pdf.SetFont(pdfFont, "B", 26)
+	pdf.MultiCell(0, 12, objectName(r), "", "L", false)
+	pdf.Ln(2)
+	pdf.SetFont(pdfFont, "", 13)
+	pdf.MultiCell(0, 7, r.RefinedAddress, "", "L", false)
+
+	found, total := 0, 0
+	for _, c := range coverage(r) {
+		total++
+		if c.Found {
+			found++
+		}
+	}
+	facts := [][2]string{{t("report.html.coverage"), t("report.coverage", found, total)}}
+	if len(r.Places) > 0 && r.Places[0].Rating > 0 {
+		place := r.Places[0]
+		facts = append(facts, [2]string{t("csv.rating"),
+			fmt.Sprintf("%.1f (%s)", place.Rating, t("report.html.ratings_count", place.UserRatingsTotal))})
+	}
+	if !r.AnalyzedAt.IsZero() {
+		facts = append(facts, [2]string{t("report.html.analyzed_at"), formatTime(r.AnalyzedAt)})
+	}
+
+	pdf.SetY(150)
+	for _, fact := range facts {
+		pdf.SetFont(pdfFont, "", 11)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.CellFormat(60, 8, fact[0], "", 0, "L", false, 0, "")
+		pdf.SetFont(pdfFont, "B", 11)
+		pdf.SetTextColor(30, 30, 30)
+		pdf.CellFormat(0, 8, fact[1], "", 1, "L", false, 0, "")
+	}
+
+	pdf.SetY(-40)
+	pdf.SetFont(pdfFont, "", 9)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.CellFormat(0, 5, t("report.html.generated", generated), "", 1, "L", false, 0, "")
+}
+
+// pdfHeading выводит заголовок раздела; если под ним не останется места, раздел начинается с новой страницы
+func pdfHeading(pdf *fpdf.Fpdf, title string) {
+	_, pageH := pdf.GetPageSize()
+	if pdf.GetY() > pageH-60 {
+		pdf.AddPage()
+	}
+	pdf.Ln(4)
+	pdf.SetFont(pdfFont, "B", 14)
+	pdf.SetTextColor(30, 30, 30)
+	pdf.CellFormat(0, 9, title, "", 1, "L", false, 0, "")
+	pdf.SetDrawColor(pdfBrand[0], pdfBrand[1], pdfBrand[2])
+	pdf.SetLineWidth(0.6)
+	pageW, _ := pdf.GetPageSize()
+	pdf.Line(pdfMargin, pdf.GetY(), pageW-pdfMargin, pdf.GetY())
+	pdf.Ln(3)
+}
+
+// pdfCoverage выводит таблицу покрытия платформ
+func pdfCoverage(pdf *fpdf.Fpdf, t func(string, ...interface{}) string, r *pipeline.Result) {
+	rows := coverage(r)
+	if len(rows) == 0 {
+		pdf.SetFont(pdfFont, "", 10)
+		pdf.MultiCell(0, 6, t("report.html.no_search"), "", "L", false)
+		return
+	}
+	widths := []float64{45, 18, 20, 24, 73}
+	header := []string{t("csv.platform"), t("csv.found"), t("csv.position"), t("csv.match_score"), t("csv.link")}
+	drawHeader := func() {
+		pdf.SetFont(pdfFont, "B", 9)
+		pdf.SetFillColor(240, 244, 248)
+		pdf.SetDrawColor(210, 210, 210)
+		pdf.SetLineWidth(0.2)
+		pdf.SetTextColor(30, 30, 30)
+		for i, label := range header {
+			pdf.CellFormat(widths[i], 7, fitText(pdf, label, widths[i]-2), "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	drawHeader()
+	_, pageH := pdf.GetPageSize()
+	for _, row := range rows {
+		if pdf.GetY() > pageH-pdfMargin-15 {
+			pdf.AddPage()
+			drawHeader()
+		}
+		found, position, score := t("report.no"), "", ""
+		if row.Found {
+			found = t("report.yes")
+			position = strconv.Itoa(row.Position)
+			score = fmt.Sprintf("%.2f", row.Score)
+		}
+		pdf.SetFont(pdfFont, "", 9)
+		pdf.SetTextColor(30, 30, 30)
+		pdf.CellFormat(widths[0], 6, fitText(pdf, row.Platform, widths[0]-2), "1", 0, "L", false, 0, "")
+		if row.Found {
+			pdf.SetTextColor(30, 126, 52)
+		} else {
+			pdf.SetTextColor(192, 57, 43)
+		}
+		pdf.CellFormat(widths[1], 6, found, "1", 0, "C", false, 0, "")
+		pdf.SetTextColor(30, 30, 30)
+		pdf.CellFormat(widths[2], 6, position, "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, score, "1", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 86, 179)
+		pdf.CellFormat(widths[4], 6, fitText(pdf, row.Link, widths[4]-2), "1", 1, "L", false, 0, row.Link)
+	}
+}
+
+// pdfBars рисует горизонтальную диаграмму рейтингов по шкале 0..5; строка highlight выделяется цветом
+func pdfBars(pdf *fpdf.Fpdf, bars []bar, highlight int) {
+	const (
+		labelW = 55.0
+		barW   = 90.0
+		rowH   = 7.0
+		barH   = 4.5
+	)
+	_, pageH := pdf.GetPageSize()
+	pdf.SetFont(pdfFont, "", 9)
+	for i, item := range bars {
+		if pdf.GetY() > pageH-pdfMargin-15 {
+			pdf.AddPage()
+		}
+		y := pdf.GetY()
+		pdf.SetTextColor(30, 30, 30)
+		pdf.SetXY(pdfMargin, y)
+		pdf.CellFormat(labelW-2, rowH, fitText(pdf, item.Label, labelW-3), "", 0, "R", false, 0, "")
+
+		x := pdfMargin + labelW
+		pdf.SetFillColor(238, 238, 238)
+		pdf.Rect(x, y+(rowH-barH)/2, barW, barH, "F")
+		if i == highlight {
+			pdf.SetFillColor(255, 127, 14)
+		} else {
+			pdf.SetFillColor(pdfBrand[0], pdfBrand[1], pdfBrand[2])
+		}
+		if item.Value > 0 {
+			pdf.Rect(x, y+(rowH-barH)/2, barW*min(item.Value/5, 1), barH, "F")
+		}
+		pdf.SetXY(x+barW+2, y)
+		value := "—"
+		if item.Value > 0 {
+			value = fmt.Sprintf("%.1f", item.Value)
+		}
+		pdf.CellFormat(0, rowH, value+"  "+item.Note, "", 1, "L", false, 0, "")
+	}
+}
+
+// pdfReviews выводит выдержки из отзывов
+func pdfReviews(pdf *fpdf.Fpdf, t func(string, ...interface{}) string, r *pipeline.Result) {
+	rows := r.Reviews()
+	if len(rows) == 0 {
+		pdf.SetFont(pdfFont, "", 10)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.MultiCell(0, 6, t("report.html.reviews_empty"), "", "L", false)
+		return
+	}
+	if len(rows) > pdfMaxReviews {
+		rows = rows[:pdfMaxReviews]
+	}
+	for _, review := range rows {
+		title := review.Author
+		if review.Rating > 0 {
+			title += fmt.Sprintf(" · %d/5", review.Rating)
+		}
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.SetTextColor(30, 30, 30)
+		pdf.MultiCell(0, 6, title, "", "L", false)
+		pdf.SetFont(pdfFont, "", 10)
+		pdf.SetTextColor(60, 60, 60)
+		text := review.Text
+		if runes := []rune(text); len(runes) > pdfExcerpt {
+			text = string(runes[:pdfExcerpt]) + "…"
+		}
+		pdf.MultiCell(0, 5, text, "", "L", false)
+		pdf.Ln(3)
+	}
+}
+
+// fitText обрезает текст с многоточием, чтобы он поместился в ширину width текущего шрифта
+func fitText(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,47 @@
+// sermersys/export/pdf_test.go
+package export
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"sermersys/i18n"
+	"sermersys/pipeline"
+)
+
+// pdfPages - число страниц документа по объектам /Type /Page
+var pdfPages = regexp.MustCompile(`/Type /Page\b[^s]`)
+
+func TestWritePDF(t *testing.T) {
+	greek := sampleResult()
+	greek.RefinedHotelName = "Ξενοδοχείο Ακρόπολις"
+
+	var single, batch bytes.Buffer
+	if err := WritePDF(&single, i18n.RU, sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	if err := WritePDF(&batch, i18n.EN, sampleResult(), greek); err != nil {
+		t.Fatal(err)
+	}
+	for name, doc := range map[string][]byte{"один объект": single.Bytes(), "пакет": batch.Bytes()} {
+		if !bytes.HasPrefix(doc, []byte("%PDF-")) || !bytes.HasSuffix(bytes.TrimSpace(doc), []byte("%%EOF")) {
+			t.Errorf("%s: не PDF-документ", name)
+		}
+	}
+	// Каждый объект начинается с титульной страницы
+	one, two := len(pdfPages.FindAll(single.Bytes(), -1)), len(pdfPages.FindAll(batch.Bytes(), -1))
+	if one < 2 || two < 2*one {
+		t.Errorf("страниц: %d у одного объекта, %d у пакета из двух", one, two)
+	}
+}
+
+func TestWritePDFWithoutPlaces(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, i18n.DE, &pipeline.Result{RefinedHotelName: "Leer"}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Errorf("не PDF-документ")
+	}
+}
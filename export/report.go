@@ -0,0 +1,199 @@
+// sermersys/export/report.go
+package export
+
+import (
+	"strings"
+
+	"sermersys/googlesearch"
+	"sermersys/i18n"
+	"sermersys/model"
+	"sermersys/pipeline"
+)
+
+// Строки отчётов, общие для всех форматов экспорта: покрытие платформ, отзывы и аудит NAP
+// (совпадение названия, адреса и телефона объекта на разных источниках).
+
+// objectName возвращает название объекта для колонки «Объект»
+func objectName(r *pipeline.Result) string {
+	if r.RefinedHotelName != "" {
+		return r.RefinedHotelName
+	}
+	if len(r.Places) > 0 {
+		return r.Places[0].Name
+	}
+	return ""
+}
+
+// coverageRow - найден ли объект на платформе
+type coverageRow struct {
+	Platform string
+	Found    bool
+	Title    string
+	Link     string
+	Position int
+	Score    float64
+}
+
+// coverage возвращает покрытие по всем платформам поиска в порядке каталога.
+// В результатах, сохранённых до появления списка платформ, учитываются только найденные.
+func coverage(r *pipeline.Result) []coverageRow {
+	listings := make(map[string]model.Listing)
+	var order []string
+	for _, listing := range r.SearchResults {
+		if _, ok := listings[listing.Platform]; !ok {
+			listings[listing.Platform] = listing
+			order = append(order, listing.Platform)
+		}
+	}
+	platforms := order
+	if len(r.Platforms) > 0 {
+		platforms = nil
+		for _, platform := range r.Platforms {
+			if platform = strings.TrimSpace(platform); platform != "" {
+				platforms = append(platforms, platform)
+			}
+		}
+	}
+
+	rows := make([]coverageRow, 0, len(platforms))
+	for _, platform := range platforms {
+		row := coverageRow{Platform: platform}
+		if listing, ok := listings[platform]; ok {
+			row.Found = true
+			row.Title = listing.Title
+			row.Link = listing.Link
+			row.Position = listing.Position
+			row.Score = listing.MatchScore
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// platformRatings возвращает рейтинги Google Places и платформ, для которых в результате есть оценка
+func platformRatings(lang i18n.Lang, r *pipeline.Result) []bar {
+	var bars []bar
+	if len(r.Places) > 0 && r.Places[0].Rating > 0 {
+		bars = append(bars, bar{Label: "Google Places", Value: r.Places[0].Rating,
+			Note: i18n.T(lang, "report.html.ratings_count", r.Places[0].UserRatingsTotal)})
+	}
+	for _, c := range coverage(r) {
+		if !c.Found {
+			continue
+		}
+		for _, listing := range r.SearchResults {
+			if listing.Platform == c.Platform && listing.Rating != nil && listing.Rating.Rating > 0 {
+				bars = append(bars, bar{Label: c.Platform, Value: listing.Rating.Rating,
+					Note: i18n.T(lang, "report.html.ratings_count", listing.Rating.UserRatingsTotal)})
+				break
+			}
+		}
+	}
+	return bars
+}
+
+// competitorRatings возвращает рейтинги объекта и конкурентов из Google Places; объект - первый
+func competitorRatings(lang i18n.Lang, r *pipeline.Result) []bar {
+	var bars []bar
+	for _, place := range r.Places {
+		bars = append(bars, bar{Label: place.Name, Value: place.Rating,
+			Note: i18n.T(lang, "report.html.ratings_count", place.UserRatingsTotal)})
+	}
+	return bars
+}
+
+// Статусы строк аудита NAP - ключи каталога сообщений
+const (
+	napReference    = "report.nap.reference"
+	napConsistent   = "report.nap.consistent"
+	napNameMismatch = "report.nap.name_mismatch"
+	napNoPhone      = "report.nap.missing_phone"
+)
+
+// napRow - название, адрес и телефон объекта в одном источнике
+type napRow struct {
+	Source  string
+	Name    string
+	Address string
+	Phone   string
+	Website string
+	Score   float64 // совпадение названия с эталоном; для эталона - 1
+	Status  string  // ключ каталога сообщений
+}
+
+// napAudit сравнивает данные объекта на платформах с эталоном из Google Places.
+// Выдача поиска содержит только заголовок страницы, поэтому на платформах проверяется
+// название: оно совпадает, если оценка не ниже порога сопоставления по умолчанию.
+func napAudit(r *pipeline.Result) []napRow {
+	reference := napRow{Source: "Google Places", Name: r.RefinedHotelName, Address: r.RefinedAddress, Score: 1, Status: napReference}
+	if len(r.Places) > 0 {
+		place := r.Places[0]
+		reference.Phone = place.Phone
+		reference.Website = place.Website
+		if reference.Phone == "" {
+			reference.Status = napNoPhone
+		}
+	}
+	rows := []napRow{reference}
+	for _, c := range coverage(r) {
+		if !c.Found {
+			continue
+		}
+		status := napConsistent
+		if c.Score < googlesearch.DefaultMatchThreshold {
+			status = napNameMismatch
+		}
+		rows = append(rows, napRow{Source: c.Platform, Name: c.Title, Website: c.Link, Score: c.Score, Status: status})
+	}
+	return rows
+}
+
+// Роли объектов на карте
+const (
+	roleResolved   = "resolved"   // объект анализа - первый кандидат Google Places
+	roleCompetitor = "competitor" // остальные кандидаты рядом
+)
+
+// placePoint - объект Google Places с координатами и покрытием платформ
+type placePoint struct {
+	Object    string
+	Role      string
+	Place     model.Place
+	Found     []string // платформы, на которых найден объект
+	Platforms int      // всего платформ в поиске
+}
+
+// coverageRatio возвращает долю платформ, на которых найден объект
+func (p placePoint) coverageRatio() float64 {
+	if p.Platforms == 0 {
+		return 0
+	}
+	return float64(len(p.Found)) / float64(p.Platforms)
+}
+
+// placePoints возвращает объекты результата, у которых есть координаты. Поиск по платформам
+// идёт только по уточнённому объекту, поэтому покрытие есть лишь у него, у конкурентов оно пустое.
+func placePoints(r *pipeline.Result) []placePoint {
+	var found []string
+	rows := coverage(r)
+	for _, c := range rows {
+		if c.Found {
+			found = append(found, c.Platform)
+		}
+	}
+	object := objectName(r)
+	var points []placePoint
+	for i, place := range r.Places {
+		if place.Lat == 0 && place.Lng == 0 {
+			continue
+		}
+		point := placePoint{Object: object, Role: roleCompetitor, Place: place}
+		if i == 0 {
+			point.Role = roleResolved
+			point.Found = found
+			point.Platforms = len(rows)
+		}
+		points = append(points, point)
+	}
+	return points
+}
@@ -0,0 +1,111 @@
+// sermersys/export/svg.go
+package export
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Графики отчётов в виде встроенного SVG: без скриптов и внешних библиотек, поэтому отчёт
+// открывается без сети и печатается так же, как выглядит на экране.
+
+// chartColors - цвета рядов графиков
+var chartColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#17becf", "#bcbd22", "#7f7f7f"}
+
+// bar - строка горизонтальной диаграммы
+type bar struct {
+	Label string
+	Value float64
+	Note  string // подпись справа от столбца, например число оценок
+}
+
+// barChart рисует горизонтальную диаграмму со шкалой от 0 до scale
+func barChart(bars []bar, scale float64) string {
+	const (
+		width    = 640
+		labelW   = 170
+		noteW    = 110
+		rowH     = 24
+		barH     = 16
+		barWidth = width - labelW - noteW
+	)
+	height := len(bars)*rowH + 8
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" class="chart" viewBox="0 0 %d %d" width="%d" height="%d" role="img">`, width, height, width, height)
+	for i, item := range bars {
+		y := i*rowH + 4
+		w := 0.0
+		if scale > 0 {
+			w = math.Min(item.Value/scale, 1) * barWidth
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end" font-size="12">%s</text>`, labelW-8, y+barH-3, escapeXML(item.Label))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#eee"/>`, labelW, y, barWidth, barH)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%d" fill="%s"/>`, labelW, y, w, barH, chartColors[0])
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="12">%s</text>`, labelW+barWidth+6, y+barH-3, escapeXML(item.Note))
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// series - ряд линейного графика; NaN - нет значения в этой точке
+type series struct {
+	Name   string
+	Values []float64
+}
+
+// lineChart рисует линейный график долей (0..1) в процентах с подписями по оси X и легендой
+func lineChart(labels []string, rows []series) string {
+	const (
+		width   = 640
+		plotH   = 180
+		left    = 44
+		right   = 16
+		top     = 10
+		legendH = 18
+	)
+	plotW := float64(width - left - right)
+	legendRows := (len(rows) + 2) / 3
+	height := top + plotH + 28 + legendRows*legendH
+	x := func(i int) float64 {
+		if len(labels) < 2 {
+			return left + plotW/2
+		}
+		return left + plotW*float64(i)/float64(len(labels)-1)
+	}
+	y := func(v float64) float64 { return top + plotH*(1-v) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" class="chart" viewBox="0 0 %d %d" width="%d" height="%d" role="img">`, width, height, width, height)
+	for _, v := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`, left, y(v), width-right, y(v))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" font-size="11">%.0f%%</text>`, left-6, y(v)+4, v*100)
+	}
+	// Подписи недель прореживаются, чтобы не налезали друг на друга
+	step := (len(labels) + 7) / 8
+	for i, label := range labels {
+		if i%step == 0 || i == len(labels)-1 {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" font-size="11">%s</text>`, x(i), top+plotH+16, escapeXML(label))
+		}
+	}
+	for n, row := range rows {
+		color := chartColors[n%len(chartColors)]
+		var points []string
+		for i, v := range row.Values {
+			if math.IsNaN(v) {
+				continue
+			}
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(i), y(v)))
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2.5" fill="%s"/>`, x(i), y(v), color)
+		}
+		if len(points) > 1 {
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(points, " "), color)
+		}
+		lx := left + (n%3)*200
+		ly := top + plotH + 28 + (n/3)*legendH
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, lx, ly, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11">%s</text>`, lx+14, ly+9, escapeXML(row.Name))
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
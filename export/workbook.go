@@ -0,0 +1,68 @@
+// sermersys/export/workbook.go
+package export
+
+import (
+	"io"
+
+	"sermersys/i18n"
+	"sermersys/pipeline"
+)
+
+// WriteXLSX записывает книгу Excel по результатам анализа: листы с объектами Google Places,
+// найденными размещениями, отзывами, покрытием платформ и аудитом NAP. Числа записываются
+// числами, ссылки - гиперссылками, строка заголовка закреплена, рейтинги раскрашены
+// цветовой шкалой. Заголовки и названия листов - на языке lang. Результатов может быть
+// несколько (пакетный анализ): первая колонка каждого листа - объект.
+func WriteXLSX(w io.Writer, lang i18n.Lang, results ...*pipeline.Result) error {
+	t := func(key string) string { return i18n.T(lang, key) }
+
+	places := &sheet{name: t("report.sheet.places"), widths: []float64{28, 32, 48, 12, 12, 30, 36, 18, 10, 12}}
+	places.header(i18n.Header(lang, "object", "name", "formatted_address", "lat", "lng", "place_id", "website", "phone", "rating", "user_ratings_total")...)
+	places.scales = []colorScale{{column: 8, min: 1, mid: 3.5, max: 5}}
+
+	listings := &sheet{name: t("report.sheet.listings"), widths: []float64{28, 20, 48, 48, 8, 10, 12, 10, 12}}
+	listings.header(i18n.Header(lang, "object", "platform", "title", "link", "page", "position", "match_score", "rating", "user_ratings")...)
+	listings.scales = []colorScale{{column: 7, min: 1, mid: 3.5, max: 5}, {column: 6, min: 0, mid: 0.75, max: 1}}
+
+	reviewSheet := &sheet{name: t("report.sheet.reviews"), widths: []float64{28, 24, 10, 100}}
+	reviewSheet.header(i18n.Header(lang, "object", "review_author", "review_rating", "review_text")...)
+	reviewSheet.scales = []colorScale{{column: 2, min: 1, mid: 3, max: 5}}
+
+	coverageSheet := &sheet{name: t("report.sheet.coverage"), widths: []float64{28, 20, 10, 10, 12, 48}}
+	coverageSheet.header(i18n.Header(lang, "object", "platform", "found", "position", "match_score", "link")...)
+
+	nap := &sheet{name: t("report.sheet.nap"), widths: []float64{28, 20, 40, 48, 18, 40, 12, 28}}
+	nap.header(i18n.Header(lang, "object", "source", "name", "formatted_address", "phone", "website", "match_score", "status")...)
+	nap.scales = []colorScale{{column: 6, min: 0, mid: 0.75, max: 1}}
+
+	for _, r := range results {
+		object := objectName(r)
+		for _, p := range r.Places {
+			places.add(text(object), text(p.Name), text(p.FormattedAddress), coord(p.Lat), coord(p.Lng), text(p.PlaceID),
+				link(p.Website), text(p.Phone), optional(decimal(p.Rating)), integer(p.UserRatingsTotal))
+		}
+		for _, l := range r.SearchResults {
+			rating, count := 0.0, 0
+			if l.Rating != nil {
+				rating, count = l.Rating.Rating, l.Rating.UserRatingsTotal
+			}
+			listings.add(text(object), text(l.Platform), text(l.Title), link(l.Link),
+				integer(l.Page), integer(l.Position), decimal(l.MatchScore),
+				optional(decimal(rating)), optional(integer(count)))
+		}
+		for _, review := range r.Reviews() {
+			reviewSheet.add(text(object), text(review.Author), optional(integer(review.Rating)), text(review.Text))
+		}
+		for _, c := range coverage(r) {
+			found := t("report.no")
+			if c.Found {
+				found = t("report.yes")
+			}
+			coverageSheet.add(text(object), text(c.Platform), text(found), optional(integer(c.Position)), optional(decimal(c.Score)), link(c.Link))
+		}
+		for _, n := range napAudit(r) {
+			nap.add(text(object), text(n.Source), text(n.Name), text(n.Address), text(n.Phone), link(n.Website), decimal(n.Score), text(t(n.Status)))
+		}
+	}
+	return writeXLSX(w, []*sheet{places, listings, reviewSheet, coverageSheet, nap})
+}
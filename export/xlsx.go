@@ -0,0 +1,305 @@
+// sermersys/export/xlsx.go
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Минимальная запись книг Excel (Office Open XML, .xlsx) без внешних библиотек: листы со строками
+// и числами, гиперссылки, закреплённая строка заголовка, автофильтр и цветовая шкала для
+// условного форматирования. Строки записываются прямо в ячейки (inlineStr), без таблицы общих строк.
+
+// Стили ячеек - индексы cellXfs в styles.xml
+const (
+	styleDefault = iota
+	styleHeader
+	styleLink
+	styleDecimal // 0.00
+	styleInteger // 0
+	styleCoord   // 0.000000
+)
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="0.000000"/></numFmts>
+<fonts count="3">
+<font><sz val="11"/><name val="Calibri"/></font>
+<font><b/><sz val="11"/><name val="Calibri"/></font>
+<font><u/><sz val="11"/><color rgb="FF0563C1"/><name val="Calibri"/></font>
+</fonts>
+<fills count="3">
+<fill><patternFill patternType="none"/></fill>
+<fill><patternFill patternType="gray125"/></fill>
+<fill><patternFill patternType="solid"><fgColor rgb="FFDDEBF7"/><bgColor indexed="64"/></patternFill></fill>
+</fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="6">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>
+<xf numFmtId="0" fontId="2" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="1" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+</cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
+
+// cell - значение ячейки: строка, число или ссылка; пустая ячейка не записывается
+type cell struct {
+	text   string
+	number float64
+	kind   byte // 0 - пусто, 's' - строка, 'n' - число, 'l' - ссылка
+	style  int
+}
+
+func text(s string) cell { return cell{text: s, kind: 's'} }
+
+func link(url string) cell {
+	if url == "" {
+		return cell{}
+	}
+	return cell{text: url, kind: 'l', style: styleLink}
+}
+
+func decimal(v float64) cell { return cell{number: v, kind: 'n', style: styleDecimal} }
+func integer(v int) cell     { return cell{number: float64(v), kind: 'n', style: styleInteger} }
+func coord(v float64) cell   { return cell{number: v, kind: 'n', style: styleCoord} }
+
+// optional возвращает пустую ячейку вместо нуля (например, объект без рейтинга)
+func optional(c cell) cell {
+	if c.kind == 'n' && c.number == 0 {
+		return cell{}
+	}
+	return c
+}
+
+// colorScale - цветовая шкала от красного (min) через жёлтый (mid) к зелёному (max) для столбца
+type colorScale struct {
+	column        int
+	min, mid, max float64
+}
+
+// sheet - лист книги; первая строка rows - заголовок
+type sheet struct {
+	name   string
+	widths []float64 // ширина столбцов в символах
+	rows   [][]cell
+	scales []colorScale
+}
+
+func (s *sheet) header(labels ...string) {
+	row := make([]cell, len(labels))
+	for i, label := range labels {
+		row[i] = cell{text: label, kind: 's', style: styleHeader}
+	}
+	s.rows = append(s.rows, row)
+}
+
+func (s *sheet) add(cells ...cell) {
+	s.rows = append(s.rows, cells)
+}
+
+// writeXLSX записывает книгу из листов sheets
+func writeXLSX(w io.Writer, sheets []*sheet) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name, content string
+	}{
+		{"[Content_Types].xml", contentTypes(len(sheets))},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+		{"xl/workbook.xml", workbookXML(sheets)},
+		{"xl/_rels/workbook.xml.rels", workbookRels(len(sheets))},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, s := range sheets {
+		content, rels := sheetXML(s)
+		files = append(files, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), content})
+		if rels != "" {
+			files = append(files, struct{ name, content string }{fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", i+1), rels})
+		}
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func contentTypes(sheets int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+"\n", i)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func workbookXML(sheets []*sheet) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>
+`)
+	for i, s := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`+"\n", escapeXML(sheetName(s.name)), i+1, i+1)
+	}
+	b.WriteString("</sheets>\n<definedNames>\n")
+	// Автофильтр Excel хранит как скрытое имя _FilterDatabase листа
+	for i, s := range sheets {
+		if len(s.rows) > 1 {
+			fmt.Fprintf(&b, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">'%s'!%s</definedName>`+"\n",
+				i, escapeXML(strings.ReplaceAll(sheetName(s.name), "'", "''")), absRange(s))
+		}
+	}
+	b.WriteString("</definedNames>\n</workbook>")
+	return b.String()
+}
+
+func workbookRels(sheets int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`+"\n", i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`+"\n", sheets+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// sheetXML возвращает содержимое листа и его связи (адреса гиперссылок)
+func sheetXML(s *sheet) (string, string) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/><selection pane="bottomLeft" activeCell="A2" sqref="A2"/></sheetView></sheetViews>
+`)
+	if len(s.widths) > 0 {
+		b.WriteString("<cols>")
+		for i, width := range s.widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+		}
+		b.WriteString("</cols>\n")
+	}
+
+	var links []string // ячейка и адрес по порядку rId
+	var linkRefs []string
+	b.WriteString("<sheetData>\n")
+	for r, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, v := range row {
+			ref := columnName(c) + strconv.Itoa(r+1)
+			switch v.kind {
+			case 'n':
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, v.style, strconv.FormatFloat(v.number, 'f', -1, 64))
+			case 's', 'l':
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, v.style, escapeXML(truncateCell(v.text)))
+				if v.kind == 'l' {
+					links = append(links, v.text)
+					linkRefs = append(linkRefs, ref)
+				}
+			}
+		}
+		b.WriteString("</row>\n")
+	}
+	b.WriteString("</sheetData>\n")
+	if len(s.rows) > 1 {
+		fmt.Fprintf(&b, "<autoFilter ref=\"%s\"/>\n", strings.ReplaceAll(absRange(s), "$", ""))
+	}
+	for i, scale := range s.scales {
+		if len(s.rows) < 2 {
+			break
+		}
+		col := columnName(scale.column)
+		fmt.Fprintf(&b, `<conditionalFormatting sqref="%s2:%s%d"><cfRule type="colorScale" priority="%d"><colorScale>`+
+			`<cfvo type="num" val="%g"/><cfvo type="num" val="%g"/><cfvo type="num" val="%g"/>`+
+			`<color rgb="FFF8696B"/><color rgb="FFFFEB84"/><color rgb="FF63BE7B"/></colorScale></cfRule></conditionalFormatting>`+"\n",
+			col, col, len(s.rows), i+1, scale.min, scale.mid, scale.max)
+	}
+	if len(links) == 0 {
+		b.WriteString("</worksheet>")
+		return b.String(), ""
+	}
+
+	var rels strings.Builder
+	rels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+`)
+	b.WriteString("<hyperlinks>")
+	for i, target := range links {
+		fmt.Fprintf(&b, `<hyperlink ref="%s" r:id="rId%d"/>`, linkRefs[i], i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="%s" TargetMode="External"/>`+"\n", i+1, escapeXML(target))
+	}
+	b.WriteString("</hyperlinks>\n</worksheet>")
+	rels.WriteString(`</Relationships>`)
+	return b.String(), rels.String()
+}
+
+// absRange возвращает диапазон листа с заголовком, например $A$1:$F$12
+func absRange(s *sheet) string {
+	columns := 0
+	for _, row := range s.rows {
+		columns = max(columns, len(row))
+	}
+	return fmt.Sprintf("$A$1:$%s$%d", columnName(max(columns, 1)-1), len(s.rows))
+}
+
+// columnName возвращает букву столбца по индексу с нуля: 0 - A, 26 - AA
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// sheetName приводит имя листа к ограничениям Excel: до 31 символа, без []:*?/\
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	return name
+}
+
+// truncateCell обрезает текст до предела ячейки Excel (32767 символов)
+func truncateCell(s string) string {
+	if runes := []rune(s); len(runes) > 32767 {
+		return string(runes[:32767])
+	}
+	return s
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
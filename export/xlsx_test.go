@@ -0,0 +1,106 @@
+// sermersys/export/xlsx_test.go
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"sermersys/i18n"
+)
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, i18n.RU, sampleResult(), sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("книга не открывается как zip: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+		// Каждая часть книги - корректный XML
+		decoder := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: неверный XML: %v", f.Name, err)
+			}
+		}
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("в книге нет %s", name)
+		}
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal([]byte(files["xl/workbook.xml"]), &workbook); err != nil {
+		t.Fatal(err)
+	}
+	if len(workbook.Sheets) != 5 {
+		t.Fatalf("листов %d, ожидалось 5", len(workbook.Sheets))
+	}
+	if workbook.Sheets[0].Name != i18n.T(i18n.RU, "report.sheet.places") {
+		t.Errorf("первый лист: %q", workbook.Sheets[0].Name)
+	}
+	for i := range workbook.Sheets {
+		name := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		if _, ok := files[name]; !ok {
+			t.Errorf("нет листа %s", name)
+		}
+		if !strings.Contains(files["[Content_Types].xml"], "/"+name) {
+			t.Errorf("%s не объявлен в [Content_Types].xml", name)
+		}
+	}
+
+	// Лист размещений: строка заголовка и по две ссылки на каждый из двух анализов
+	var listings struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal([]byte(files["xl/worksheets/sheet2.xml"]), &listings); err != nil {
+		t.Fatal(err)
+	}
+	if len(listings.Rows) != 5 {
+		t.Fatalf("строк на листе размещений %d, ожидалось 5", len(listings.Rows))
+	}
+	first := listings.Rows[1].Cells
+	if first[2].Inline != "Hotel Adriatic & Spa <Budva>" {
+		t.Errorf("заголовок ссылки: %q", first[2].Inline)
+	}
+	// Позиция записана числом, а не строкой
+	if first[5].Type == "inlineStr" || first[5].Value != "2" {
+		t.Errorf("позиция: %+v", first[5])
+	}
+	if !strings.Contains(files["xl/worksheets/_rels/sheet2.xml.rels"], "aid=1&amp;lang=en") {
+		t.Errorf("гиперссылка не записана в связи листа")
+	}
+}
@@ -0,0 +1,202 @@
+// sermersys/fixture/fixture.go
+package fixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sermersys/metrics"
+)
+
+// Режимы работы транспорта
+const (
+	ModeOff    = "off"    // запросы идут напрямую в Google API (так же трактуется пустая строка)
+	ModeRecord = "record" // запросы идут в Google API, пары запрос-ответ сохраняются в каталог
+	ModeReplay = "replay" // ответы берутся из каталога, сеть не используется
+)
+
+// redacted подставляется вместо значений ключей API в сохранённых записях
+const redacted = "REDACTED"
+
+// secretParams - параметры запроса, значения которых не попадают в записи и не влияют на их поиск
+var secretParams = []string{"key"}
+
+// ErrNotRecorded - в режиме replay для запроса нет сохранённой записи
+var ErrNotRecorded = errors.New("запрос не записан")
+
+// Entry - сохранённая пара запрос-ответ
+type Entry struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// Transport - http.RoundTripper, который записывает или воспроизводит ответы Google API
+type Transport struct {
+	Mode string
+	Dir  string
+	Base http.RoundTripper // используется в режимах off и record; по умолчанию http.DefaultTransport
+}
+
+// Enabled сообщает, что режим требует записи или воспроизведения
+func Enabled(mode string) bool {
+	return mode == ModeRecord || mode == ModeReplay
+}
+
+// ValidMode сообщает, поддерживается ли режим
+func ValidMode(mode string) bool {
+	switch mode {
+	case "", ModeOff, ModeRecord, ModeReplay:
+		return true
+	}
+	return false
+}
+
+// RoundTrip выполняет запрос в соответствии с режимом транспорта
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch t.Mode {
+	case ModeReplay:
+		return t.replay(req)
+	case ModeRecord:
+		return t.record(req)
+	default:
+		return t.base().RoundTrip(req)
+	}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// record выполняет запрос и сохраняет ответ в каталог
+func (t *Transport) record(req *http.Request) (*http.Response, error) {
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ответа для записи: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := Entry{
+		Method: req.Method,
+		URL:    redactURL(req.URL),
+		Status: resp.StatusCode,
+		Header: resp.Header.Clone(),
+		Body:   redactBody(string(body), req.URL),
+	}
+	if err := t.save(req, entry); err != nil {
+		return nil, err
+	}
+	metrics.CacheRequests.Inc("fixtures", "store")
+	return resp, nil
+}
+
+// replay возвращает сохранённый ответ на запрос
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	path := t.path(req)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		metrics.CacheRequests.Inc("fixtures", "miss")
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, redactURL(req.URL))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения записи %s: %w", path, err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга записи %s: %w", path, err)
+	}
+	metrics.CacheRequests.Inc("fixtures", "hit")
+	header := entry.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+		StatusCode:    entry.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}, nil
+}
+
+// save атомарно записывает запись в каталог
+func (t *Transport) save(req *http.Request, entry Entry) error {
+	if err := os.MkdirAll(t.Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("ошибка создания каталога записей: %w", err)
+	}
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(entry); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(t.Dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла записи: %w", err)
+	}
+	if _, err := tmp.Write(data.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("ошибка записи файла записи: %w", err)
+	}
+	tmp.Close()
+	return os.Rename(tmp.Name(), t.path(req))
+}
+
+// path возвращает файл записи для запроса. Имя зависит от метода, адреса и параметров
+// запроса без ключей API, поэтому записанную сессию можно воспроизвести с другим ключом.
+func (t *Transport) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + redactURL(req.URL)))
+	host := strings.NewReplacer(".", "_", ":", "_").Replace(req.URL.Host)
+	return filepath.Join(t.Dir, host+"-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// redactURL возвращает адрес с заменёнными ключами API и отсортированными параметрами
+func redactURL(u *url.URL) string {
+	clean := *u
+	q := clean.Query()
+	for _, name := range secretParams {
+		if q.Has(name) {
+			q.Set(name, redacted)
+		}
+	}
+	clean.RawQuery = q.Encode() // Encode сортирует параметры по имени
+	return clean.String()
+}
+
+// redactBody убирает ключи API, если сервис повторил их в теле ответа
+func redactBody(body string, u *url.URL) string {
+	q := u.Query()
+	for _, name := range secretParams {
+		if value := q.Get(name); value != "" {
+			body = strings.ReplaceAll(body, value, redacted)
+		}
+	}
+	return body
+}
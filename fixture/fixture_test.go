@@ -0,0 +1,99 @@
+// sermersys/fixture/fixture_test.go
+package fixture
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func get(t *testing.T, client *http.Client, url string) (int, string, error) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// Сервис повторяет ключ в теле ответа, как Google API в сообщениях об ошибках
+		fmt.Fprintf(w, `{"status":"OK","query":%q,"echo":"key %s"}`, r.URL.Query().Get("query"), r.URL.Query().Get("key"))
+	}))
+	defer upstream.Close()
+	dir := t.TempDir()
+
+	recorder := &http.Client{Transport: &Transport{Mode: ModeRecord, Dir: dir}}
+	status, body, err := get(t, recorder, upstream.URL+"/textsearch?query=Hotel+Adriatic&key=AIza-secret-1")
+	if err != nil || status != http.StatusOK || !strings.Contains(body, "AIza-secret-1") {
+		t.Fatalf("record: %d %s %v", status, body, err)
+	}
+
+	// В записях нет ключа ни в адресе, ни в теле ответа
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("записей %d: %v", len(files), err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "AIza-secret-1") || !strings.Contains(string(data), "key="+redacted) {
+		t.Errorf("ключ API попал в запись: %s", data)
+	}
+
+	// Воспроизведение с другим ключом и другим порядком параметров находит ту же запись без сети
+	player := &http.Client{Transport: &Transport{Mode: ModeReplay, Dir: dir}}
+	status, body, err = get(t, player, upstream.URL+"/textsearch?key=AIza-other-2&query=Hotel+Adriatic")
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("replay: %d %v", status, err)
+	}
+	if !strings.Contains(body, `"query":"Hotel Adriatic"`) || !strings.Contains(body, "key "+redacted) {
+		t.Errorf("replay: %s", body)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("обращений к сервису %d, ожидалось 1", calls.Load())
+	}
+
+	// Запрос, которого нет в записях; http.Client добавил бы к ошибке исходный адрес, поэтому напрямую
+	req, err := http.NewRequest(http.MethodGet, upstream.URL+"/textsearch?query=Hotel+Mogren&key=AIza-other-2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = player.Transport.RoundTrip(req)
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("ожидалась ErrNotRecorded, получено %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "AIza-other-2") {
+		t.Errorf("ключ API в тексте ошибки: %v", err)
+	}
+}
+
+func TestModes(t *testing.T) {
+	for mode, want := range map[string][2]bool{
+		"":         {true, false},
+		ModeOff:    {true, false},
+		ModeRecord: {true, true},
+		ModeReplay: {true, true},
+		"replay!":  {false, false},
+	} {
+		if ValidMode(mode) != want[0] || Enabled(mode) != want[1] {
+			t.Errorf("режим %q: ValidMode %v, Enabled %v", mode, ValidMode(mode), Enabled(mode))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// sermersys/googleapi/googleapi.go
+package googleapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"sermersys/logging"
+	"sermersys/metrics"
+	"sermersys/tracing"
+)
+
+// Общие для клиентов mapsearchg и googlesearch запросы к Google API: GET с ключом, журнал,
+// метрики и спан на каждый запрос, а также повтор запросов, упёршихся в квоту.
+
+// StatusOverQueryLimit - статус Google Places API при превышении квоты запросов
+const StatusOverQueryLimit = "OVER_QUERY_LIMIT"
+
+// HTTPError - Google API ответил кодом, отличным от 200
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Message    string // error.message из тела ответа, если есть
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s: %s", e.Status, e.Message)
+	}
+	return e.Status
+}
+
+// GetJSON выполняет GET-запрос к baseURL с параметрами и ключом API и разбирает JSON-ответ в v.
+// На ответы с кодом, отличным от 200, возвращает *HTTPError. api - имя API в метриках,
+// журнале и трассировке запросов к Google.
+func GetJSON(ctx context.Context, client *http.Client, apiKey, api, baseURL string, params url.Values, v interface{}) (err error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("невалидный baseURL: %w", err)
+	}
+	q := u.Query()
+	for key, values := range params {
+		q[key] = values
+	}
+	q.Set("key", apiKey)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	// Каждый запрос - одна запись в журнале и метриках и один спан
+	start := time.Now()
+	ctx, span := tracing.StartUpstream(ctx, api, u)
+	status, httpStatus := "", 0
+	defer func() {
+		metrics.ObserveUpstream(api, status, start)
+		logging.Upstream(ctx, api, u, status, httpStatus, start, err)
+		tracing.EndUpstream(span, status, httpStatus, err)
+	}()
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Ошибка HTTP-клиента содержит URL с ключом API
+		err = logging.RedactError(err)
+		status = metrics.ErrorStatus(err)
+		return fmt.Errorf("ошибка GET запроса: %w", err)
+	}
+	defer resp.Body.Close()
+	httpStatus = resp.StatusCode
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		status = metrics.ErrorStatus(err)
+		return fmt.Errorf("ошибка чтения ответа: %w", err)
+	}
+	status = metrics.UpstreamStatus(resp.StatusCode, body)
+	if resp.StatusCode != http.StatusOK {
+		httpErr := &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil {
+			httpErr.Message = apiErr.Error.Message
+		}
+		return httpErr
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+	return nil
+}
+
+// Retry вызывает do, пока оно сообщает, что запрос упёрся в квоту, но не больше retries
+// повторов. Пауза перед повтором начинается с delay и удваивается. Возвращает ошибку
+// последнего вызова или ошибку контекста, если он отменён во время паузы.
+func Retry(ctx context.Context, retries int, delay time.Duration, do func() (overQuota bool, err error)) error {
+	for attempt := 0; ; attempt++ {
+		overQuota, err := do()
+		if !overQuota || attempt >= retries {
+			return err
+		}
+		if err := SleepContext(ctx, delay<<attempt); err != nil {
+			return err
+		}
+	}
+}
+
+// SleepContext ждёт d или отмены ctx
+func SleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
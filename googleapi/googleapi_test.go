@@ -0,0 +1,53 @@
+// sermersys/googleapi/googleapi_test.go
+package googleapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	errDenied := errors.New("REQUEST_DENIED")
+	cases := []struct {
+		name    string
+		results []bool // признак квоты по попыткам
+		err     error
+		calls   int
+	}{
+		{"успех с первого раза", []bool{false}, nil, 1},
+		{"успех после повтора", []bool{true, false}, nil, 2},
+		{"повторы исчерпаны", []bool{true, true, true, true}, nil, 3},
+		{"ошибка без квоты не повторяется", []bool{false}, errDenied, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			calls := 0
+			err := Retry(context.Background(), 2, time.Millisecond, func() (bool, error) {
+				overQuota := c.results[calls]
+				calls++
+				return overQuota, c.err
+			})
+			if !errors.Is(err, c.err) && err != c.err {
+				t.Errorf("ошибка %v, ожидалась %v", err, c.err)
+			}
+			if calls != c.calls {
+				t.Errorf("вызовов %d, ожидалось %d", calls, c.calls)
+			}
+		})
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Retry(ctx, 5, time.Hour, func() (bool, error) {
+		calls++
+		cancel()
+		return true, nil
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Fatalf("ожидалась отмена после первой попытки: %v, вызовов %d", err, calls)
+	}
+}
@@ -0,0 +1,209 @@
+// sermersys/googlesearch/client.go
+package googlesearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sermersys/config"
+	"sermersys/googleapi"
+	"sermersys/metrics"
+	"sermersys/tracing"
+)
+
+// Client - клиент Google Custom Search и Places API (рейтинг и отзывы).
+// HTTP-клиент и адреса API задаются явно, что позволяет подменять их (например, на httptest-сервер).
+type Client struct {
+	HTTP            *http.Client
+	APIKey          string
+	CX              string
+	CustomSearchURL string
+	FindPlaceURL    string
+	DetailsURL      string
+	MaxPages        int           // сколько страниц выдачи CSE просматривать (по 10 результатов)
+	Retries         int           // сколько раз повторять запрос Places на OVER_QUERY_LIMIT
+	RetryDelay      time.Duration // пауза перед первым повтором, дальше удваивается
+}
+
+// NewClient создаёт клиент по конфигурации
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		HTTP:            cfg.HTTPClient(),
+		APIKey:          cfg.GoogleAPIKey,
+		CX:              cfg.GoogleCX,
+		CustomSearchURL: cfg.Endpoints.CustomSearch,
+		FindPlaceURL:    cfg.Endpoints.PlacesFindPlace,
+		DetailsURL:      cfg.Endpoints.PlacesDetails,
+		MaxPages:        3,
+		Retries:         2,
+		RetryDelay:      time.Second,
+	}
+}
+
+// **Функция поиска Google CSE с поддержкой проверки заголовков**
+// Для каждой платформы сохраняется первая (самая высокая) позиция в выдаче.
+// Вторым значением возвращается разбор каждого просмотренного результата.
+// Просмотр выдачи прекращается, если CSE не сообщает о следующей странице.
+func (c *Client) Search(ctx context.Context, query string, platforms []string, matcher *TitleMatcher) (map[string]Hit, []Explanation) {
+	links := make(map[string]Hit)
+	var explanations []Explanation
+	examined := 0
+
+	for start := 1; start <= max(c.MaxPages, 1)*10 && ctx.Err() == nil; start += 10 {
+		page := (start-1)/10 + 1
+		params := url.Values{}
+		params.Set("cx", c.CX)
+		params.Set("q", query)
+		params.Set("start", fmt.Sprintf("%d", start))
+
+		var result CustomSearchResponse
+		if err := c.getJSON(ctx, "customsearch", c.CustomSearchURL, params, &result); err != nil {
+			explanations = append(explanations, Explanation{Query: query, Page: page, Rule: RuleRequestFailed, Detail: err.Error()})
+			break
+		}
+
+		if len(result.Items) == 0 {
+			explanations = append(explanations, Explanation{Query: query, Page: page, Rule: RuleNoResults})
+			break
+		}
+
+		for idx, item := range result.Items {
+			examined++
+			ok, score := matcher.Match(item.Title)
+			explanation := Explanation{
+				Query:    query,
+				Platform: matchPlatform(item.Link, platforms),
+				Page:     page,
+				Index:    idx + 1,
+				Position: start + idx,
+				Title:    item.Title,
+				Link:     item.Link,
+				Score:    score,
+			}
+
+			switch {
+			case explanation.Platform == "":
+				explanation.Rule = RuleDomainMismatch
+			case !ok:
+				explanation.Rule = RuleTitleMismatch
+				explanation.Detail = fmt.Sprintf("оценка %.2f ниже порога %.2f", score, matcher.Threshold)
+			default:
+				explanation.Rule = RuleAccepted
+				for _, platform := range platforms {
+					if !strings.Contains(item.Link, platform) {
+						continue
+					}
+					if _, seen := links[platform]; seen {
+						explanation.Rule = RuleDuplicate
+						continue
+					}
+					explanation.Rule = RuleAccepted
+					links[platform] = Hit{
+						Title:    item.Title,
+						Link:     item.Link,
+						Page:     page,
+						Index:    idx + 1,
+						Position: start + idx,
+						Score:    score,
+					}
+				}
+			}
+			explanations = append(explanations, explanation)
+		}
+
+		if len(result.Queries.NextPage) == 0 {
+			break
+		}
+	}
+
+	for _, platform := range platforms {
+		if _, ok := links[platform]; !ok {
+			explanations = append(explanations, Explanation{
+				Query:    query,
+				Platform: platform,
+				Rule:     RuleNotFound,
+				Detail:   fmt.Sprintf("просмотрено результатов: %d", examined),
+			})
+		}
+	}
+	return links, explanations
+}
+
+// PlaceDetails получает рейтинг и отзывы о месте из Google Places API:
+// сначала находит place_id через Find Place, затем запрашивает Place Details.
+// Если место не найдено, возвращает nil без ошибки.
+func (c *Client) PlaceDetails(ctx context.Context, hotelName, city string) (_ *PlaceDetails, err error) {
+	defer metrics.Since(metrics.StepRating, time.Now())
+	ctx, span := tracing.Start(ctx, "rating")
+	defer func() { tracing.End(span, err) }()
+	params := url.Values{}
+	params.Set("input", fmt.Sprintf("%s, %s", hotelName, city))
+	params.Set("inputtype", "textquery")
+	params.Set("fields", "place_id")
+
+	var found findPlaceResponse
+	err = googleapi.Retry(ctx, c.Retries, c.RetryDelay, func() (bool, error) {
+		found = findPlaceResponse{}
+		err := c.getJSON(ctx, "places_findplace", c.FindPlaceURL, params, &found)
+		return err == nil && found.Status == googleapi.StatusOverQueryLimit, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса к Google Places API: %w", err)
+	}
+	switch found.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return nil, nil
+	default:
+		return nil, placesStatusError("findplacefromtext", found.Status, found.ErrorMessage)
+	}
+	if len(found.Candidates) == 0 {
+		return nil, nil
+	}
+	span.SetAttributes(attribute.String("place_id", found.Candidates[0].PlaceID))
+
+	params = url.Values{}
+	params.Set("place_id", found.Candidates[0].PlaceID)
+	params.Set("fields", "name,rating,user_ratings_total,reviews")
+
+	var details PlaceDetails
+	err = googleapi.Retry(ctx, c.Retries, c.RetryDelay, func() (bool, error) {
+		details = PlaceDetails{}
+		err := c.getJSON(ctx, "places_details", c.DetailsURL, params, &details)
+		return err == nil && details.Status == googleapi.StatusOverQueryLimit, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса к Google Places API: %w", err)
+	}
+	if details.Status != "OK" {
+		return nil, placesStatusError("details", details.Status, details.ErrorMessage)
+	}
+	return &details, nil
+}
+
+// findPlaceResponse - ответ Find Place API
+type findPlaceResponse struct {
+	Candidates []struct {
+		PlaceID string `json:"place_id"`
+	} `json:"candidates"`
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+func placesStatusError(api, status, message string) error {
+	if message != "" {
+		return fmt.Errorf("Google Places %s вернул статус %s: %s", api, status, message)
+	}
+	return fmt.Errorf("Google Places %s вернул статус %s", api, status)
+}
+
+// getJSON выполняет GET-запрос к Google API (см. googleapi.GetJSON)
+func (c *Client) getJSON(ctx context.Context, api, baseURL string, params url.Values, v interface{}) error {
+	return googleapi.GetJSON(ctx, c.HTTP, c.APIKey, api, baseURL, params, v)
+}
@@ -0,0 +1,236 @@
+// sermersys/googlesearch/client_test.go
+package googlesearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient создаёт клиент поддельных Google CSE и Places API
+func newTestClient(t *testing.T, handlers map[string]http.HandlerFunc) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	for path, h := range handlers {
+		mux.HandleFunc(path, h)
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return &Client{
+		HTTP:            srv.Client(),
+		APIKey:          "test-key",
+		CX:              "test-cx",
+		CustomSearchURL: srv.URL + "/customsearch/v1",
+		FindPlaceURL:    srv.URL + "/findplacefromtext/json",
+		DetailsURL:      srv.URL + "/details/json",
+		MaxPages:        3,
+		Retries:         2,
+		RetryDelay:      time.Millisecond,
+	}
+}
+
+// rules возвращает правила разбора в порядке следования
+func rules(explanations []Explanation) []string {
+	var out []string
+	for _, e := range explanations {
+		out = append(out, e.Rule)
+	}
+	return out
+}
+
+func TestSearchPagination(t *testing.T) {
+	var starts []string
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"/customsearch/v1": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if q.Get("key") != "test-key" || q.Get("cx") != "test-cx" {
+				t.Errorf("ключ или cx не переданы: %s", r.URL.RawQuery)
+			}
+			starts = append(starts, q.Get("start"))
+			switch q.Get("start") {
+			case "1":
+				fmt.Fprint(w, `{"items":[{"title":"Hotel Adriatic Budva - Booking.com","link":"https://www.booking.com/hotel/me/adriatic.html"},{"title":"Adriatic Budva - Wikipedia","link":"https://en.wikipedia.org/wiki/Adriatic"}],"queries":{"nextPage":[{"startIndex":11}]}}`)
+			case "11":
+				fmt.Fprint(w, `{"items":[{"title":"Hotel Adriatic, Budva - Tripadvisor","link":"https://www.tripadvisor.com/Hotel_Review-adriatic"},{"title":"Adriatic Budva, Montenegro","link":"https://www.booking.com/hotel/me/adriatic.de.html"}]}`)
+			default:
+				t.Errorf("лишняя страница: start=%s", q.Get("start"))
+			}
+		},
+	})
+
+	links, explanations := c.Search(context.Background(), "Hotel Adriatic Budva", []string{"booking.com", "tripadvisor.com", "expedia.com"}, NewTitleMatcher("Hotel Adriatic", "en", 0))
+
+	if strings.Join(starts, ",") != "1,11" {
+		t.Errorf("запрошены страницы %v, ожидалось 1,11 (без nextPage просмотр прекращается)", starts)
+	}
+	if hit := links["booking.com"]; hit.Position != 1 || hit.Page != 1 {
+		t.Errorf("booking.com: %+v, ожидалась позиция 1", hit)
+	}
+	if hit := links["tripadvisor.com"]; hit.Position != 11 || hit.Page != 2 || hit.Index != 1 {
+		t.Errorf("tripadvisor.com: %+v, ожидалась позиция 11 на второй странице", hit)
+	}
+	if _, ok := links["expedia.com"]; ok {
+		t.Errorf("expedia.com не должна быть найдена")
+	}
+	want := []string{RuleAccepted, RuleDomainMismatch, RuleAccepted, RuleDuplicate, RuleNotFound}
+	if got := rules(explanations); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("правила %v, ожидалось %v", got, want)
+	}
+	if last := explanations[len(explanations)-1]; last.Platform != "expedia.com" || last.Detail != "просмотрено результатов: 4" {
+		t.Errorf("итог по expedia.com: %+v", last)
+	}
+}
+
+func TestSearchMaxPages(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"/customsearch/v1": func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			fmt.Fprint(w, `{"items":[{"title":"Other","link":"https://example.com/"}],"queries":{"nextPage":[{"startIndex":11}]}}`)
+		},
+	})
+	c.MaxPages = 2
+
+	c.Search(context.Background(), "Hotel Adriatic Budva", []string{"booking.com"}, NewTitleMatcher("Hotel Adriatic", "en", 0))
+	if n := calls.Load(); n != 2 {
+		t.Errorf("запросов %d, ожидалось 2 (MaxPages)", n)
+	}
+}
+
+func TestSearchNoResults(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"/customsearch/v1": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"searchInformation":{"totalResults":"0"}}`)
+		},
+	})
+
+	links, explanations := c.Search(context.Background(), "Nowhere Hotel", []string{"booking.com"}, NewTitleMatcher("Nowhere Hotel", "en", 0))
+	if len(links) != 0 {
+		t.Errorf("ожидался пустой результат: %+v", links)
+	}
+	if got := rules(explanations); strings.Join(got, ",") != RuleNoResults+","+RuleNotFound {
+		t.Errorf("правила %v", got)
+	}
+}
+
+func TestSearchMalformedJSON(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"/customsearch/v1": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"items":[{"title":"Hotel`)
+		},
+	})
+
+	_, explanations := c.Search(context.Background(), "Hotel Adriatic", []string{"booking.com"}, NewTitleMatcher("Hotel Adriatic", "en", 0))
+	if len(explanations) == 0 || explanations[0].Rule != RuleRequestFailed || !strings.Contains(explanations[0].Detail, "парсинга JSON") {
+		t.Fatalf("ожидался request_failed с ошибкой разбора JSON: %+v", explanations)
+	}
+}
+
+func TestSearchHTTPError(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"/customsearch/v1": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"code":429,"message":"Quota exceeded for quota metric 'Queries'"}}`)
+		},
+	})
+
+	_, explanations := c.Search(context.Background(), "Hotel Adriatic", []string{"booking.com"}, NewTitleMatcher("Hotel Adriatic", "en", 0))
+	if len(explanations) == 0 || explanations[0].Rule != RuleRequestFailed {
+		t.Fatalf("ожидался request_failed: %+v", explanations)
+	}
+	if detail := explanations[0].Detail; !strings.Contains(detail, "429") || !strings.Contains(detail, "Quota exceeded") || strings.Contains(detail, "test-key") {
+		t.Errorf("неожиданная причина: %q", detail)
+	}
+}
+
+func TestPlaceDetailsZeroResults(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"/findplacefromtext/json": func(w http.ResponseWriter, r *http.Request) {
+			if got := r.URL.Query().Get("input"); got != "Nowhere Hotel, Budva" {
+				t.Errorf("input = %q", got)
+			}
+			fmt.Fprint(w, `{"candidates":[],"status":"ZERO_RESULTS"}`)
+		},
+		"/details/json": func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("Place Details не должен запрашиваться без кандидатов")
+		},
+	})
+
+	details, err := c.PlaceDetails(context.Background(), "Nowhere Hotel", "Budva")
+	if err != nil || details != nil {
+		t.Fatalf("ZERO_RESULTS: получено %+v, %v, ожидалось nil, nil", details, err)
+	}
+}
+
+func TestPlaceDetailsOverQueryLimitRetry(t *testing.T) {
+	var findCalls, detailsCalls atomic.Int32
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"/findplacefromtext/json": func(w http.ResponseWriter, r *http.Request) {
+			if findCalls.Add(1) == 1 {
+				fmt.Fprint(w, `{"status":"OVER_QUERY_LIMIT"}`)
+				return
+			}
+			fmt.Fprint(w, `{"candidates":[{"place_id":"P1"}],"status":"OK"}`)
+		},
+		"/details/json": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("place_id") != "P1" {
+				t.Errorf("place_id = %q", r.URL.Query().Get("place_id"))
+			}
+			if detailsCalls.Add(1) <= 2 {
+				fmt.Fprint(w, `{"status":"OVER_QUERY_LIMIT"}`)
+				return
+			}
+			fmt.Fprint(w, `{"result":{"name":"Hotel Adriatic","rating":4.4,"user_ratings_total":812,"reviews":[{"author_name":"Ana","rating":5,"text":"Great"}]},"status":"OK"}`)
+		},
+	})
+
+	details, err := c.PlaceDetails(context.Background(), "Hotel Adriatic", "Budva")
+	if err != nil {
+		t.Fatalf("после повторов ожидался успех: %v", err)
+	}
+	if details.Result.Rating != 4.4 || details.Result.UserRatingsTotal != 812 || len(details.Result.Reviews) != 1 {
+		t.Errorf("неожиданные детали: %+v", details.Result)
+	}
+	if f, d := findCalls.Load(), detailsCalls.Load(); f != 2 || d != 3 {
+		t.Errorf("запросов Find Place %d и Details %d, ожидалось 2 и 3", f, d)
+	}
+}
+
+func TestPlaceDetailsOverQueryLimitExhausted(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"/findplacefromtext/json": func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			fmt.Fprint(w, `{"status":"OVER_QUERY_LIMIT","error_message":"You have exceeded your daily request quota"}`)
+		},
+	})
+
+	_, err := c.PlaceDetails(context.Background(), "Hotel Adriatic", "Budva")
+	if err == nil || !strings.Contains(err.Error(), "OVER_QUERY_LIMIT") || !strings.Contains(err.Error(), "daily request quota") {
+		t.Fatalf("ожидалась ошибка OVER_QUERY_LIMIT, получено %v", err)
+	}
+	if n := calls.Load(); n != int32(c.Retries+1) {
+		t.Errorf("запросов %d, ожидалось %d", n, c.Retries+1)
+	}
+}
+
+func TestPlaceDetailsMalformedJSON(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"/findplacefromtext/json": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"candidates":[{"place_id":"P1"}],"status":"OK"}`)
+		},
+		"/details/json": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<html>Service Unavailable</html>`)
+		},
+	})
+
+	_, err := c.PlaceDetails(context.Background(), "Hotel Adriatic", "Budva")
+	if err == nil || !strings.Contains(err.Error(), "парсинга JSON") {
+		t.Fatalf("ожидалась ошибка разбора JSON, получено %v", err)
+	}
+}
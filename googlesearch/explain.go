@@ -0,0 +1,56 @@
+package googlesearch
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	"sermersys/i18n"
+	"sermersys/model"
+)
+
+// Правила, по которым результат выдачи принимается или отклоняется
+const (
+	RuleAccepted       = "accepted"        // заголовок совпал, ссылка ведёт на платформу
+	RuleTitleMismatch  = "title_mismatch"  // ссылка ведёт на платформу, но заголовок не совпал с названием
+	RuleDomainMismatch = "domain_mismatch" // ссылка не содержит домен ни одной платформы запроса
+	RuleDuplicate      = "duplicate"       // платформа уже найдена на более высокой позиции
+	RuleNoResults      = "no_results"      // CSE не вернул результатов для страницы
+	RuleRequestFailed  = "request_failed"  // ошибка запроса к CSE
+	RuleNotFound       = "not_found"       // итог по платформе: подходящих результатов нет
+)
+
+// Explanation - разбор одного результата выдачи (или страницы без результатов)
+type Explanation = model.Explanation
+
+// saveExplanationsCSV сохраняет разбор в CSV-файл с заголовками на языке lang
+func saveExplanationsCSV(filename string, lang i18n.Lang, explanations []Explanation) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("Ошибка создания файла разбора: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(i18n.Header(lang, "query", "platform", "page", "index", "position", "title", "link", "rule", "score", "detail"))
+	for _, e := range explanations {
+		writer.Write([]string{
+			e.Query,
+			e.Platform,
+			strconv.Itoa(e.Page),
+			strconv.Itoa(e.Index),
+			strconv.Itoa(e.Position),
+			e.Title,
+			e.Link,
+			e.Rule,
+			fmt.Sprintf("%.2f", e.Score),
+			e.Detail,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("Ошибка записи файла разбора: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package googlesearch
+
+import (
+	"strings"
+	"unicode"
+
+	"sermersys/translit"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMatchThreshold - минимальная доля совпавших слов названия в заголовке
+const DefaultMatchThreshold = 0.75
+
+// minFuzzyTokenLen - слова короче этой длины сравниваются только точно
+const minFuzzyTokenLen = 4
+
+// minTokenSimilarity - минимальное сходство двух слов, чтобы считать их совпавшими
+const minTokenSimilarity = 0.8
+
+// foldReplacer раскладывает буквы, которые не разлагаются Unicode-нормализацией
+var foldReplacer = strings.NewReplacer(
+	"ß", "ss", "ẞ", "ss",
+	"æ", "ae", "Æ", "ae",
+	"œ", "oe", "Œ", "oe",
+	"ø", "o", "Ø", "o",
+	"ł", "l", "Ł", "l",
+	"đ", "d", "Đ", "d",
+	"ı", "i",
+	"&", " and ",
+)
+
+// commonStopWords - слова, которые не различают объекты и удаляются всегда
+var commonStopWords = []string{
+	"hotel", "hotels", "the", "and", "a", "an", "of", "at", "by", "in", "on",
+	"booking", "com", "reviews", "review", "deals", "prices", "price",
+}
+
+// stopWords - стоп-слова по языкам (после свёртки диакритики)
+var stopWords = map[string][]string{
+	"en": {"inn", "suites", "apartments", "apartment", "rooms", "updated", "from"},
+	"de": {"das", "der", "die", "und", "am", "im", "zum", "zur", "von", "garni", "bewertungen", "preise"},
+	"fr": {"le", "la", "les", "de", "du", "des", "et", "avis", "prix"},
+	"es": {"el", "la", "los", "las", "de", "del", "y", "opiniones", "precios"},
+	"it": {"il", "lo", "la", "gli", "le", "di", "del", "e", "recensioni", "prezzi"},
+	"ru": {"отель", "гостиница", "хостел", "и", "в", "на", "отзывы", "цены"},
+}
+
+// TitleMatcher - нечёткое сравнение заголовков выдачи с названием объекта
+//
+// Название и заголовок приводятся к NFKD, из них удаляются диакритические
+// знаки и стоп-слова, после чего считается доля слов названия, найденных
+// в заголовке (с допуском на опечатки для длинных слов).
+// При включённой транслитерации сравниваются также латинские варианты
+// названия и заголовка, и берётся лучшая оценка.
+type TitleMatcher struct {
+	Threshold float64
+	stopWords map[string]bool
+	variants  [][]string // слова названия: исходное написание и транслитерации
+	schemes   []translit.Scheme
+}
+
+// NewTitleMatcher создаёт сравнитель для названия объекта на заданном языке
+func NewTitleMatcher(name, language string, threshold float64) *TitleMatcher {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultMatchThreshold
+	}
+	m := &TitleMatcher{
+		Threshold: threshold,
+		stopWords: buildStopWords(language),
+	}
+	m.variants = [][]string{m.significantTokens(name)}
+	return m
+}
+
+// WithTransliteration включает сравнение по латинским вариантам кириллических и греческих написаний
+func (m *TitleMatcher) WithTransliteration(schemes ...translit.Scheme) *TitleMatcher {
+	if len(schemes) == 0 {
+		schemes = translit.DefaultSchemes
+	}
+	m.schemes = schemes
+	// Транслитерируются значимые слова: стоп-слова («гостиница») в латинице уже не распознаются
+	for _, variant := range translit.LatinVariants(strings.Join(m.variants[0], " "), schemes...) {
+		m.variants = append(m.variants, m.significantTokens(variant))
+	}
+	return m
+}
+
+// Match сравнивает заголовок с названием и возвращает признак совпадения и оценку от 0 до 1
+func (m *TitleMatcher) Match(title string) (bool, float64) {
+	titles := [][]string{tokenize(normalizeText(title))}
+	if len(m.schemes) > 0 && translit.HasNonLatin(title) {
+		for _, variant := range translit.LatinVariants(title, m.schemes...) {
+			titles = append(titles, tokenize(normalizeText(variant)))
+		}
+	}
+
+	best := 0.0
+	for _, nameTokens := range m.variants {
+		for _, titleTokens := range titles {
+			if score := tokenSetScore(nameTokens, titleTokens); score > best {
+				best = score
+			}
+		}
+	}
+	return best >= m.Threshold, best
+}
+
+// tokenSetScore возвращает долю слов названия, найденных среди слов заголовка
+func tokenSetScore(nameTokens, titleTokens []string) float64 {
+	if len(nameTokens) == 0 || len(titleTokens) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, token := range nameTokens {
+		best := 0.0
+		for _, candidate := range titleTokens {
+			if s := tokenSimilarity(token, candidate); s > best {
+				best = s
+				if best == 1 {
+					break
+				}
+			}
+		}
+		total += best
+	}
+	return total / float64(len(nameTokens))
+}
+
+// significantTokens нормализует текст и удаляет стоп-слова; если остаются только стоп-слова, они сохраняются
+func (m *TitleMatcher) significantTokens(text string) []string {
+	all := tokenize(normalizeText(text))
+	var tokens []string
+	for _, token := range all {
+		if !m.stopWords[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) == 0 {
+		return all
+	}
+	return dedupTokens(tokens)
+}
+
+// buildStopWords собирает стоп-слова для языка; без языка используются все списки
+func buildStopWords(language string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range commonStopWords {
+		set[word] = true
+	}
+	language = strings.ToLower(language)
+	for lang, words := range stopWords {
+		if language != "" && lang != language && lang != "en" {
+			continue
+		}
+		for _, word := range words {
+			set[normalizeText(word)] = true
+		}
+	}
+	return set
+}
+
+// normalizeText приводит строку к нижнему регистру без диакритики
+func normalizeText(s string) string {
+	s = foldReplacer.Replace(s)
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+// tokenize делит строку на слова из букв и цифр
+func tokenize(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// dedupTokens удаляет повторяющиеся слова, сохраняя порядок
+func dedupTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	result := tokens[:0]
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// tokenSimilarity возвращает сходство двух слов: 1 для точного совпадения,
+// для длинных слов - нормированное расстояние Левенштейна, если оно не ниже порога
+func tokenSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < minFuzzyTokenLen || len(rb) < minFuzzyTokenLen {
+		return 0
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	similarity := 1 - float64(levenshtein(ra, rb))/float64(longest)
+	if similarity < minTokenSimilarity {
+		return 0
+	}
+	return similarity
+}
+
+// levenshtein считает расстояние редактирования между двумя словами
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
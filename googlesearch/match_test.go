@@ -0,0 +1,97 @@
+// sermersys/googlesearch/match_test.go
+package googlesearch
+
+import (
+	"math"
+	"testing"
+
+	"sermersys/translit"
+)
+
+// Заголовки взяты из выдачи Google CSE по платформам каталога
+func TestTitleMatcherMatch(t *testing.T) {
+	cases := []struct {
+		name      string
+		object    string
+		language  string
+		threshold float64
+		translit  bool
+		title     string
+		match     bool
+		score     float64 // -1 - оценка не проверяется, только признак совпадения
+	}{
+		// Диакритика и лигатуры
+		{"hôtel без диакритики", "Hôtel Le Meurice", "fr", 0, false, "Le Meurice Paris - Hotel Reviews - Tripadvisor", true, 1},
+		{"é и è", "Hôtel Plaza Athénée", "fr", 0, false, "Hotel Plaza Athenee, Paris – Updated 2025 Prices", true, 1},
+		{"ß и ss", "Hotel Schloss Elmau", "de", 0, false, "Schloß Elmau Luxury Spa Retreat | Booking.com", true, 1},
+		{"ss и ß", "Gasthof Weißes Rössl", "de", 0, false, "Gasthof Weisses Roessl am Wolfgangsee", true, -1},
+		{"æ и ø", "Hotel Skt. Petri", "da", 0, false, "Hotel Sankt Petri, København", false, -1},
+
+		// Стоп-слова
+		{"hotel и booking.com не считаются", "Hotel Adlon Kempinski", "de", 0, false, "Adlon Kempinski Berlin - Booking.com", true, 1},
+		{"немецкие артикли", "Hotel Zur Post", "de", 0, false, "Post Hotel - Bewertungen und Preise", true, 1},
+		{"только стоп-слова сохраняются", "The Hotel", "en", 0, false, "The Hotel, Lucerne – Updated Prices", true, 1},
+		{"русские стоп-слова", "Отель Метрополь", "ru", 0, false, "Метрополь, Москва — отзывы и цены", true, 1},
+
+		// Кириллица и латиница
+		{"кириллица без транслитерации", "Гостиница Москва", "ru", 0, false, "Hotel Moskva, Saint Petersburg", false, 0},
+		{"кириллица с транслитерацией", "Гостиница Москва", "ru", 0, true, "Hotel Moskva, Saint Petersburg", true, 1},
+		{"латиница против кириллического заголовка", "Hotel Kosmos", "ru", 0, true, "Космос, Москва — бронирование", true, 1},
+		{"другой объект", "Гостиница Москва", "ru", 0, true, "Hotel Kosmos, Moscow", false, 0},
+
+		// Порог: доля слов названия в заголовке
+		{"3 из 4 слов - ровно порог", "Grand Hotel Villa Serbelloni Bellagio", "it", 0, false, "Villa Serbelloni Bellagio, Lake Como", true, 0.75},
+		{"2 из 4 слов - ниже порога", "Grand Hotel Villa Serbelloni Bellagio", "it", 0, false, "Villa Serbelloni, Lake Como", false, 0.5},
+		{"опечатка чуть выше порога", "Hotel Adlon Kempinski Berlin", "de", 0.8, false, "Adlon Kempinsky Berlin", true, -1},
+		{"опечатка и нет слова - ниже порога", "Hotel Adlon Kempinski Berlin", "de", 0.8, false, "Adlon Kempinsky Potsdam", false, -1},
+		{"4 из 5 слов при пороге 0.8", "Sea View Apartments Budva Riviera Becici", "en", 0.8, false, "Sea View Budva Riviera - Booking.com", true, 0.8},
+		{"3 из 5 слов при пороге 0.8", "Sea View Apartments Budva Riviera Becici", "en", 0.8, false, "Sea View Budva - Booking.com", false, 0.6},
+
+		// Короткие слова сравниваются только точно
+		{"короткие слова без допуска", "Hotel Vila Ana", "en", 0, false, "Hotel Vila Ina", false, -1},
+		{"пустой заголовок", "Hotel Adlon", "de", 0, false, "", false, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := NewTitleMatcher(c.object, c.language, c.threshold)
+			if c.translit {
+				m.WithTransliteration(translit.DefaultSchemes...)
+			}
+			match, score := m.Match(c.title)
+			if match != c.match {
+				t.Errorf("Match(%q) для %q = %v (оценка %.3f), ожидалось %v", c.title, c.object, match, score, c.match)
+			}
+			if c.score >= 0 && math.Abs(score-c.score) > 1e-9 {
+				t.Errorf("Match(%q) для %q: оценка %.3f, ожидалось %.3f", c.title, c.object, score, c.score)
+			}
+		})
+	}
+}
+
+func TestTitleMatcherFuzzyScoreAroundThreshold(t *testing.T) {
+	m := NewTitleMatcher("Kempinski", "", 0)
+	// Одна опечатка в слове из 9 букв - сходство 1 - 1/9, выше порога сходства слов
+	if match, score := m.Match("Kempinsky"); !match || math.Abs(score-(1-1.0/9)) > 1e-9 {
+		t.Errorf("Kempinsky: совпадение %v, оценка %.3f, ожидалось true, %.3f", match, score, 1-1.0/9)
+	}
+	// Две опечатки - сходство 1 - 2/9 ниже minTokenSimilarity, слово не засчитывается
+	if match, score := m.Match("Kampinsky"); match || score != 0 {
+		t.Errorf("Kampinsky: совпадение %v, оценка %.3f, ожидалось false, 0", match, score)
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	cases := map[string]string{
+		"Hôtel":       "hotel",
+		"Straße":      "strasse",
+		"Ærø":         "aero",
+		"Łódź":        "lodz",
+		"Crème & Co":  "creme  and  co",
+		"ГОСТИНИЦА Ё": "гостиница е",
+	}
+	for in, want := range cases {
+		if got := normalizeText(in); got != want {
+			t.Errorf("normalizeText(%q) = %q, ожидалось %q", in, got, want)
+		}
+	}
+}
@@ -1,25 +1,42 @@
 package googlesearch
 
 import (
+	"context"
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
-	"net/http"
-	"net/url"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"sermersys/config"
+	"sermersys/i18n"
+	"sermersys/metrics"
+	"sermersys/model"
+	"sermersys/tracing"
+	"sermersys/translit"
 )
 
 // RequestData - структура входных данных
 type RequestData struct {
-	HotelName     string `json:"hotel_name"`
-	Address       string `json:"address,omitempty"`
-	City          string `json:"city"`
-	Country       string `json:"country"`
-	PlatformsFile string `json:"platforms_file"`
+	HotelName          string   `json:"hotel_name"`
+	Address            string   `json:"address,omitempty"`
+	City               string   `json:"city"`
+	Country            string   `json:"country"`
+	PlatformsFile      string   `json:"platforms_file"`
+	Language           string   `json:"language,omitempty"`             // язык для выбора шаблона запроса
+	QueryTemplatesFile string   `json:"query_templates_file,omitempty"` // файл шаблонов запросов
+	MatchThreshold     float64  `json:"match_threshold,omitempty"`      // порог совпадения заголовка, по умолчанию DefaultMatchThreshold
+	Transliterate      bool     `json:"transliterate,omitempty"`        // пробовать транслитерированные варианты названия
+	TranslitSchemes    []string `json:"translit_schemes,omitempty"`     // схемы: gost, iso9, icao; по умолчанию все
+	Explain            bool     `json:"explain,omitempty"`              // вернуть разбор принятых и отклонённых результатов
+	Locale             string   `json:"locale,omitempty"`               // язык заголовков CSV, по умолчанию английский
 }
 
 // CustomSearchResponse - структура ответа от Google CSE
@@ -29,12 +46,17 @@ type CustomSearchResponse struct {
 		Link    string `json:"link"`
 		Snippet string `json:"snippet"`
 	} `json:"items"`
+	Queries struct {
+		NextPage []struct {
+			StartIndex int `json:"startIndex"`
+		} `json:"nextPage"`
+	} `json:"queries"`
 }
 
 // PlaceDetails - структура ответа от Google Places API
 type PlaceDetails struct {
 	Result struct {
-		Name             string `json:"name"`
+		Name             string  `json:"name"`
 		Rating           float64 `json:"rating"`
 		UserRatingsTotal int     `json:"user_ratings_total"`
 		Reviews          []struct {
@@ -43,177 +65,255 @@ type PlaceDetails struct {
 			Text       string `json:"text"`
 		} `json:"reviews"`
 	} `json:"result"`
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message,omitempty"`
 }
 
-// Config - структура конфигурации
-type Config struct {
-	GoogleAPIKey string `json:"google_api_key"`
-	GoogleCX     string `json:"google_cx"`
+// FetchReport - результат поиска по платформам вместе с разбором решений
+type FetchReport struct {
+	Filename        string
+	ExplainFilename string // CSV с разбором, заполняется при RequestData.Explain
+	Results         []model.Listing
+	Explanations    []Explanation // заполняется при RequestData.Explain
+	Platforms       []string      // платформы, по которым шёл поиск (для покрытия)
 }
 
-// FetchData - выполняет поиск, записывает CSV и возвращает JSON
-func FetchData(data RequestData) (string, []map[string]string, error) {
-	config, err := loadConfig("./config.json")
+// FetchData - выполняет поиск, записывает CSV и возвращает ссылки строками map[string]string.
+//
+// Deprecated: используйте FetchDataExplain, он возвращает типизированные model.Listing.
+func FetchData(cfg *config.Config, data RequestData) (string, []map[string]string, error) {
+	return FetchDataContext(context.Background(), cfg, data)
+}
+
+// FetchDataContext - как FetchData, но прерывается при отмене ctx.
+//
+// Deprecated: используйте FetchDataExplainContext.
+func FetchDataContext(ctx context.Context, cfg *config.Config, data RequestData) (string, []map[string]string, error) {
+	report, err := FetchDataExplainContext(ctx, cfg, data)
 	if err != nil {
-		return "", nil, fmt.Errorf("Ошибка загрузки конфигурации: %v", err)
+		return "", nil, err
+	}
+	return report.Filename, model.ListingMaps(report.Results), nil
+}
+
+// FetchDataExplain - как FetchData, но при data.Explain дополнительно возвращает
+// каждый просмотренный результат выдачи и правило, по которому он принят или отклонён
+func FetchDataExplain(cfg *config.Config, data RequestData) (*FetchReport, error) {
+	return FetchDataExplainContext(context.Background(), cfg, data)
+}
+
+// FetchDataExplainContext - как FetchDataExplain, но прерывается при отмене ctx.
+// При отмене файлы результатов и история позиций не записываются.
+func FetchDataExplainContext(ctx context.Context, cfg *config.Config, data RequestData) (*FetchReport, error) {
+	if data.PlatformsFile == "" {
+		data.PlatformsFile = cfg.PlatformsFile
+	}
+	if data.QueryTemplatesFile == "" {
+		data.QueryTemplatesFile = cfg.QueryTemplatesFile
 	}
+	client := NewClient(cfg)
 
 	platforms, err := loadPlatforms(data.PlatformsFile)
 	if err != nil {
-		return "", nil, fmt.Errorf("Ошибка загрузки платформ: %v", err)
+		return nil, fmt.Errorf("Ошибка загрузки платформ: %v", err)
 	}
 
-	query := buildQuery(data.HotelName, data.City, data.Country)
-
-	// Создаём CSV-файл
-	timestamp := time.Now().Format("20060102150405")
-	filename := fmt.Sprintf("./results/%s-%s.csv", timestamp, strings.ReplaceAll(data.HotelName, " ", "_"))
-	file, err := os.Create(filename)
+	templates, err := loadQueryTemplates(data.QueryTemplatesFile)
 	if err != nil {
-		return "", nil, fmt.Errorf("Ошибка создания файла: %v", err)
+		return nil, fmt.Errorf("Ошибка загрузки шаблонов запросов: %v", err)
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-	writer.Write([]string{"Platform", "Title", "Link", "Rating", "User Ratings", "Review Author", "Review Rating", "Review Text"})
 
 	// Получаем рейтинг и отзывы из Google Places API
-	details, err := getPlaceDetails(config.GoogleAPIKey, data.HotelName, data.City)
-	if err != nil {
-		log.Println("Ошибка при получении данных из Google Places API:", err)
+	var details *PlaceDetails
+	if cfg.Providers.Places {
+		details, err = client.PlaceDetails(ctx, data.HotelName, data.City)
+		if err != nil {
+			slog.WarnContext(ctx, "ошибка при получении рейтинга из Google Places API", "error", err)
+		}
 	}
 
-	// Запускаем поиск по платформам
-	results := []map[string]string{}
-	for i := 0; i < len(platforms); i += 5 {
-		end := i + 5
-		if end > len(platforms) {
-			end = len(platforms)
-		}
+	matcher := NewTitleMatcher(data.HotelName, data.Language, data.MatchThreshold)
+	if data.Transliterate {
+		matcher.WithTransliteration(translit.ParseSchemes(data.TranslitSchemes)...)
+	}
 
-		platformSubset := platforms[i:end]
-		searchQuery := buildSearchQuery(query, platformSubset)
-		links := findLinksGoogleCSE(searchQuery, config.GoogleAPIKey, config.GoogleCX, platformSubset, data.HotelName, 3)
+	// Формируем запросы для каждого написания названия
+	var batches []searchBatch
+	for _, name := range queryNameVariants(data) {
+		variant := data
+		variant.HotelName = name
+		batches = append(batches, planSearchBatches(templates, variant, platforms)...)
+	}
 
-		for platform, item := range links {
-			rating, userRatingsTotal, reviewAuthor, reviewRating, reviewText := "", "", "", "", ""
+	// Запускаем поиск по платформам: запросы идут параллельно (не более cfg.Concurrency),
+	// а результаты обрабатываются в исходном порядке запросов
+	type batchResult struct {
+		links        map[string]Hit
+		explanations []Explanation
+	}
+	batchResults := make([]batchResult, len(batches))
+	sem := make(chan struct{}, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		wg.Add(1)
+		go func(i int, batch searchBatch) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
 
-			if details != nil {
-				rating = fmt.Sprintf("%.1f", details.Result.Rating)
-				userRatingsTotal = fmt.Sprintf("%d", details.Result.UserRatingsTotal)
+			start := time.Now()
+			batchCtx, span := tracing.Start(ctx, "cse_batch", trace.WithAttributes(
+				attribute.String("query", batch.Query),
+				attribute.StringSlice("platforms", batch.Platforms),
+			))
+			links, explanations := client.Search(batchCtx, batch.Query, batch.Platforms, matcher)
+			metrics.Since(metrics.StepCSEBatch, start)
+			span.SetAttributes(
+				attribute.Int("result.count", len(links)),
+				attribute.Int("explanations.count", len(explanations)))
+			span.End()
+			batchResults[i] = batchResult{links, explanations}
+		}(i, batch)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("поиск прерван: %w", err)
+	}
 
-				if len(details.Result.Reviews) > 0 {
-					review := details.Result.Reviews[0]
-					reviewAuthor = review.AuthorName
-					reviewRating = fmt.Sprintf("%d", review.Rating)
-					reviewText = review.Text
-				}
-			}
+	// Создаём CSV-файл
+	if err := os.MkdirAll(cfg.ResultsDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("Ошибка создания директории: %v", err)
+	}
+	timestamp := time.Now().Format("20060102150405")
+	filename := filepath.Join(cfg.ResultsDir, fmt.Sprintf("%s-%s.csv", timestamp, strings.ReplaceAll(data.HotelName, " ", "_")))
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка создания файла: %v", err)
+	}
+	defer file.Close()
 
-			entry := map[string]string{
-				"platform":      platform,
-				"title":         item.Title,
-				"link":          item.Link,
-				"rating":        rating,
-				"user_ratings":  userRatingsTotal,
-				"review_author": reviewAuthor,
-				"review_rating": reviewRating,
-				"review_text":   reviewText,
-			}
-			results = append(results, entry)
-			writer.Write([]string{platform, item.Title, item.Link, rating, userRatingsTotal, reviewAuthor, reviewRating, reviewText})
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+	writer.Write(i18n.Header(i18n.Resolve(data.Locale, i18n.EN), "platform", "title", "link", "page", "position", "match_score", "rating", "user_ratings", "review_author", "review_rating", "review_text"))
+
+	checkedAt := time.Now().UTC()
+
+	// Рейтинг и первый отзыв Google Places одинаковы для всех найденных платформ
+	var rating *model.RatingSnapshot
+	var review *model.Review
+	if details != nil {
+		rating = &model.RatingSnapshot{
+			Source:           model.SourceGooglePlaces,
+			Rating:           details.Result.Rating,
+			UserRatingsTotal: details.Result.UserRatingsTotal,
+			CheckedAt:        checkedAt,
+		}
+		if len(details.Result.Reviews) > 0 {
+			first := details.Result.Reviews[0]
+			review = &model.Review{Author: first.AuthorName, Rating: first.Rating, Text: first.Text}
 		}
 	}
 
-	log.Println("Данные успешно сохранены в файл:", filename)
-	return filename, results, nil
-}
-
-// **Функция поиска Google CSE с поддержкой проверки заголовков**
-func findLinksGoogleCSE(query, apiKey, cx string, platforms []string, hotelName string, maxPages int) map[string]struct{ Title, Link string } {
-	baseURL := "https://www.googleapis.com/customsearch/v1"
-	links := make(map[string]struct{ Title, Link string })
-	hotelWords := strings.Fields(strings.ToLower(hotelName))
-
-	for start := 1; start <= maxPages*10; start += 10 {
-		u, _ := url.Parse(baseURL)
-		q := u.Query()
-		q.Set("key", apiKey)
-		q.Set("cx", cx)
-		q.Set("q", query)
-		q.Set("start", fmt.Sprintf("%d", start))
-		u.RawQuery = q.Encode()
-
-		resp, err := http.Get(u.String())
-		if err != nil || resp.StatusCode != http.StatusOK {
-			continue
+	var explanations []Explanation
+	serpLinks := make([]map[string]Hit, len(batches))
+	for i := range batches {
+		serpLinks[i] = batchResults[i].links
+		for _, e := range batchResults[i].explanations {
+			metrics.MatchDecisions.Inc(e.Platform, e.Rule)
 		}
-		defer resp.Body.Close()
-
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		var result CustomSearchResponse
-		json.Unmarshal(bodyBytes, &result)
-
-		for _, item := range result.Items {
-			titleLower := strings.ToLower(item.Title)
-			if checkTitle(titleLower, hotelWords) {
-				for _, platform := range platforms {
-					if strings.Contains(item.Link, platform) {
-						links[platform] = struct{ Title, Link string }{Title: item.Title, Link: item.Link}
-					}
-				}
-			}
+		if data.Explain {
+			explanations = append(explanations, batchResults[i].explanations...)
 		}
 	}
-	return links
-}
 
-// **Функция проверки заголовков**
-func checkTitle(title string, words []string) bool {
-	if len(words) == 1 {
-		return strings.Contains(title, words[0])
+	results := []model.Listing{}
+	for _, hit := range bestHits(platforms, serpLinks) {
+		listing := model.Listing{
+			Platform:   hit.Platform,
+			Title:      hit.Title,
+			Link:       hit.Link,
+			Page:       hit.Page,
+			Position:   hit.Position,
+			MatchScore: hit.Score,
+			Rating:     rating,
+			Review:     review,
+		}
+		results = append(results, listing)
+		row := listing.Map()
+		writer.Write([]string{row["platform"], row["title"], row["link"], row["page"], row["position"], row["match_score"], row["rating"], row["user_ratings"], row["review_author"], row["review_rating"], row["review_text"]})
 	}
 
-	wordPairs := generateWordPairs(words)
+	// Сохраняем позиции в истории выдачи
+	if err := appendSERPHistory(serpHistoryFile(cfg), buildSERPRecords(checkedAt, data, batches, serpLinks)); err != nil {
+		slog.ErrorContext(ctx, "ошибка сохранения истории позиций", "error", err)
+	}
 
-	for _, pair := range wordPairs {
-		if strings.Contains(title, pair[0]) && strings.Contains(title, pair[1]) {
-			return true
+	report := &FetchReport{Filename: filename, Results: results, Platforms: platforms}
+	if data.Explain {
+		report.Explanations = explanations
+		report.ExplainFilename = strings.TrimSuffix(filename, ".csv") + "-explain.csv"
+		if err := saveExplanationsCSV(report.ExplainFilename, i18n.Resolve(data.Locale, i18n.EN), explanations); err != nil {
+			return nil, err
 		}
 	}
 
-	return false
+	slog.DebugContext(ctx, "данные сохранены", "file", filename)
+	return report, nil
 }
 
-// **Генерация комбинаций слов (2 слова)**
-func generateWordPairs(words []string) [][]string {
-	var pairs [][]string
-	for i := 0; i < len(words); i++ {
-		for j := i + 1; j < len(words); j++ {
-			pairs = append(pairs, []string{words[i], words[j]})
-		}
-	}
-	return pairs
+// Hit - найденная ссылка платформы и её позиция в выдаче
+type Hit struct {
+	Title    string
+	Link     string
+	Page     int     // номер страницы выдачи, начиная с 1
+	Index    int     // позиция на странице, начиная с 1
+	Position int     // абсолютная позиция в выдаче, начиная с 1
+	Score    float64 // оценка совпадения заголовка с названием объекта
 }
 
-// **Формирование поискового запроса**
-func buildQuery(hotelName, city, country string) string {
-	return fmt.Sprintf("%s %s %s", hotelName, city, country)
+// platformHit - лучшая ссылка платформы среди всех запросов анализа
+type platformHit struct {
+	Platform string
+	Hit
 }
 
-// **Функция загрузки конфигурации**
-func loadConfig(filename string) (*Config, error) {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, err
+// bestHits выбирает для каждой платформы ссылку с наименьшей позицией среди всех запросов
+// (при равных позициях - из более раннего запроса) и возвращает их в порядке каталога platforms,
+// чтобы строки CSV не зависели от порядка обхода map и завершения запросов
+func bestHits(platforms []string, links []map[string]Hit) []platformHit {
+	var hits []platformHit
+	seen := make(map[string]bool)
+	for _, platform := range platforms {
+		if seen[platform] {
+			continue
+		}
+		seen[platform] = true
+		best := platformHit{Platform: platform}
+		for _, batch := range links {
+			hit, ok := batch[platform]
+			if ok && (best.Position == 0 || hit.Position < best.Position) {
+				best.Hit = hit
+			}
+		}
+		if best.Position > 0 {
+			hits = append(hits, best)
+		}
 	}
-	var config Config
-	err = json.Unmarshal(data, &config)
-	if err != nil {
-		return nil, err
+	return hits
+}
+
+// matchPlatform возвращает первую платформу, домен которой содержится в ссылке
+func matchPlatform(link string, platforms []string) string {
+	for _, platform := range platforms {
+		if strings.Contains(link, platform) {
+			return platform
+		}
 	}
-	return &config, nil
+	return ""
 }
 
 // **Функция загрузки списка платформ**
@@ -234,39 +334,3 @@ func buildSearchQuery(query string, platforms []string) string {
 	}
 	return strings.Join(siteFilters, " OR ") + " " + query
 }
-
-// getPlaceDetails получает информацию о месте из Google Places API
-func getPlaceDetails(apiKey, hotelName, city string) (*PlaceDetails, error) {
-	baseURL := "https://maps.googleapis.com/maps/api/place/findplacefromtext/json"
-	u, _ := url.Parse(baseURL)
-
-	// Формируем поисковый запрос
-	query := fmt.Sprintf("%s, %s", hotelName, city)
-	q := u.Query()
-	q.Set("input", query)
-	q.Set("inputtype", "textquery")
-	q.Set("fields", "name,rating,user_ratings_total,reviews")
-	q.Set("key", apiKey)
-	u.RawQuery = q.Encode()
-
-	// Отправляем GET-запрос
-	resp, err := http.Get(u.String())
-	if err != nil {
-		return nil, fmt.Errorf("ошибка запроса к Google Places API: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Читаем и парсим ответ
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка чтения ответа от Google Places API: %v", err)
-	}
-
-	var placeDetails PlaceDetails
-	err = json.Unmarshal(bodyBytes, &placeDetails)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка парсинга JSON от Google Places API: %v", err)
-	}
-
-	return &placeDetails, nil
-}
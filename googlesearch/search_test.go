@@ -0,0 +1,129 @@
+// sermersys/googlesearch/search_test.go
+package googlesearch
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sermersys/config"
+)
+
+func TestBestHits(t *testing.T) {
+	links := []map[string]Hit{
+		{"booking.com": {Link: "https://booking.com/first", Position: 7}, "agoda.com": {Link: "https://agoda.com/a", Position: 2}},
+		nil,
+		{"booking.com": {Link: "https://booking.com/best", Position: 3}},
+		{"booking.com": {Link: "https://booking.com/tie", Position: 3}, "unknown.com": {Position: 1}},
+	}
+	got := bestHits([]string{"agoda.com", "expedia.com", "booking.com", "agoda.com"}, links)
+	if len(got) != 2 {
+		t.Fatalf("ссылок %d, ожидалось 2: %+v", len(got), got)
+	}
+	if got[0].Platform != "agoda.com" || got[0].Position != 2 {
+		t.Errorf("первая ссылка: %+v", got[0])
+	}
+	// Лучшая позиция, а при равенстве - из более раннего запроса
+	if got[1].Platform != "booking.com" || got[1].Link != "https://booking.com/best" {
+		t.Errorf("booking.com: %+v", got[1])
+	}
+}
+
+func TestFetchDataExplainOrder(t *testing.T) {
+	// booking.com находится обоими написаниями названия, но ГОСТ-вариантом - выше
+	mux := http.NewServeMux()
+	mux.HandleFunc("/customsearch", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		switch {
+		case strings.Contains(q, "site:tripadvisor.com"):
+			fmt.Fprint(w, `{"items":[{"title":"Gostinitsa Moskva - Tripadvisor","link":"https://www.tripadvisor.com/Hotel_Review-moskva"}]}`)
+		case strings.Contains(q, "site:booking.com") && strings.Contains(q, "Gostinicza"):
+			fmt.Fprint(w, `{"items":[{"title":"Gostinicza Moskva - Booking.com","link":"https://www.booking.com/hotel/ru/moskva.html"}]}`)
+		case strings.Contains(q, "site:booking.com"):
+			fmt.Fprint(w, `{"items":[{"title":"Moscow guide","link":"https://example.com/guide"},{"title":"Gostinitsa Moskva - Booking.com","link":"https://www.booking.com/hotel/ru/moskva.en-gb.html"}]}`)
+		default:
+			fmt.Fprint(w, `{"items":[]}`)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	platformsFile := filepath.Join(dir, "platforms.txt")
+	templatesFile := filepath.Join(dir, "templates.json")
+	if err := os.WriteFile(platformsFile, []byte("tripadvisor.com\nexpedia.com\nbooking.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(templatesFile, []byte(`{"default":"{name} {city}","mode":"single"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.ResultsDir = filepath.Join(dir, "results")
+	cfg.Providers.Places = false
+	cfg.Concurrency = 8
+	cfg.Endpoints.CustomSearch = srv.URL + "/customsearch"
+
+	data := RequestData{
+		HotelName:          "Гостиница Москва",
+		City:               "Moscow",
+		PlatformsFile:      platformsFile,
+		QueryTemplatesFile: templatesFile,
+		Transliterate:      true,
+		TranslitSchemes:    []string{"icao", "gost"},
+		Explain:            true,
+	}
+	// Порядок строк не должен зависеть от порядка завершения параллельных запросов
+	for run := 0; run < 5; run++ {
+		report, err := FetchDataExplainContext(context.Background(), cfg, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Results) != 2 {
+			t.Fatalf("ссылок %d: %+v", len(report.Results), report.Results)
+		}
+		trip, booking := report.Results[0], report.Results[1]
+		if trip.Platform != "tripadvisor.com" || booking.Platform != "booking.com" {
+			t.Fatalf("ссылки не в порядке каталога: %s, %s", trip.Platform, booking.Platform)
+		}
+		if booking.Position != 1 || booking.Link != "https://www.booking.com/hotel/ru/moskva.html" {
+			t.Errorf("booking.com: ожидалась позиция 1 из ГОСТ-варианта, получено %+v", booking)
+		}
+
+		rows := readCSV(t, report.Filename)
+		if len(rows) != 3 || rows[1][0] != "tripadvisor.com" || rows[2][0] != "booking.com" || rows[2][4] != "1" {
+			t.Errorf("CSV: %q", rows)
+		}
+		explain := readCSV(t, report.ExplainFilename)
+		if len(explain) != len(report.Explanations)+1 {
+			t.Fatalf("строк разбора %d, разборов %d", len(explain)-1, len(report.Explanations))
+		}
+		for i, e := range report.Explanations {
+			if explain[i+1][0] != e.Query || explain[i+1][7] != e.Rule {
+				t.Errorf("строка разбора %d: %q, ожидалось %s / %s", i+1, explain[i+1], e.Query, e.Rule)
+			}
+		}
+		if report.Explanations[0].Query != `site:tripadvisor.com Гостиница Москва Moscow` {
+			t.Errorf("разбор начинается не с первого запроса: %+v", report.Explanations[0])
+		}
+	}
+}
+
+func readCSV(t *testing.T, filename string) [][]string {
+	t.Helper()
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
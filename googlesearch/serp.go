@@ -0,0 +1,251 @@
+package googlesearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"sermersys/config"
+	"sermersys/model"
+)
+
+// serpHistoryFile возвращает файл истории позиций платформ в выдаче (JSON Lines)
+func serpHistoryFile(cfg *config.Config) string {
+	return filepath.Join(cfg.ResultsDir, "serp_history.jsonl")
+}
+
+// SERPRecord - позиция платформы в выдаче по одному запросу
+type SERPRecord struct {
+	CheckedAt time.Time `json:"checked_at"`
+	HotelName string    `json:"hotel_name"`
+	City      string    `json:"city"`
+	Country   string    `json:"country"`
+	Query     string    `json:"query"`
+	Platform  string    `json:"platform"`
+	Found     bool      `json:"found"`
+	Page      int       `json:"page,omitempty"`
+	Index     int       `json:"index,omitempty"`
+	Position  int       `json:"position,omitempty"`
+	Score     float64   `json:"score,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Link      string    `json:"link,omitempty"`
+}
+
+// SERPWeek - видимость платформы за одну неделю
+type SERPWeek = model.VisibilityWeek
+
+// SERPPlatformReport - динамика видимости одной платформы
+type SERPPlatformReport = model.PlatformVisibility
+
+// SERPReport - отчёт о видимости объекта на платформах по запросам "<отель> <город>"
+type SERPReport struct {
+	HotelName string               `json:"hotel_name"`
+	City      string               `json:"city"`
+	Platforms []SERPPlatformReport `json:"platforms"`
+}
+
+// buildSERPRecords формирует записи истории за один анализ: по одной на пару запрос - платформа.
+// Платформа может искаться несколькими запросами (шаблоны, транслитерированные написания);
+// позиция по каждому из них сохраняется, а лучшая выбирается при агрегации (см. aggregateSERPRecords).
+func buildSERPRecords(checkedAt time.Time, data RequestData, batches []searchBatch, links []map[string]Hit) []SERPRecord {
+	var records []SERPRecord
+	index := make(map[[2]string]int)
+	for i, batch := range batches {
+		for _, platform := range batch.Platforms {
+			key := [2]string{batch.Query, platform}
+			n, ok := index[key]
+			if !ok {
+				n = len(records)
+				index[key] = n
+				records = append(records, SERPRecord{
+					CheckedAt: checkedAt,
+					HotelName: data.HotelName,
+					City:      data.City,
+					Country:   data.Country,
+					Query:     batch.Query,
+					Platform:  platform,
+				})
+			}
+			hit, found := links[i][platform]
+			if !found {
+				continue
+			}
+			record := &records[n]
+			if record.Found && record.Position <= hit.Position {
+				continue
+			}
+			record.Found = true
+			record.Page = hit.Page
+			record.Index = hit.Index
+			record.Position = hit.Position
+			record.Score = hit.Score
+			record.Title = hit.Title
+			record.Link = hit.Link
+		}
+	}
+	return records
+}
+
+// appendSERPHistory дописывает записи в файл истории
+func appendSERPHistory(filename string, records []SERPRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return fmt.Errorf("ошибка создания директории: %v", err)
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия файла истории: %v", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("ошибка записи истории: %v", err)
+		}
+	}
+	return nil
+}
+
+// loadSERPHistory читает записи истории для объекта в городе
+func loadSERPHistory(filename, hotelName, city string) ([]SERPRecord, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка открытия файла истории: %v", err)
+	}
+	defer file.Close()
+
+	var records []SERPRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record SERPRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if !strings.EqualFold(record.HotelName, hotelName) {
+			continue
+		}
+		if city != "" && !strings.EqualFold(record.City, city) {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла истории: %v", err)
+	}
+	return records, nil
+}
+
+// BuildSERPReport строит понедельный отчёт о видимости платформ для объекта
+func BuildSERPReport(cfg *config.Config, hotelName, city string) (*SERPReport, error) {
+	records, err := loadSERPHistory(serpHistoryFile(cfg), hotelName, city)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateSERPRecords(hotelName, city, records), nil
+}
+
+// aggregateSERPRecords группирует записи по платформам и ISO-неделям. Проверка - один анализ
+// (записи с одним checked_at): платформа видна, если найдена хотя бы по одному запросу,
+// и её позиция в проверке - лучшая среди запросов.
+func aggregateSERPRecords(hotelName, city string, records []SERPRecord) *SERPReport {
+	type check struct {
+		platform  string
+		checkedAt time.Time
+		found     bool
+		position  int
+	}
+	type checkKey struct {
+		platform  string
+		checkedAt int64
+	}
+	var checks []*check
+	byCheck := make(map[checkKey]*check)
+	for _, record := range records {
+		key := checkKey{record.Platform, record.CheckedAt.UnixNano()}
+		c, ok := byCheck[key]
+		if !ok {
+			c = &check{platform: record.Platform, checkedAt: record.CheckedAt}
+			byCheck[key] = c
+			checks = append(checks, c)
+		}
+		if record.Found && (!c.found || record.Position < c.position) {
+			c.found = true
+			c.position = record.Position
+		}
+	}
+
+	type weekStats struct {
+		checks, appearances, best, sum int
+	}
+	byPlatform := make(map[string]map[string]*weekStats)
+
+	for _, c := range checks {
+		year, week := c.checkedAt.ISOWeek()
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
+		weeks, ok := byPlatform[c.platform]
+		if !ok {
+			weeks = make(map[string]*weekStats)
+			byPlatform[c.platform] = weeks
+		}
+		stats, ok := weeks[weekKey]
+		if !ok {
+			stats = &weekStats{}
+			weeks[weekKey] = stats
+		}
+		stats.checks++
+		if c.found {
+			stats.appearances++
+			stats.sum += c.position
+			if stats.best == 0 || c.position < stats.best {
+				stats.best = c.position
+			}
+		}
+	}
+
+	report := &SERPReport{HotelName: hotelName, City: city}
+	for platform, weeks := range byPlatform {
+		keys := make([]string, 0, len(weeks))
+		for key := range weeks {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		platformReport := SERPPlatformReport{Platform: platform}
+		prevBest := 0
+		for _, key := range keys {
+			stats := weeks[key]
+			week := SERPWeek{
+				Week:         key,
+				Checks:       stats.checks,
+				Appearances:  stats.appearances,
+				BestPosition: stats.best,
+				Visibility:   float64(stats.appearances) / float64(stats.checks),
+			}
+			if stats.appearances > 0 {
+				week.AvgPosition = float64(stats.sum) / float64(stats.appearances)
+			}
+			if prevBest > 0 && stats.best > 0 {
+				week.PositionDelta = stats.best - prevBest
+			}
+			prevBest = stats.best
+			platformReport.Weeks = append(platformReport.Weeks, week)
+		}
+		report.Platforms = append(report.Platforms, platformReport)
+	}
+	sort.Slice(report.Platforms, func(i, j int) bool {
+		return report.Platforms[i].Platform < report.Platforms[j].Platform
+	})
+	return report
+}
@@ -0,0 +1,118 @@
+// sermersys/googlesearch/serp_test.go
+package googlesearch
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildSERPRecordsPerQuery(t *testing.T) {
+	checkedAt := time.Date(2025, 2, 12, 10, 0, 0, 0, time.UTC)
+	data := RequestData{HotelName: "Гостиница Москва", City: "Москва", Country: "Россия"}
+	// Три написания названия ищут одни и те же платформы; booking.com найден только
+	// транслитерированными вариантами, airbnb.com - ни одним
+	batches := []searchBatch{
+		{Query: "Гостиница Москва", Platforms: []string{"booking.com", "airbnb.com"}},
+		{Query: "Gostinitsa Moskva", Platforms: []string{"booking.com", "airbnb.com"}},
+		{Query: "Gostinica Moskva", Platforms: []string{"booking.com", "airbnb.com"}},
+	}
+	links := []map[string]Hit{
+		nil,
+		{"booking.com": {Title: "Gostinitsa Moskva", Link: "https://booking.com/a", Page: 1, Index: 7, Position: 7, Score: 0.9}},
+		{"booking.com": {Title: "Gostinica Moskva", Link: "https://booking.com/b", Page: 1, Index: 3, Position: 3, Score: 0.8}},
+	}
+
+	records := buildSERPRecords(checkedAt, data, batches, links)
+	if len(records) != 6 {
+		t.Fatalf("записей %d, ожидалось 6 (запрос × платформа): %+v", len(records), records)
+	}
+	want := []struct {
+		query, platform string
+		position        int
+	}{
+		{"Гостиница Москва", "booking.com", 0},
+		{"Гостиница Москва", "airbnb.com", 0},
+		{"Gostinitsa Moskva", "booking.com", 7},
+		{"Gostinitsa Moskva", "airbnb.com", 0},
+		{"Gostinica Moskva", "booking.com", 3},
+		{"Gostinica Moskva", "airbnb.com", 0},
+	}
+	for i, w := range want {
+		r := records[i]
+		if r.Query != w.query || r.Platform != w.platform || r.Position != w.position || r.Found != (w.position > 0) || !r.CheckedAt.Equal(checkedAt) {
+			t.Errorf("запись %d: %+v, ожидалось %+v", i, r, w)
+		}
+	}
+
+	// В отчёте анализ - одна проверка платформы с лучшей позицией среди запросов
+	report := aggregateSERPRecords(data.HotelName, data.City, records)
+	if len(report.Platforms) != 2 {
+		t.Fatalf("платформ %d: %+v", len(report.Platforms), report.Platforms)
+	}
+	for _, p := range report.Platforms {
+		if len(p.Weeks) != 1 || p.Weeks[0].Checks != 1 {
+			t.Fatalf("%s: ожидалась одна проверка за неделю, получено %+v", p.Platform, p.Weeks)
+		}
+		week := p.Weeks[0]
+		switch p.Platform {
+		case "booking.com":
+			if week.Visibility != 1 || week.BestPosition != 3 || week.AvgPosition != 3 {
+				t.Errorf("booking.com: %+v", week)
+			}
+		case "airbnb.com":
+			if week.Visibility != 0 || week.BestPosition != 0 {
+				t.Errorf("airbnb.com: %+v", week)
+			}
+		}
+	}
+}
+
+func TestAggregateSERPRecordsWeeks(t *testing.T) {
+	monday := time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC) // 2025-W07
+	record := func(at time.Time, query string, position int) SERPRecord {
+		return SERPRecord{CheckedAt: at, HotelName: "Hotel Adriatic", City: "Budva", Query: query, Platform: "booking.com", Found: position > 0, Position: position}
+	}
+	records := []SERPRecord{
+		// W07: два анализа, во втором платформа не найдена ни одним запросом
+		record(monday, "Hotel Adriatic Budva", 5),
+		record(monday, "Adriatic Budva booking", 2),
+		record(monday.Add(24*time.Hour), "Hotel Adriatic Budva", 0),
+		record(monday.Add(24*time.Hour), "Adriatic Budva booking", 0),
+		// W08: позиция ухудшилась до 4
+		record(monday.AddDate(0, 0, 7), "Hotel Adriatic Budva", 4),
+	}
+	report := aggregateSERPRecords("Hotel Adriatic", "Budva", records)
+	if len(report.Platforms) != 1 || len(report.Platforms[0].Weeks) != 2 {
+		t.Fatalf("неожиданный отчёт: %+v", report)
+	}
+	w7, w8 := report.Platforms[0].Weeks[0], report.Platforms[0].Weeks[1]
+	if w7.Week != "2025-W07" || w7.Checks != 2 || w7.Appearances != 1 || w7.Visibility != 0.5 || w7.BestPosition != 2 || w7.AvgPosition != 2 {
+		t.Errorf("W07: %+v", w7)
+	}
+	if w8.Week != "2025-W08" || w8.Checks != 1 || w8.BestPosition != 4 || w8.PositionDelta != 2 {
+		t.Errorf("W08: %+v", w8)
+	}
+}
+
+func TestSERPHistoryRoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "serp_history.jsonl")
+	at := time.Date(2025, 2, 12, 10, 0, 0, 0, time.UTC)
+	records := []SERPRecord{
+		{CheckedAt: at, HotelName: "Hotel Adriatic", City: "Budva", Query: "q", Platform: "booking.com", Found: true, Position: 1},
+		{CheckedAt: at, HotelName: "Hotel Mogren", City: "Budva", Query: "q", Platform: "booking.com"},
+	}
+	if err := appendSERPHistory(filename, records); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadSERPHistory(filename, "hotel adriatic", "BUDVA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Position != 1 || !got[0].CheckedAt.Equal(at) {
+		t.Errorf("loadSERPHistory = %+v", got)
+	}
+	if got, err := loadSERPHistory(filepath.Join(t.TempDir(), "missing.jsonl"), "x", ""); err != nil || got != nil {
+		t.Errorf("отсутствующая история: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,254 @@
+package googlesearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"sermersys/translit"
+)
+
+// Режимы формирования запросов к Google CSE
+const (
+	ModeGrouped = "grouped" // несколько site:-фильтров через OR в одном запросе
+	ModeSingle  = "single"  // отдельный запрос для каждой платформы
+)
+
+// defaultQueryTemplatesFile - файл шаблонов, используемый, если в запросе не указан другой
+const defaultQueryTemplatesFile = "./query_templates.json"
+
+// QueryTemplates - настройки шаблонов поисковых запросов
+//
+// Шаблон поддерживает подстановки {name}, {exact_name} (имя в кавычках),
+// {city}, {country} и {address}.
+type QueryTemplates struct {
+	Default    string                       `json:"default"`
+	Mode       string                       `json:"mode"`
+	GroupSize  int                          `json:"group_size"`
+	Categories map[string]CategoryTemplates `json:"categories"`
+	Platforms  map[string]PlatformOverride  `json:"platforms"`
+}
+
+// CategoryTemplates - шаблоны для категории платформ (OTA, отзовики и т.д.)
+type CategoryTemplates struct {
+	Platforms []string          `json:"platforms"`
+	Templates map[string]string `json:"templates"` // язык -> шаблон, ключ "default" - для остальных языков
+	Mode      string            `json:"mode,omitempty"`
+}
+
+// PlatformOverride - переопределение шаблона и режима для отдельной платформы
+type PlatformOverride struct {
+	Template  string            `json:"template,omitempty"`
+	Templates map[string]string `json:"templates,omitempty"` // язык -> шаблон
+	Mode      string            `json:"mode,omitempty"`
+}
+
+// searchBatch - один запрос к Google CSE и платформы, которые он покрывает
+type searchBatch struct {
+	Query     string
+	Platforms []string
+}
+
+// defaultQueryTemplates повторяет прежнее поведение: "{name} {city} {country}", группы по 5 платформ
+func defaultQueryTemplates() *QueryTemplates {
+	return &QueryTemplates{
+		Default:   "{name} {city} {country}",
+		Mode:      ModeGrouped,
+		GroupSize: 5,
+	}
+}
+
+// loadQueryTemplates загружает шаблоны из файла; при отсутствии файла по умолчанию используются встроенные
+func loadQueryTemplates(filename string) (*QueryTemplates, error) {
+	explicit := filename != ""
+	if !explicit {
+		filename = defaultQueryTemplatesFile
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return defaultQueryTemplates(), nil
+		}
+		return nil, err
+	}
+
+	templates := defaultQueryTemplates()
+	if err := json.Unmarshal(data, templates); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга шаблонов запросов: %v", err)
+	}
+	if err := templates.validate(); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// validate проверяет режимы, размер группы и то, что каждая платформа входит не больше
+// чем в одну категорию: иначе шаблон платформы зависел бы от порядка обхода категорий
+func (t *QueryTemplates) validate() error {
+	if t.GroupSize <= 0 {
+		return fmt.Errorf("group_size должен быть больше нуля")
+	}
+	names := make([]string, 0, len(t.Categories))
+	for name := range t.Categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	modes := []string{t.Mode}
+	categoryOf := make(map[string]string)
+	for _, name := range names {
+		category := t.Categories[name]
+		modes = append(modes, category.Mode)
+		for _, platform := range category.Platforms {
+			if other, ok := categoryOf[platform]; ok {
+				return fmt.Errorf("платформа %s входит в категории %s и %s", platform, other, name)
+			}
+			categoryOf[platform] = name
+		}
+	}
+	for _, override := range t.Platforms {
+		modes = append(modes, override.Mode)
+	}
+	for _, mode := range modes {
+		if mode != "" && mode != ModeGrouped && mode != ModeSingle {
+			return fmt.Errorf("неизвестный режим запроса: %s", mode)
+		}
+	}
+	return nil
+}
+
+// resolve возвращает шаблон и режим для платформы с учётом языка. Платформа входит не больше
+// чем в одну категорию (см. validate).
+func (t *QueryTemplates) resolve(platform, language string) (string, string) {
+	template, mode := t.Default, t.Mode
+
+	for _, category := range t.Categories {
+		if !containsString(category.Platforms, platform) {
+			continue
+		}
+		if tpl := pickTemplate(category.Templates, language); tpl != "" {
+			template = tpl
+		}
+		if category.Mode != "" {
+			mode = category.Mode
+		}
+		break
+	}
+
+	if override, ok := t.Platforms[platform]; ok {
+		if tpl := pickTemplate(override.Templates, language); tpl != "" {
+			template = tpl
+		} else if override.Template != "" {
+			template = override.Template
+		}
+		if override.Mode != "" {
+			mode = override.Mode
+		}
+	}
+
+	if mode == "" {
+		mode = ModeGrouped
+	}
+	return template, mode
+}
+
+// pickTemplate выбирает шаблон для языка, иначе шаблон "default"
+func pickTemplate(templates map[string]string, language string) string {
+	if tpl, ok := templates[strings.ToLower(language)]; ok && language != "" {
+		return tpl
+	}
+	return templates["default"]
+}
+
+// planSearchBatches группирует платформы по шаблону и режиму и формирует запросы к Google CSE
+func planSearchBatches(t *QueryTemplates, data RequestData, platforms []string) []searchBatch {
+	type groupKey struct{ template, mode string }
+	groups := make(map[groupKey][]string)
+	var order []groupKey
+
+	for _, platform := range platforms {
+		template, mode := t.resolve(platform, data.Language)
+		key := groupKey{template, mode}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], platform)
+	}
+
+	var batches []searchBatch
+	for _, key := range order {
+		query := renderQueryTemplate(key.template, data)
+		size := t.GroupSize
+		if key.mode == ModeSingle {
+			size = 1
+		}
+		members := groups[key]
+		for i := 0; i < len(members); i += size {
+			end := i + size
+			if end > len(members) {
+				end = len(members)
+			}
+			subset := members[i:end]
+			batches = append(batches, searchBatch{
+				Query:     buildSearchQuery(query, subset),
+				Platforms: subset,
+			})
+		}
+	}
+	return batches
+}
+
+// renderQueryTemplate подставляет данные объекта в шаблон
+func renderQueryTemplate(template string, data RequestData) string {
+	replacer := strings.NewReplacer(
+		"{name}", data.HotelName,
+		"{exact_name}", `"`+data.HotelName+`"`,
+		"{city}", data.City,
+		"{country}", data.Country,
+		"{address}", data.Address,
+	)
+	return strings.Join(strings.Fields(replacer.Replace(template)), " ")
+}
+
+// cyrillicLanguages - языки, для которых латинское название дополняется кириллическим вариантом
+var cyrillicLanguages = map[string]bool{"ru": true, "uk": true, "be": true, "bg": true, "sr": true, "kk": true}
+
+// queryNameVariants возвращает написания названия, по которым строятся запросы.
+// Без транслитерации это только исходное название.
+func queryNameVariants(data RequestData) []string {
+	names := []string{data.HotelName}
+	if !data.Transliterate {
+		return names
+	}
+
+	seen := map[string]bool{normalizeText(data.HotelName): true}
+	add := func(name string) {
+		key := normalizeText(name)
+		if !seen[key] {
+			seen[key] = true
+			names = append(names, name)
+		}
+	}
+
+	if translit.HasNonLatin(data.HotelName) {
+		for _, variant := range translit.LatinVariants(data.HotelName, translit.ParseSchemes(data.TranslitSchemes)...) {
+			add(variant)
+		}
+	} else if cyrillicLanguages[strings.ToLower(data.Language)] {
+		add(translit.ToCyrillic(data.HotelName))
+	}
+	return names
+}
+
+// containsString проверяет наличие строки в срезе
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+// sermersys/googlesearch/templates_test.go
+package googlesearch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplates(t *testing.T, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "templates.json")
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadQueryTemplatesExample(t *testing.T) {
+	templates, err := loadQueryTemplates("../query_templates.example.json")
+	if err != nil {
+		t.Fatalf("пример шаблонов не загружается: %v", err)
+	}
+	cases := []struct {
+		platform, language string
+		template, mode     string
+	}{
+		{"booking.com", "ru", "{exact_name} {city} отель", ModeGrouped},
+		{"booking.com", "RU", "{exact_name} {city} отель", ModeGrouped},
+		{"booking.com", "en", "{exact_name} {city}", ModeGrouped},
+		{"tripadvisor.com", "de", "{name} {city} Bewertungen", ModeSingle},
+		{"trivago.com", "ru", "{name} {city}", ModeSingle},
+		{"airbnb.com", "ru", "{name} {city} {country}", ModeGrouped},
+	}
+	for _, c := range cases {
+		template, mode := templates.resolve(c.platform, c.language)
+		if template != c.template || mode != c.mode {
+			t.Errorf("resolve(%s, %s) = %q, %s; ожидалось %q, %s", c.platform, c.language, template, mode, c.template, c.mode)
+		}
+	}
+}
+
+func TestLoadQueryTemplatesInvalid(t *testing.T) {
+	cases := []struct {
+		name, content, problem string
+	}{
+		{"платформа в двух категориях", `{"categories":{
+			"ota":{"platforms":["booking.com","agoda.com"],"templates":{"default":"{name} ota"}},
+			"deals":{"platforms":["agoda.com"],"templates":{"default":"{name} deals"}}}}`,
+			"платформа agoda.com входит в категории deals и ota"},
+		{"неизвестный режим", `{"mode":"parallel"}`, "неизвестный режим"},
+		{"нулевая группа", `{"group_size":0}`, "group_size"},
+		{"неверный JSON", `{"default":`, "ошибка парсинга"},
+	}
+	for _, c := range cases {
+		_, err := loadQueryTemplates(writeTemplates(t, c.content))
+		if err == nil || !strings.Contains(err.Error(), c.problem) {
+			t.Errorf("%s: ожидалась ошибка %q, получено %v", c.name, c.problem, err)
+		}
+	}
+	if _, err := loadQueryTemplates(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("явно указанный отсутствующий файл должен быть ошибкой")
+	}
+}
+
+func TestPlanSearchBatches(t *testing.T) {
+	templates, err := loadQueryTemplates(writeTemplates(t, `{
+		"default": "{name} {city}",
+		"group_size": 2,
+		"categories": {"ota": {"platforms": ["booking.com", "agoda.com", "expedia.com"], "templates": {"default": "{exact_name} {city}"}}},
+		"platforms": {"tripadvisor.com": {"template": "{name} reviews", "mode": "single"}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := RequestData{HotelName: "Hotel Adriatic", City: "Budva"}
+	platforms := []string{"booking.com", "airbnb.com", "agoda.com", "tripadvisor.com", "expedia.com"}
+	got := planSearchBatches(templates, data, platforms)
+	want := []searchBatch{
+		{`site:booking.com OR site:agoda.com "Hotel Adriatic" Budva`, []string{"booking.com", "agoda.com"}},
+		{`site:expedia.com "Hotel Adriatic" Budva`, []string{"expedia.com"}},
+		{`site:airbnb.com Hotel Adriatic Budva`, []string{"airbnb.com"}},
+		{`site:tripadvisor.com Hotel Adriatic reviews`, []string{"tripadvisor.com"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("запросов %d, ожидалось %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Query != want[i].Query || strings.Join(got[i].Platforms, ",") != strings.Join(want[i].Platforms, ",") {
+			t.Errorf("запрос %d: %+v, ожидалось %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRenderQueryTemplate(t *testing.T) {
+	data := RequestData{HotelName: "Hotel Adriatic", City: "Budva", Address: "Slovenska obala 1"}
+	got := renderQueryTemplate("{exact_name}  {address} {country} {city}", data)
+	if want := `"Hotel Adriatic" Slovenska obala 1 Budva`; got != want {
+		t.Errorf("renderQueryTemplate = %q, ожидалось %q", got, want)
+	}
+}
+
+func TestQueryNameVariants(t *testing.T) {
+	cases := []struct {
+		name string
+		data RequestData
+		want []string
+	}{
+		{"без транслитерации", RequestData{HotelName: "Гостиница Москва"}, []string{"Гостиница Москва"}},
+		{"кириллица в латиницу", RequestData{HotelName: "Щука", Transliterate: true, TranslitSchemes: []string{"icao", "gost"}},
+			[]string{"Щука", "Shchuka", "Shhuka"}},
+		{"латиница в кириллицу для ru", RequestData{HotelName: "Hotel Moskva", Language: "ru", Transliterate: true},
+			[]string{"Hotel Moskva", "Хотел Москва"}},
+		{"İ в названии не роняет транслитерацию", RequestData{HotelName: "İstanbul Palace", Language: "bg", Transliterate: true},
+			[]string{"İstanbul Palace", "Истанбул Палаке"}},
+		{"латиница без кириллического языка", RequestData{HotelName: "Hotel Moskva", Language: "en", Transliterate: true},
+			[]string{"Hotel Moskva"}},
+	}
+	for _, c := range cases {
+		got := queryNameVariants(c.data)
+		if strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("%s: %q, ожидалось %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// sermersys/i18n/de.go
+package i18n
+
+// de - fehlende Meldungen werden auf Englisch angezeigt
+var de = map[string]string{
+	// API-Fehler
+	"error.api_key_required":      "API-Schlüssel erforderlich",
+	"error.api_key_unknown":       "Unbekannter API-Schlüssel",
+	"error.api_key_revoked":       "API-Schlüssel wurde widerrufen",
+	"error.forbidden":             "Für diesen API-Schlüssel nicht erlaubt",
+	"error.rate_limited":          "Anfragelimit überschritten",
+	"error.quota_exceeded":        "Tageskontingent für Analysen erschöpft",
+	"error.workspace_not_found":   "Arbeitsbereich nicht gefunden",
+	"error.method_not_allowed":    "Methode nicht erlaubt",
+	"error.invalid_json":          "Ungültiges JSON",
+	"error.required_fields":       "Pflichtfelder fehlen",
+	"error.platforms_not_allowed": "Plattformkatalog ist in diesem Arbeitsbereich nicht erlaubt",
+	"error.templates_not_allowed": "Datei mit Suchvorlagen ist in diesem Arbeitsbereich nicht erlaubt",
+	"error.missing_parameter":     "Parameter %s fehlt",
+	"error.queue_full":            "Die Analysewarteschlange ist voll",
+	"error.shutting_down":         "Der Server wird heruntergefahren, bitte später erneut versuchen",
+	"error.analysis_not_found":    "Analyse nicht gefunden",
+	"error.analysis_not_ready":    "Die Analyse hat kein Ergebnis",
+	"error.analysis_finished":     "Die Analyse ist bereits abgeschlossen",
+	"error.resource_not_found":    "Ressource nicht gefunden",
+	"error.file_not_found":        "Datei nicht gefunden",
+	"error.result_not_found":      "Ergebnis nicht gefunden",
+	"error.link_expired":          "Der Link ist abgelaufen",
+	"error.timeout":               "Zeitüberschreitung der Analyse",
+	"error.canceled":              "Die Analyse wurde abgebrochen",
+	"error.no_places":             "Google Places hat das Objekt nicht gefunden",
+	"error.upstream":              "Google API ist nicht erreichbar",
+	"error.analysis":              "Analyse fehlgeschlagen: %v",
+	"error.internal":              "Interner Serverfehler",
+	"error.mail_disabled":         "Der E-Mail-Versand ist auf dem Server nicht eingerichtet",
+	"error.invalid_recipients":    "Ungültiges email_to: %v",
+	"error.mail_requires_auth":    "Der Versand per E-Mail (email_to) erfordert einen API-Schlüssel",
+	"error.recipient_not_allowed": "Versand an %s ist nicht erlaubt: die Domain steht nicht in mail.allowed_domains",
+	"error.batch_empty":           "Der Stapel enthält keine Anfragen",
+	"error.batch_queue_full":      "In der Analysewarteschlange ist nicht genug Platz für den ganzen Stapel",
+	"error.batch_not_found":       "Stapel nicht gefunden",
+	"error.batch_read":            "Der Stapel konnte nicht gelesen werden",
+
+	// Analyseschritte
+	"step.places_request":  "1️⃣ Anfrage an mapsearchg für genauen Namen und Adresse",
+	"step.places_received": "2️⃣ Präzisierte Daten von mapsearchg erhalten",
+	"step.search_request":  "3️⃣ Anfrage an googlesearch.FetchData mit den präzisierten Daten",
+	"step.done":            "4️⃣ Analyse abgeschlossen",
+	"step.duration":        "⏳ Ausführungszeit: %v",
+
+	// CSV-Spalten
+	"csv.platform":      "Plattform",
+	"csv.title":         "Titel",
+	"csv.link":          "Link",
+	"csv.page":          "Seite",
+	"csv.position":      "Position",
+	"csv.match_score":   "Übereinstimmung",
+	"csv.rating":        "Bewertung",
+	"csv.user_ratings":  "Anzahl Bewertungen",
+	"csv.review_author": "Rezensent",
+	"csv.review_rating": "Bewertung der Rezension",
+	"csv.review_text":   "Rezensionstext",
+	"csv.object":        "Objekt",
+	"csv.found":         "Gefunden",
+	"csv.source":        "Quelle",
+	"csv.status":        "Status",
+	"csv.role":          "Rolle",
+	"csv.coverage":      "Abdeckung",
+	"csv.found_on":      "Gefunden auf",
+
+	// Berichte (XLSX, KML, HTML, PDF)
+	"report.sheet.places":       "Orte",
+	"report.sheet.listings":     "Einträge",
+	"report.sheet.reviews":      "Rezensionen",
+	"report.sheet.coverage":     "Abdeckung",
+	"report.sheet.nap":          "NAP-Prüfung",
+	"report.yes":                "Ja",
+	"report.no":                 "Nein",
+	"report.nap.reference":      "Referenz",
+	"report.nap.consistent":     "Name stimmt überein",
+	"report.nap.name_mismatch":  "Name weicht ab",
+	"report.nap.missing_phone":  "Keine Telefonnummer in Google Places",
+	"report.role.resolved":      "Analysiertes Objekt",
+	"report.role.competitor":    "Mitbewerber",
+	"report.coverage":           "%d von %d Plattformen",
+	"report.html.title":         "Präsenzbericht",
+	"report.html.generated":     "Erstellt am %s mit sermersys",
+	"report.html.matrix":        "Abdeckungsmatrix",
+	"report.html.place":         "Objektdaten",
+	"report.html.no_place":      "Für diese Analyse liegen keine Google-Places-Daten vor.",
+	"report.html.coordinates":   "Koordinaten",
+	"report.html.ratings_count": "%d Bewertungen",
+	"report.html.competitors":   "Mitbewerber in der Nähe",
+	"report.html.coverage":      "Plattformabdeckung",
+	"report.html.no_search":     "Die Plattformsuche wurde nicht ausgeführt.",
+	"report.html.ratings":       "Bewertungen nach Plattform",
+	"report.html.trends":        "Sichtbarkeit in den Suchergebnissen nach Woche",
+	"report.html.trends_empty":  "Noch keine Suchhistorie: Der Verlauf erscheint nach wiederholten Analysen.",
+	"report.html.reviews":       "Rezensionen",
+	"report.html.reviews_empty": "Keine Rezensionen.",
+	"report.html.run":           "Angaben zum Lauf",
+	"report.html.analyzed_at":   "Analysiert am",
+	"report.html.duration":      "Dauer",
+	"report.html.listings":      "Gefundene Einträge",
+	"report.html.steps":         "Schritte",
+	"report.pdf.competitors":    "Bewertung im Vergleich zu Mitbewerbern in der Nähe",
+
+	// Письма с отчётами
+	"mail.subject": "Präsenzbericht: %s",
+	"mail.body":    "Guten Tag,\n\nder Präsenzbericht für %s (%s) ist als PDF angehängt.\n\nsermersys",
+
+	// Weboberfläche
+	"ui.title":           "Präsenzanalyse",
+	"ui.heading":         "Präsenzanalyse",
+	"ui.language":        "Sprache",
+	"ui.object_type":     "Objekttyp:",
+	"ui.type_cafe":       "Café",
+	"ui.type_hotel":      "Hotel",
+	"ui.object_name":     "Name des Objekts:",
+	"ui.address":         "Adresse (optional):",
+	"ui.city":            "Stadt:",
+	"ui.country":         "Land:",
+	"ui.api_key":         "API-Schlüssel (falls der Server einen verlangt):",
+	"ui.start":           "Analyse starten",
+	"ui.running":         "Analyse läuft…",
+	"ui.results":         "Analyseergebnisse",
+	"ui.refined_name":    "Präzisierter Name:",
+	"ui.refined_address": "Präzisierte Adresse:",
+	"ui.download":        "Ergebnisse herunterladen",
+	"ui.report":          "Bericht herunterladen",
+	"ui.rating":          "Bewertung",
+	"ui.reviews":         "Bewertungen",
+	"ui.na":              "k. A.",
+	"ui.no_results":      "Das Objekt wurde auf keiner Plattform gefunden",
+	"ui.error":           "Fehler",
+}
@@ -0,0 +1,161 @@
+// sermersys/i18n/en.go
+package i18n
+
+var en = map[string]string{
+	// API errors
+	"error.api_key_required":      "API key required",
+	"error.api_key_unknown":       "Unknown API key",
+	"error.api_key_revoked":       "API key has been revoked",
+	"error.forbidden":             "Not allowed for this API key",
+	"error.rate_limited":          "Rate limit exceeded",
+	"error.quota_exceeded":        "Daily analysis quota exhausted",
+	"error.auth_failed":           "Failed to check the API key",
+	"error.workspace_not_found":   "Workspace not found",
+	"error.workspace_load":        "Failed to load the workspace",
+	"error.method_not_allowed":    "Method not allowed",
+	"error.read_body":             "Failed to read the request body",
+	"error.invalid_json":          "Invalid JSON",
+	"error.required_fields":       "Required fields are missing",
+	"error.platforms_not_allowed": "Platform catalog is not allowed in this workspace",
+	"error.templates_not_allowed": "Query templates file is not allowed in this workspace",
+	"error.invalid_wait":          "wait: expected true, false or a duration such as 30s",
+	"error.invalid_limit":         "limit: expected a positive integer",
+	"error.missing_parameter":     "Missing %s parameter",
+	"error.queue_full":            "Analysis queue is full",
+	"error.shutting_down":         "The server is shutting down, retry the request later",
+	"error.analysis_not_found":    "Analysis not found",
+	"error.analysis_read":         "Failed to read the analysis",
+	"error.analysis_list":         "Failed to read analyses",
+	"error.analysis_not_ready":    "Analysis has no result",
+	"error.analysis_finished":     "Analysis has already finished",
+	"error.resource_not_found":    "Resource not found",
+	"error.file_not_found":        "File not found",
+	"error.result_not_found":      "Result not found",
+	"error.bad_signature":         "Invalid link signature",
+	"error.link_expired":          "Link has expired",
+	"error.serp_report":           "Failed to build the report: %v",
+	"error.timeout":               "Analysis timed out",
+	"error.canceled":              "Analysis was canceled",
+	"error.no_places":             "Google Places did not find the property",
+	"error.upstream_places":       "Google Places API error",
+	"error.upstream":              "Google API is unavailable",
+	"error.analysis":              "Analysis failed: %v",
+	"error.internal":              "Internal server error",
+	"error.no_places_legacy":      "No results from mapsearchg",
+	"error.invalid_locale":        "locale: unsupported language %q",
+	"error.mail_disabled":         "Email delivery is not configured on the server",
+	"error.invalid_recipients":    "Invalid email_to: %v",
+	"error.mail_requires_auth":    "Emailing the report (email_to) requires an API key",
+	"error.recipient_not_allowed": "Sending to %s is not allowed: its domain is not in mail.allowed_domains",
+	"error.batch_empty":           "The batch has no requests",
+	"error.batch_queue_full":      "The analysis queue has no room for the whole batch",
+	"error.batch_not_found":       "Batch not found",
+	"error.batch_read":            "Failed to read the batch",
+
+	// Analysis steps
+	"step.places_request":  "1️⃣ Request to mapsearchg for the exact name and address",
+	"step.places_received": "2️⃣ Refined data received from mapsearchg",
+	"step.search_request":  "3️⃣ Request to googlesearch.FetchData with the refined data",
+	"step.done":            "4️⃣ Analysis complete",
+	"step.duration":        "⏳ Execution time: %v",
+
+	// CSV headers (as before localization)
+	"csv.platform":           "Platform",
+	"csv.title":              "Title",
+	"csv.link":               "Link",
+	"csv.page":               "Page",
+	"csv.position":           "Position",
+	"csv.match_score":        "Match Score",
+	"csv.rating":             "Rating",
+	"csv.user_ratings":       "User Ratings",
+	"csv.review_author":      "Review Author",
+	"csv.review_rating":      "Review Rating",
+	"csv.review_text":        "Review Text",
+	"csv.query":              "Query",
+	"csv.index":              "Index",
+	"csv.rule":               "Rule",
+	"csv.score":              "Score",
+	"csv.detail":             "Detail",
+	"csv.timestamp":          "Timestamp",
+	"csv.name":               "Name",
+	"csv.formatted_address":  "FormattedAddress",
+	"csv.lat":                "Lat",
+	"csv.lng":                "Lng",
+	"csv.place_id":           "PlaceID",
+	"csv.website":            "Website",
+	"csv.phone":              "Phone",
+	"csv.user_ratings_total": "UserRatingsTotal",
+	"csv.object":             "Object",
+	"csv.found":              "Found",
+	"csv.source":             "Source",
+	"csv.status":             "Status",
+	"csv.role":               "Role",
+	"csv.coverage":           "Coverage",
+	"csv.found_on":           "Found On",
+
+	// Reports (XLSX, KML, HTML, PDF)
+	"report.sheet.places":       "Places",
+	"report.sheet.listings":     "Listings",
+	"report.sheet.reviews":      "Reviews",
+	"report.sheet.coverage":     "Coverage",
+	"report.sheet.nap":          "NAP audit",
+	"report.yes":                "Yes",
+	"report.no":                 "No",
+	"report.nap.reference":      "Reference",
+	"report.nap.consistent":     "Name matches",
+	"report.nap.name_mismatch":  "Name differs",
+	"report.nap.missing_phone":  "No phone in Google Places",
+	"report.role.resolved":      "Analysed object",
+	"report.role.competitor":    "Competitor",
+	"report.coverage":           "%d of %d platforms",
+	"report.html.title":         "Presence report",
+	"report.html.generated":     "Generated %s by sermersys",
+	"report.html.matrix":        "Coverage matrix",
+	"report.html.place":         "Place details",
+	"report.html.no_place":      "Google Places data is not available for this analysis.",
+	"report.html.coordinates":   "Coordinates",
+	"report.html.ratings_count": "%d ratings",
+	"report.html.competitors":   "Nearby competitors",
+	"report.html.coverage":      "Platform coverage",
+	"report.html.no_search":     "The platform search was not run.",
+	"report.html.ratings":       "Ratings by platform",
+	"report.html.trends":        "Search visibility by week",
+	"report.html.trends_empty":  "No search history yet: the trend appears after repeated analyses.",
+	"report.html.reviews":       "Reviews",
+	"report.html.reviews_empty": "No reviews.",
+	"report.html.run":           "Run details",
+	"report.html.analyzed_at":   "Analysed at",
+	"report.html.duration":      "Duration",
+	"report.html.listings":      "Listings found",
+	"report.html.steps":         "Steps",
+	"report.pdf.competitors":    "Rating compared with nearby competitors",
+
+	// Письма с отчётами
+	"mail.subject": "Presence report: %s",
+	"mail.body":    "Hello,\n\nthe presence report for %s (%s) is attached as a PDF.\n\nsermersys",
+
+	// Web UI
+	"ui.title":           "Search Analyzer",
+	"ui.heading":         "Search Analyzer",
+	"ui.language":        "Language",
+	"ui.object_type":     "Type of object:",
+	"ui.type_cafe":       "Cafe",
+	"ui.type_hotel":      "Hotel",
+	"ui.object_name":     "Object Name:",
+	"ui.address":         "Address (optional):",
+	"ui.city":            "City:",
+	"ui.country":         "Country:",
+	"ui.api_key":         "API key (if the server requires one):",
+	"ui.start":           "Start Analysis",
+	"ui.running":         "Analysis in progress…",
+	"ui.results":         "Analysis Results",
+	"ui.refined_name":    "Refined Name:",
+	"ui.refined_address": "Refined Address:",
+	"ui.download":        "Download Results",
+	"ui.report":          "Download Report",
+	"ui.rating":          "Rating",
+	"ui.reviews":         "reviews",
+	"ui.na":              "N/A",
+	"ui.no_results":      "The property was not found on any platform",
+	"ui.error":           "Error",
+}
@@ -0,0 +1,147 @@
+// sermersys/i18n/i18n.go
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lang - язык сообщений
+type Lang string
+
+// Поддерживаемые языки
+const (
+	RU Lang = "ru"
+	EN Lang = "en"
+	DE Lang = "de"
+)
+
+// Default - язык сообщений API, если клиент его не указал
+const Default = RU
+
+// fallback - порядок поиска сообщения, отсутствующего в каталоге выбранного языка
+var fallback = []Lang{EN, RU}
+
+// catalogs - каталоги сообщений по языкам
+var catalogs = map[Lang]map[string]string{
+	RU: ru,
+	EN: en,
+	DE: de,
+}
+
+// Languages возвращает поддерживаемые языки
+func Languages() []Lang {
+	return []Lang{RU, EN, DE}
+}
+
+// Parse разбирает код языка ("en", "en-US", "ru_RU"); второе значение - поддерживается ли язык
+func Parse(s string) (Lang, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexAny(s, "-_"); i >= 0 {
+		s = s[:i]
+	}
+	lang := Lang(s)
+	_, ok := catalogs[lang]
+	return lang, ok
+}
+
+// Resolve возвращает язык s, если он поддерживается, иначе def
+func Resolve(s string, def Lang) Lang {
+	if lang, ok := Parse(s); ok {
+		return lang
+	}
+	return def
+}
+
+// Negotiate выбирает язык по заголовку Accept-Language с учётом весов q
+func Negotiate(acceptLanguage string, def Lang) Lang {
+	type candidate struct {
+		lang Lang
+		q    float64
+		pos  int
+	}
+	var candidates []candidate
+	for i, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang, ok := Parse(fields[0])
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, found := strings.CutPrefix(strings.TrimSpace(param), "q="); found {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{lang, q, i})
+		}
+	}
+	if len(candidates) == 0 {
+		return def
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// T возвращает сообщение key на языке lang, подставляя args (как в fmt.Sprintf).
+// Если в каталоге языка сообщения нет, используется английский, затем русский;
+// неизвестный ключ возвращается как есть, что позволяет передавать уже готовый текст.
+func T(lang Lang, key string, args ...interface{}) string {
+	format, ok := catalogs[lang][key]
+	for _, l := range fallback {
+		if ok {
+			break
+		}
+		format, ok = catalogs[l][key]
+	}
+	if !ok {
+		format = key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Strings возвращает сообщения с префиксом prefix на языке lang (с учётом запасных языков)
+func Strings(lang Lang, prefix string) map[string]string {
+	result := make(map[string]string)
+	for _, l := range append([]Lang{lang}, fallback...) {
+		for key, value := range catalogs[l] {
+			if _, seen := result[key]; !seen && strings.HasPrefix(key, prefix) {
+				result[key] = value
+			}
+		}
+	}
+	return result
+}
+
+// Header возвращает заголовки колонок CSV на языке lang: сообщения csv.<column>
+func Header(lang Lang, columns ...string) []string {
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = T(lang, "csv."+column)
+	}
+	return header
+}
+
+type contextKey struct{}
+
+// WithLang возвращает контекст с языком сообщений
+func WithLang(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext возвращает язык из контекста или Default
+func FromContext(ctx context.Context) Lang {
+	if lang, ok := ctx.Value(contextKey{}).(Lang); ok {
+		return lang
+	}
+	return Default
+}
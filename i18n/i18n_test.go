@@ -0,0 +1,87 @@
+// sermersys/i18n/i18n_test.go
+package i18n
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		header string
+		want   Lang
+	}{
+		{"", RU},
+		{"de-DE,de;q=0.9,en;q=0.8", DE},
+		{"fr-FR,fr;q=0.9,en-US;q=0.8,ru;q=0.7", EN},
+		{"ru;q=0.5, en;q=0.9", EN},
+		{"en;q=0, de;q=0.1", DE},
+		{"en;q=0.8, de;q=0.8", EN}, // при равных весах - первый по порядку
+		{"fr, it;q=0.9", RU},
+		{"EN_gb", EN},
+		{"de;q=abc", DE}, // неверный вес считается единицей
+	}
+	for _, c := range cases {
+		if got := Negotiate(c.header, RU); got != c.want {
+			t.Errorf("Negotiate(%q) = %s, ожидалось %s", c.header, got, c.want)
+		}
+	}
+}
+
+func TestParseAndResolve(t *testing.T) {
+	if lang, ok := Parse(" ru_RU "); !ok || lang != RU {
+		t.Errorf("Parse(ru_RU) = %s, %v", lang, ok)
+	}
+	if _, ok := Parse("fr"); ok {
+		t.Errorf("fr считается поддерживаемым")
+	}
+	if got := Resolve("fr", EN); got != EN {
+		t.Errorf("Resolve(fr) = %s", got)
+	}
+	if got := FromContext(context.Background()); got != Default {
+		t.Errorf("язык пустого контекста: %s", got)
+	}
+	if got := FromContext(WithLang(context.Background(), DE)); got != DE {
+		t.Errorf("язык контекста: %s", got)
+	}
+}
+
+func TestTFallback(t *testing.T) {
+	if got := T(EN, "error.invalid_recipients", "bad"); got != "Invalid email_to: bad" {
+		t.Errorf("T: %q", got)
+	}
+	// Неизвестный ключ возвращается как готовый текст
+	if got := T(DE, "Готовый текст"); got != "Готовый текст" {
+		t.Errorf("T с неизвестным ключом: %q", got)
+	}
+	for key := range en {
+		if _, ok := de[key]; !ok && T(DE, key) != en[key] {
+			t.Errorf("%s: нет перевода на немецкий и не использован английский", key)
+		}
+	}
+}
+
+// verbs - подстановки fmt в сообщении
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func TestCatalogsAgree(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for key, format := range catalog {
+			reference, ok := ru[key]
+			if !ok {
+				t.Errorf("%s: ключа %s нет в русском каталоге", lang, key)
+				continue
+			}
+			got, want := verbs.FindAllString(format, -1), verbs.FindAllString(reference, -1)
+			if len(got) != len(want) {
+				t.Errorf("%s: %s: подстановки %v, в русском каталоге %v", lang, key, got, want)
+			}
+		}
+	}
+	for key := range ru {
+		if _, ok := en[key]; !ok {
+			t.Errorf("en: нет перевода %s", key)
+		}
+	}
+}
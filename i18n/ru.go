@@ -0,0 +1,162 @@
+// sermersys/i18n/ru.go
+package i18n
+
+// ru - исходный язык сервиса
+var ru = map[string]string{
+	// Ошибки API
+	"error.api_key_required":      "Требуется ключ API",
+	"error.api_key_unknown":       "Неизвестный ключ API",
+	"error.api_key_revoked":       "Ключ API отозван",
+	"error.forbidden":             "Недостаточно прав для операции",
+	"error.rate_limited":          "Превышен лимит запросов",
+	"error.quota_exceeded":        "Исчерпана дневная квота анализов",
+	"error.auth_failed":           "Ошибка проверки ключа API",
+	"error.workspace_not_found":   "Рабочее пространство не найдено",
+	"error.workspace_load":        "Ошибка загрузки рабочего пространства",
+	"error.method_not_allowed":    "Метод не поддерживается",
+	"error.read_body":             "Ошибка чтения тела запроса",
+	"error.invalid_json":          "Неверный формат JSON",
+	"error.required_fields":       "Не заполнены обязательные поля",
+	"error.platforms_not_allowed": "Каталог платформ не разрешён в рабочем пространстве",
+	"error.templates_not_allowed": "Файл шаблонов запросов не разрешён в рабочем пространстве",
+	"error.invalid_wait":          "wait: ожидается true, false или длительность вида 30s",
+	"error.invalid_limit":         "limit: ожидается положительное целое число",
+	"error.missing_parameter":     "Не указан параметр %s",
+	"error.queue_full":            "Очередь анализов заполнена",
+	"error.shutting_down":         "Сервер останавливается, повторите запрос позже",
+	"error.analysis_not_found":    "Анализ не найден",
+	"error.analysis_read":         "Ошибка чтения анализа",
+	"error.analysis_list":         "Ошибка чтения анализов",
+	"error.analysis_not_ready":    "У анализа нет результата",
+	"error.analysis_finished":     "Анализ уже завершён",
+	"error.resource_not_found":    "Ресурс не найден",
+	"error.file_not_found":        "Файл не найден",
+	"error.result_not_found":      "Результат не найден",
+	"error.bad_signature":         "Неверная подпись ссылки",
+	"error.link_expired":          "Срок действия ссылки истёк",
+	"error.serp_report":           "Ошибка построения отчёта: %v",
+	"error.timeout":               "Превышено время анализа",
+	"error.canceled":              "Анализ отменён",
+	"error.no_places":             "Google Places не нашёл объект",
+	"error.upstream_places":       "Ошибка Google Places API",
+	"error.upstream":              "Google API недоступен",
+	"error.analysis":              "Ошибка анализа: %v",
+	"error.internal":              "Внутренняя ошибка сервера",
+	"error.no_places_legacy":      "Нет результатов в mapsearchg",
+	"error.invalid_locale":        "locale: неподдерживаемый язык %q",
+	"error.mail_disabled":         "Отправка почты на сервере не настроена",
+	"error.invalid_recipients":    "Недопустимый email_to: %v",
+	"error.mail_requires_auth":    "Отправка отчёта по почте (email_to) доступна только с ключом API",
+	"error.recipient_not_allowed": "Отправка на %s не разрешена: домена нет в mail.allowed_domains",
+	"error.batch_empty":           "В пакете нет ни одного запроса",
+	"error.batch_queue_full":      "В очереди анализов не хватает места для всего пакета",
+	"error.batch_not_found":       "Пакет не найден",
+	"error.batch_read":            "Ошибка чтения пакета",
+
+	// Шаги анализа
+	"step.places_request":  "1️⃣ Запрос в mapsearchg для получения точного имени и адреса",
+	"step.places_received": "2️⃣ Уточнённые данные получены от mapsearchg",
+	"step.search_request":  "3️⃣ Запрос в googlesearch.FetchData с уточнёнными данными",
+	"step.done":            "4️⃣ Итоговый анализ завершён",
+	"step.duration":        "⏳ Время выполнения: %v",
+
+	// Заголовки CSV
+	"csv.platform":           "Платформа",
+	"csv.title":              "Заголовок",
+	"csv.link":               "Ссылка",
+	"csv.page":               "Страница",
+	"csv.position":           "Позиция",
+	"csv.match_score":        "Совпадение",
+	"csv.rating":             "Рейтинг",
+	"csv.user_ratings":       "Оценок",
+	"csv.review_author":      "Автор отзыва",
+	"csv.review_rating":      "Оценка в отзыве",
+	"csv.review_text":        "Текст отзыва",
+	"csv.query":              "Запрос",
+	"csv.index":              "Номер на странице",
+	"csv.rule":               "Правило",
+	"csv.score":              "Оценка",
+	"csv.detail":             "Пояснение",
+	"csv.timestamp":          "Время",
+	"csv.name":               "Название",
+	"csv.formatted_address":  "Адрес",
+	"csv.lat":                "Широта",
+	"csv.lng":                "Долгота",
+	"csv.place_id":           "Place ID",
+	"csv.website":            "Сайт",
+	"csv.phone":              "Телефон",
+	"csv.user_ratings_total": "Всего оценок",
+	"csv.object":             "Объект",
+	"csv.found":              "Найдено",
+	"csv.source":             "Источник",
+	"csv.status":             "Статус",
+	"csv.role":               "Роль",
+	"csv.coverage":           "Покрытие",
+	"csv.found_on":           "Найден на",
+
+	// Отчёты (XLSX, KML, HTML, PDF)
+	"report.sheet.places":       "Объекты",
+	"report.sheet.listings":     "Размещения",
+	"report.sheet.reviews":      "Отзывы",
+	"report.sheet.coverage":     "Покрытие",
+	"report.sheet.nap":          "Аудит NAP",
+	"report.yes":                "Да",
+	"report.no":                 "Нет",
+	"report.nap.reference":      "Эталон",
+	"report.nap.consistent":     "Название совпадает",
+	"report.nap.name_mismatch":  "Название отличается",
+	"report.nap.missing_phone":  "Нет телефона в Google Places",
+	"report.role.resolved":      "Объект анализа",
+	"report.role.competitor":    "Конкурент",
+	"report.coverage":           "%d из %d платформ",
+	"report.html.title":         "Отчёт о присутствии",
+	"report.html.generated":     "Сформирован %s, sermersys",
+	"report.html.matrix":        "Матрица покрытия",
+	"report.html.place":         "Данные объекта",
+	"report.html.no_place":      "Данных Google Places для этого анализа нет.",
+	"report.html.coordinates":   "Координаты",
+	"report.html.ratings_count": "оценок: %d",
+	"report.html.competitors":   "Конкуренты рядом",
+	"report.html.coverage":      "Покрытие платформ",
+	"report.html.no_search":     "Поиск по платформам не выполнялся.",
+	"report.html.ratings":       "Рейтинги по платформам",
+	"report.html.trends":        "Видимость в выдаче по неделям",
+	"report.html.trends_empty":  "Истории выдачи пока нет: динамика появится после повторных анализов.",
+	"report.html.reviews":       "Отзывы",
+	"report.html.reviews_empty": "Отзывов нет.",
+	"report.html.run":           "Сведения о запуске",
+	"report.html.analyzed_at":   "Время анализа",
+	"report.html.duration":      "Длительность",
+	"report.html.listings":      "Найдено размещений",
+	"report.html.steps":         "Шаги",
+	"report.pdf.competitors":    "Рейтинг в сравнении с конкурентами рядом",
+
+	// Письма с отчётами
+	"mail.subject": "Отчёт о присутствии: %s",
+	"mail.body":    "Здравствуйте!\n\nОтчёт о присутствии объекта %s (%s) - во вложении в формате PDF.\n\nsermersys",
+
+	// Веб-интерфейс
+	"ui.title":           "Анализ присутствия",
+	"ui.heading":         "Анализ присутствия",
+	"ui.language":        "Язык",
+	"ui.object_type":     "Тип объекта:",
+	"ui.type_cafe":       "Кафе",
+	"ui.type_hotel":      "Отель",
+	"ui.object_name":     "Название объекта:",
+	"ui.address":         "Адрес (необязательно):",
+	"ui.city":            "Город:",
+	"ui.country":         "Страна:",
+	"ui.api_key":         "Ключ API (если сервер его требует):",
+	"ui.start":           "Начать анализ",
+	"ui.running":         "Анализ выполняется…",
+	"ui.results":         "Результаты анализа",
+	"ui.refined_name":    "Уточнённое название:",
+	"ui.refined_address": "Уточнённый адрес:",
+	"ui.download":        "Скачать результаты",
+	"ui.report":          "Скачать отчёт",
+	"ui.rating":          "Рейтинг",
+	"ui.reviews":         "отзывов",
+	"ui.na":              "нет данных",
+	"ui.no_results":      "Объект не найден ни на одной платформе",
+	"ui.error":           "Ошибка",
+}
@@ -0,0 +1,180 @@
+// sermersys/logging/logging.go
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Форматы журнала
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// ValidFormat сообщает, поддерживается ли формат журнала
+func ValidFormat(format string) bool {
+	return format == FormatJSON || format == FormatText
+}
+
+// ParseLevel разбирает уровень журнала: debug, info, warn, error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("неизвестный уровень журнала %q, допустимые: debug, info, warn, error", s)
+	}
+	return level, nil
+}
+
+// Setup настраивает журнал по умолчанию (slog и стандартный log) с заданным уровнем и форматом.
+// Атрибуты из контекста (см. With) добавляются к каждой записи, секреты в строках маскируются.
+func Setup(w io.Writer, level, format string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	if w == nil {
+		w = os.Stderr
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redactAttr}
+	var h slog.Handler
+	switch format {
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("неизвестный формат журнала %q, допустимые: json, text", format)
+	}
+	slog.SetDefault(slog.New(contextHandler{h}))
+	return nil
+}
+
+// =================== Атрибуты из контекста ===================
+
+type attrsKey struct{}
+
+// With возвращает контекст, записи журнала в котором получают атрибуты args
+// (пары ключ-значение, как в slog): ID запроса, ID анализа, рабочее пространство
+func With(ctx context.Context, args ...any) context.Context {
+	attrs := append([]slog.Attr(nil), contextAttrs(ctx)...)
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(args...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+func contextAttrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler добавляет к записи атрибуты из контекста и ID трассировки и спана,
+// если в контексте есть записываемый спан (см. пакет tracing)
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := contextAttrs(ctx)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs[:len(attrs):len(attrs)],
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()))
+	}
+	if len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// =================== Маскирование секретов ===================
+
+// secretParam - параметры URL с секретами: ключ Google API, подпись ссылки
+var secretParam = regexp.MustCompile(`([?&](?:key|sig)=)[^&\s"']+`)
+
+// apiToken - ключи HTTP API сервиса
+var apiToken = regexp.MustCompile(`smk_[0-9a-f]{8,}`)
+
+// Redact маскирует ключи API и подписи в строке (URL, сообщении об ошибке)
+func Redact(s string) string {
+	if !strings.Contains(s, "=") && !strings.Contains(s, "smk_") {
+		return s
+	}
+	s = secretParam.ReplaceAllString(s, "${1}REDACTED")
+	return apiToken.ReplaceAllString(s, "smk_REDACTED")
+}
+
+// RedactURL возвращает URL без секретов для журнала
+func RedactURL(u *url.URL) string {
+	return Redact(u.String())
+}
+
+// RedactError убирает секреты из URL в ошибке HTTP-клиента (*url.Error), которая иначе
+// попадает в журнал и ответы API вместе с ключом Google API
+func RedactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = Redact(urlErr.URL)
+	}
+	return err
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if redacted := Redact(a.Value.String()); redacted != a.Value.String() {
+			return slog.String(a.Key, redacted)
+		}
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
+
+// =================== Запросы к внешним API ===================
+
+// Upstream записывает одно событие о запросе к внешнему API: адрес без секретов, статус ответа
+// (поле status или HTTP-код), HTTP-код и длительность. Ошибки и статусы, отличные от OK,
+// ZERO_RESULTS и 200, записываются с уровнем warn.
+func Upstream(ctx context.Context, api string, u *url.URL, status string, httpStatus int, start time.Time, err error) {
+	level := slog.LevelInfo
+	if err != nil || (status != "OK" && status != "ZERO_RESULTS" && status != "200") {
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("api", api),
+		slog.String("url", RedactURL(u)),
+		slog.String("status", status),
+		slog.Int("http_status", httpStatus),
+		slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", Redact(err.Error())))
+	}
+	slog.LogAttrs(ctx, level, "запрос к Google API", attrs...)
+}
@@ -0,0 +1,219 @@
+// sermersys/mailer/mailer.go
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"sermersys/config"
+	"sermersys/i18n"
+	"sermersys/tracing"
+)
+
+// Отправка писем с отчётами через SMTP: текст письма и вложения (PDF), без внешних библиотек.
+
+// MaxRecipients - сколько получателей можно указать в одном запросе
+const MaxRecipients = 10
+
+// sendTimeout - время на отправку письма, если в контексте нет своего срока
+const sendTimeout = time.Minute
+
+// Attachment - вложение письма
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message - письмо с текстом и вложениями
+type Message struct {
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Report строит письмо с PDF-отчётом об объекте на языке lang
+func Report(lang i18n.Lang, to []string, object, address, filename string, pdf []byte) Message {
+	return Message{
+		To:          to,
+		Subject:     i18n.T(lang, "mail.subject", object),
+		Body:        i18n.T(lang, "mail.body", object, address),
+		Attachments: []Attachment{{Filename: filename, ContentType: "application/pdf", Data: pdf}},
+	}
+}
+
+// ParseRecipients проверяет адреса получателей и возвращает их без имён
+func ParseRecipients(to []string) ([]string, error) {
+	if len(to) > MaxRecipients {
+		return nil, fmt.Errorf("не больше %d получателей", MaxRecipients)
+	}
+	addresses := make([]string, 0, len(to))
+	for _, value := range to {
+		address, err := mail.ParseAddress(value)
+		if err != nil {
+			return nil, fmt.Errorf("недопустимый адрес %q", value)
+		}
+		addresses = append(addresses, address.Address)
+	}
+	return addresses, nil
+}
+
+// Send отправляет письмо через SMTP-сервер cfg. На порту 465 соединение шифруется сразу,
+// на остальных - командой STARTTLS, если сервер её поддерживает. Пароль передаётся
+// только по зашифрованному соединению (или на localhost).
+func Send(ctx context.Context, cfg config.Mail, msg Message) (err error) {
+	ctx, span := tracing.Start(ctx, "mail.send", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("server.address", cfg.Host),
+		attribute.Int("server.port", cfg.Port),
+		attribute.Int("mail.recipients", len(msg.To)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if !cfg.Enabled() {
+		return fmt.Errorf("отправка почты не настроена (mail.host)")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return fmt.Errorf("недопустимый адрес отправителя: %v", err)
+	}
+	to, err := ParseRecipients(msg.To)
+	if err != nil {
+		return err
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("не указаны получатели")
+	}
+	data, err := buildMessage(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(sendTimeout)
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	if cfg.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: cfg.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к SMTP-серверу: %v", err)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("ошибка SMTP: %v", err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok && cfg.Port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			return fmt.Errorf("ошибка STARTTLS: %v", err)
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("ошибка авторизации SMTP: %v", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("ошибка SMTP MAIL FROM: %v", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("сервер отклонил получателя %s: %v", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("ошибка SMTP DATA: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("ошибка отправки письма: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("ошибка отправки письма: %v", err)
+	}
+	return client.Quit()
+}
+
+// buildMessage собирает письмо MIME: текст в quoted-printable и вложения в base64
+func buildMessage(from *mail.Address, to []string, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	recipients := make([]string, len(to))
+	for i, address := range to {
+		recipients[i] = (&mail.Address{Address: address}).String()
+	}
+	header := []struct{ key, value string }{
+		{"From", from.String()},
+		{"To", strings.Join(recipients, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": body.Boundary()})},
+	}
+	var head bytes.Buffer
+	for _, h := range header {
+		fmt.Fprintf(&head, "%s: %s\r\n", h.key, h.value)
+	}
+	head.WriteString("\r\n")
+
+	part, err := body.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	qp := quotedprintable.NewWriter(part)
+	qp.Write([]byte(strings.ReplaceAll(msg.Body, "\n", "\r\n")))
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, a := range msg.Attachments {
+		part, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(a.ContentType, map[string]string{"name": a.Filename})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// base64 в письме разбивается на строки не длиннее 76 символов
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return append(head.Bytes(), buf.Bytes()...), nil
+}
@@ -0,0 +1,234 @@
+// sermersys/mapsearchg/client.go
+package mapsearchg
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"sermersys/config"
+	"sermersys/googleapi"
+	"sermersys/metrics"
+	"sermersys/model"
+	"sermersys/tracing"
+)
+
+// Статусы Google Places API
+const (
+	StatusOK             = "OK"
+	StatusZeroResults    = "ZERO_RESULTS"
+	StatusOverQueryLimit = googleapi.StatusOverQueryLimit
+	StatusRequestDenied  = "REQUEST_DENIED"
+	StatusInvalidRequest = "INVALID_REQUEST"
+)
+
+// APIError - Google Places API вернул статус, отличный от OK и ZERO_RESULTS
+type APIError struct {
+	API     string // textsearch или details
+	Status  string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("Google Places %s вернул статус %s: %s", e.API, e.Status, e.Message)
+	}
+	return fmt.Sprintf("Google Places %s вернул статус %s", e.API, e.Status)
+}
+
+// Client - клиент Google Places API.
+// HTTP-клиент и адреса API задаются явно, что позволяет подменять их (например, на httptest-сервер).
+type Client struct {
+	HTTP          *http.Client
+	APIKey        string
+	TextSearchURL string
+	DetailsURL    string
+	Concurrency   int           // число параллельных запросов Place Details
+	MaxPages      int           // сколько страниц Text Search запрашивать (по 20 результатов)
+	PageDelay     time.Duration // пауза перед запросом по next_page_token (токен активируется не сразу)
+	Retries       int           // сколько раз повторять запрос на OVER_QUERY_LIMIT
+	RetryDelay    time.Duration // пауза перед первым повтором, дальше удваивается
+}
+
+// NewClient создаёт клиент по конфигурации
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		HTTP:          cfg.HTTPClient(),
+		APIKey:        cfg.GoogleAPIKey,
+		TextSearchURL: cfg.Endpoints.PlacesTextSearch,
+		DetailsURL:    cfg.Endpoints.PlacesDetails,
+		Concurrency:   cfg.Concurrency,
+		MaxPages:      1,
+		PageDelay:     2 * time.Second,
+		Retries:       2,
+		RetryDelay:    time.Second,
+	}
+}
+
+// =================== Text Search API ===================
+
+// TextSearch вызывает Places Text Search API и возвращает результаты со всех запрошенных страниц.
+// ZERO_RESULTS не считается ошибкой - возвращается пустой срез.
+func (c *Client) TextSearch(ctx context.Context, query string) ([]TextSearchResult, error) {
+	var results []TextSearchResult
+	pageToken := ""
+	for page := 0; page < max(c.MaxPages, 1); page++ {
+		params := url.Values{}
+		if pageToken == "" {
+			params.Set("query", query)
+		} else {
+			params.Set("pagetoken", pageToken)
+			if err := googleapi.SleepContext(ctx, c.PageDelay); err != nil {
+				return nil, err
+			}
+		}
+
+		var tsr TextSearchResponse
+		start := time.Now()
+		pageCtx, span := tracing.Start(ctx, "places.text_search", trace.WithAttributes(attribute.Int("page", page+1)))
+		err := googleapi.Retry(pageCtx, c.Retries, c.RetryDelay, func() (bool, error) {
+			tsr = TextSearchResponse{}
+			err := c.getJSON(pageCtx, "places_textsearch", c.TextSearchURL, params, &tsr)
+			return err == nil && tsr.Status == StatusOverQueryLimit, err
+		})
+		metrics.Since(metrics.StepPlacesTextSearch, start)
+		span.SetAttributes(attribute.Int("result.count", len(tsr.Results)))
+		tracing.End(span, err)
+		if err != nil {
+			return nil, err
+		}
+
+		switch tsr.Status {
+		case StatusOK:
+			results = append(results, tsr.Results...)
+		case StatusZeroResults:
+			slog.DebugContext(ctx, "Text Search не нашёл объектов", "query", query)
+			return results, nil
+		default:
+			return nil, &APIError{API: "textsearch", Status: tsr.Status, Message: tsr.ErrorMessage}
+		}
+
+		if tsr.NextPageToken == "" {
+			break
+		}
+		pageToken = tsr.NextPageToken
+	}
+	return results, nil
+}
+
+// =================== Place Details API ===================
+
+// PlaceDetails вызывает Places Details API и возвращает результат
+func (c *Client) PlaceDetails(ctx context.Context, placeID string) (_ *PlaceDetailsResult, err error) {
+	defer metrics.Since(metrics.StepPlacesDetails, time.Now())
+	ctx, span := tracing.Start(ctx, "places.details", trace.WithAttributes(attribute.String("place_id", placeID)))
+	defer func() { tracing.End(span, err) }()
+	params := url.Values{}
+	params.Set("place_id", placeID)
+	params.Set("fields", "formatted_address,name,geometry,place_id,website,formatted_phone_number,rating,user_ratings_total")
+
+	var pdr PlaceDetailsResponse
+	err = googleapi.Retry(ctx, c.Retries, c.RetryDelay, func() (bool, error) {
+		pdr = PlaceDetailsResponse{}
+		err := c.getJSON(ctx, "places_details", c.DetailsURL, params, &pdr)
+		return err == nil && pdr.Status == StatusOverQueryLimit, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pdr.Status != StatusOK {
+		return nil, &APIError{API: "details", Status: pdr.Status, Message: pdr.ErrorMessage}
+	}
+	return &pdr.Result, nil
+}
+
+// =================== Поиск объекта ===================
+
+// SearchPlaces находит объекты текстовым поиском и дополняет их данными Place Details
+func (c *Client) SearchPlaces(ctx context.Context, data RequestData) ([]model.Place, error) {
+	// Формируем поисковый запрос
+	query := fmt.Sprintf("%s, %s, %s", data.ObjectName, data.City, data.Country)
+
+	// Выполняем текстовый поиск
+	textResults, err := c.TextSearch(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка TextSearch: %w", err)
+	}
+
+	if len(textResults) == 0 {
+		return nil, fmt.Errorf("нет результатов для запроса: %s", query)
+	}
+
+	// Запрашиваем детали параллельно, не более c.Concurrency запросов одновременно
+	details := make([]*PlaceDetailsResult, len(textResults))
+	errs := make([]error, len(textResults))
+	sem := make(chan struct{}, max(c.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, r := range textResults {
+		wg.Add(1)
+		go func(i int, placeID string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			d, err := c.PlaceDetails(ctx, placeID)
+			if err != nil {
+				slog.WarnContext(ctx, "не удалось получить детали объекта", "place_id", placeID, "error", err)
+				errs[i] = err
+				return
+			}
+			details[i] = d
+		}(i, r.PlaceID)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("поиск прерван: %w", err)
+	}
+
+	var finalResults []model.Place
+	for _, d := range details {
+		if d == nil {
+			continue
+		}
+		finalResults = append(finalResults, model.Place{
+			Timestamp:        time.Now().Format(time.RFC3339),
+			Name:             d.Name,
+			FormattedAddress: d.FormattedAddress,
+			Lat:              d.Geometry.Location.Lat,
+			Lng:              d.Geometry.Location.Lng,
+			PlaceID:          d.PlaceID,
+			Website:          d.Website,
+			Phone:            d.FormattedPhoneNumber,
+			Rating:           d.Rating,
+			UserRatingsTotal: d.UserRatingsTotal,
+		})
+	}
+
+	// Если детали не получены ни для одного объекта, возвращаем первую ошибку (например, OVER_QUERY_LIMIT)
+	if len(finalResults) == 0 {
+		for _, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("ошибка PlaceDetails: %w", err)
+			}
+		}
+	}
+	return finalResults, nil
+}
+
+// =================== HTTP ===================
+
+// getJSON выполняет GET-запрос к Google Places API (см. googleapi.GetJSON)
+func (c *Client) getJSON(ctx context.Context, api, baseURL string, params url.Values, v interface{}) error {
+	return googleapi.GetJSON(ctx, c.HTTP, c.APIKey, api, baseURL, params, v)
+}
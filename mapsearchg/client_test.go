@@ -0,0 +1,261 @@
+// sermersys/mapsearchg/client_test.go
+package mapsearchg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sermersys/googleapi"
+)
+
+// newTestClient создаёт клиент поддельного Google Places API с обработчиками Text Search и Place Details
+func newTestClient(t *testing.T, textSearch, details http.HandlerFunc) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	if textSearch != nil {
+		mux.HandleFunc("/textsearch/json", textSearch)
+	}
+	if details != nil {
+		mux.HandleFunc("/details/json", details)
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return &Client{
+		HTTP:          srv.Client(),
+		APIKey:        "test-key",
+		TextSearchURL: srv.URL + "/textsearch/json",
+		DetailsURL:    srv.URL + "/details/json",
+		Concurrency:   2,
+		MaxPages:      1,
+		Retries:       2,
+		RetryDelay:    time.Millisecond,
+	}
+}
+
+func TestTextSearchZeroResults(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "test-key" {
+			t.Errorf("ключ API не передан: %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"status":"ZERO_RESULTS","results":[]}`)
+	}, nil)
+
+	results, err := c.TextSearch(context.Background(), "Nowhere Hotel, Budva, Montenegro")
+	if err != nil {
+		t.Fatalf("ZERO_RESULTS не должен быть ошибкой: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("ожидался пустой результат, получено %d", len(results))
+	}
+}
+
+func TestSearchPlacesZeroResults(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"ZERO_RESULTS","results":[]}`)
+	}, nil)
+
+	_, err := c.SearchPlaces(context.Background(), RequestData{ObjectName: "Nowhere Hotel", City: "Budva"})
+	if err == nil || !strings.Contains(err.Error(), "нет результатов") {
+		t.Fatalf("ожидалась ошибка «нет результатов», получено %v", err)
+	}
+}
+
+func TestTextSearchOverQueryLimitRetry(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			fmt.Fprint(w, `{"status":"OVER_QUERY_LIMIT","error_message":"You have exceeded your rate-limit"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"OK","results":[{"place_id":"P1","name":"Hotel Adriatic"}]}`)
+	}, nil)
+
+	results, err := c.TextSearch(context.Background(), "Hotel Adriatic, Budva")
+	if err != nil {
+		t.Fatalf("после повторов ожидался успех: %v", err)
+	}
+	if len(results) != 1 || results[0].PlaceID != "P1" {
+		t.Errorf("неожиданные результаты: %+v", results)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("запросов %d, ожидалось 3 (два повтора)", n)
+	}
+}
+
+func TestTextSearchOverQueryLimitExhausted(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fmt.Fprint(w, `{"status":"OVER_QUERY_LIMIT","error_message":"You have exceeded your daily request quota"}`)
+	}, nil)
+
+	_, err := c.TextSearch(context.Background(), "Hotel Adriatic, Budva")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != StatusOverQueryLimit || apiErr.API != "textsearch" {
+		t.Fatalf("ожидалась APIError OVER_QUERY_LIMIT, получено %v", err)
+	}
+	if n := calls.Load(); n != int32(c.Retries+1) {
+		t.Errorf("запросов %d, ожидалось %d", n, c.Retries+1)
+	}
+}
+
+func TestTextSearchRequestDeniedNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fmt.Fprint(w, `{"status":"REQUEST_DENIED","error_message":"The provided API key is invalid."}`)
+	}, nil)
+
+	_, err := c.TextSearch(context.Background(), "Hotel Adriatic, Budva")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != StatusRequestDenied {
+		t.Fatalf("ожидалась APIError REQUEST_DENIED, получено %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("REQUEST_DENIED не должен повторяться, запросов %d", n)
+	}
+}
+
+func TestTextSearchMalformedJSON(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"OK","results":[{"place_id":`)
+	}, nil)
+
+	_, err := c.TextSearch(context.Background(), "Hotel Adriatic, Budva")
+	if err == nil || !strings.Contains(err.Error(), "парсинга JSON") {
+		t.Fatalf("ожидалась ошибка разбора JSON, получено %v", err)
+	}
+}
+
+func TestTextSearchHTTPError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"error":{"message":"backend error"}}`)
+	}, nil)
+
+	_, err := c.TextSearch(context.Background(), "Hotel Adriatic, Budva")
+	var httpErr *googleapi.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusInternalServerError || httpErr.Message != "backend error" {
+		t.Fatalf("ожидалась HTTPError 500 с сообщением, получено %v", err)
+	}
+	if strings.Contains(err.Error(), "test-key") {
+		t.Errorf("ключ API попал в текст ошибки: %v", err)
+	}
+}
+
+func TestTextSearchPagination(t *testing.T) {
+	var pages []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("query") != "":
+			pages = append(pages, "first")
+			fmt.Fprint(w, `{"status":"OK","results":[{"place_id":"P1"},{"place_id":"P2"}],"next_page_token":"T2"}`)
+		case q.Get("pagetoken") == "T2":
+			pages = append(pages, "T2")
+			fmt.Fprint(w, `{"status":"OK","results":[{"place_id":"P3"}],"next_page_token":"T3"}`)
+		case q.Get("pagetoken") == "T3":
+			pages = append(pages, "T3")
+			fmt.Fprint(w, `{"status":"OK","results":[{"place_id":"P4"}]}`)
+		default:
+			t.Errorf("неожиданный запрос: %s", r.URL.RawQuery)
+		}
+	}, nil)
+
+	cases := []struct {
+		maxPages int
+		want     []string
+		pages    int
+	}{
+		{1, []string{"P1", "P2"}, 1},
+		{2, []string{"P1", "P2", "P3"}, 2},
+		{5, []string{"P1", "P2", "P3", "P4"}, 3}, // последняя страница без next_page_token
+	}
+	for _, tc := range cases {
+		pages = nil
+		c.MaxPages = tc.maxPages
+		results, err := c.TextSearch(context.Background(), "hotels in Budva")
+		if err != nil {
+			t.Fatalf("MaxPages=%d: %v", tc.maxPages, err)
+		}
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.PlaceID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("MaxPages=%d: получено %v, ожидалось %v", tc.maxPages, ids, tc.want)
+		}
+		if len(pages) != tc.pages {
+			t.Errorf("MaxPages=%d: запрошено страниц %d (%v), ожидалось %d", tc.maxPages, len(pages), pages, tc.pages)
+		}
+	}
+}
+
+func TestTextSearchPaginationCanceled(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"OK","results":[{"place_id":"P1"}],"next_page_token":"T2"}`)
+	}, nil)
+	c.MaxPages = 2
+	c.PageDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.TextSearch(ctx, "hotels in Budva"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ожидалась отмена во время паузы перед следующей страницей, получено %v", err)
+	}
+}
+
+func TestSearchPlacesDetails(t *testing.T) {
+	c := newTestClient(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"status":"OK","results":[{"place_id":"P1"},{"place_id":"P2"},{"place_id":"P3"}]}`)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			switch id := r.URL.Query().Get("place_id"); id {
+			case "P1":
+				fmt.Fprint(w, `{"status":"OK","result":{"place_id":"P1","name":"Hotel Adriatic","formatted_address":"Slovenska obala 1, Budva","geometry":{"location":{"lat":42.28,"lng":18.84}},"rating":4.4,"user_ratings_total":812,"formatted_phone_number":"+382 33 000 000"}}`)
+			case "P2":
+				fmt.Fprint(w, `{"status":"NOT_FOUND"}`)
+			default:
+				fmt.Fprint(w, `{"status":"OK","result":{"place_id":"`+id+`","name":"Hotel Mogren"}}`)
+			}
+		})
+
+	places, err := c.SearchPlaces(context.Background(), RequestData{ObjectName: "Hotel Adriatic", City: "Budva", Country: "Montenegro"})
+	if err != nil {
+		t.Fatalf("SearchPlaces: %v", err)
+	}
+	if len(places) != 2 {
+		t.Fatalf("объектов %d, ожидалось 2 (P2 без деталей пропускается): %+v", len(places), places)
+	}
+	p := places[0]
+	if p.PlaceID != "P1" || p.Name != "Hotel Adriatic" || p.Lat != 42.28 || p.Lng != 18.84 || p.Rating != 4.4 || p.UserRatingsTotal != 812 || p.Phone != "+382 33 000 000" {
+		t.Errorf("неожиданный первый объект: %+v", p)
+	}
+	if places[1].PlaceID != "P3" {
+		t.Errorf("порядок объектов нарушен: %+v", places)
+	}
+}
+
+func TestSearchPlacesDetailsAllFailed(t *testing.T) {
+	c := newTestClient(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"status":"OK","results":[{"place_id":"P1"}]}`)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"status":"OVER_QUERY_LIMIT"}`)
+		})
+
+	_, err := c.SearchPlaces(context.Background(), RequestData{ObjectName: "Hotel Adriatic", City: "Budva"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.API != "details" || apiErr.Status != StatusOverQueryLimit {
+		t.Fatalf("ожидалась APIError details OVER_QUERY_LIMIT, получено %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+// sermersys/metrics/metrics.go
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Метрики в формате Prometheus на основе client_golang: счётчики, измерители и гистограммы
+// с метками. Все метрики регистрируются в реестре пакета при создании и выводятся обработчиком Handler.
+// Типы пакета оставляют вызовам только значения меток, чтобы код сервиса не зависел от client_golang.
+
+// DefaultBuckets - границы гистограмм длительности в секундах: от быстрых ответов API до полного анализа
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// registry - реестр метрик сервиса; стандартный реестр client_golang не используется,
+// чтобы /metrics отдавал только метрики sermersys. Тесты передают свой реестр в newCounter и т. п.
+var registry = prometheus.NewRegistry()
+
+// =================== Счётчик и измеритель ===================
+
+// Counter - монотонно растущий счётчик с метками
+type Counter struct {
+	vec *prometheus.CounterVec
+}
+
+// NewCounter создаёт и регистрирует счётчик
+func NewCounter(name, help string, labels ...string) *Counter {
+	return newCounter(registry, name, help, labels...)
+}
+
+func newCounter(reg prometheus.Registerer, name, help string, labels ...string) *Counter {
+	c := &Counter{vec: prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)}
+	reg.MustRegister(c.vec)
+	return c
+}
+
+// Inc увеличивает серию с метками labelValues на 1
+func (c *Counter) Inc(labelValues ...string) {
+	c.vec.WithLabelValues(labelValues...).Inc()
+}
+
+// Add увеличивает серию на v (v >= 0)
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.vec.WithLabelValues(labelValues...).Add(v)
+}
+
+// Gauge - значение, которое может расти и убывать
+type Gauge struct {
+	vec *prometheus.GaugeVec
+}
+
+// NewGauge создаёт и регистрирует измеритель
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return newGauge(registry, name, help, labels...)
+}
+
+func newGauge(reg prometheus.Registerer, name, help string, labels ...string) *Gauge {
+	g := &Gauge{vec: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)}
+	reg.MustRegister(g.vec)
+	return g
+}
+
+// Set устанавливает значение серии
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Set(v)
+}
+
+// Add изменяет значение серии на v (может быть отрицательным)
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Add(v)
+}
+
+// =================== Гистограмма ===================
+
+// Histogram - распределение значений по корзинам с метками
+type Histogram struct {
+	vec *prometheus.HistogramVec
+}
+
+// NewHistogram создаёт и регистрирует гистограмму с границами buckets (по возрастанию)
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return newHistogram(registry, name, help, buckets, labels...)
+}
+
+func newHistogram(reg prometheus.Registerer, name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{vec: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)}
+	reg.MustRegister(h.vec)
+	return h
+}
+
+// Observe добавляет значение v в серию с метками labelValues
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.vec.WithLabelValues(labelValues...).Observe(v)
+}
+
+// =================== Вывод ===================
+
+// Handler возвращает обработчик, отдающий метрики
+func Handler() http.Handler {
+	return handlerFor(registry)
+}
+
+func handlerFor(reg prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,62 @@
+// sermersys/metrics/metrics_test.go
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestHandlerExposition(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	requests := newCounter(reg, "sermersys_test_requests_total", "Тестовый счётчик.\nВторая строка с \\.", "route")
+	requests.Inc(`/a"b\c` + "\nd")
+	requests.Add(2, "/plain")
+	requests.Add(-1, "/plain") // счётчик не убывает
+	jobs := newGauge(reg, "sermersys_test_jobs", "Тестовый измеритель.", "state")
+	jobs.Add(3, "queued")
+	jobs.Add(-1, "queued")
+	duration := newHistogram(reg, "sermersys_test_duration_seconds", "Тестовая гистограмма.", []float64{0.1, 1}, "step")
+	duration.Observe(0.05, "places")
+	duration.Observe(0.5, "places")
+	duration.Observe(5, "places")
+
+	rec := httptest.NewRecorder()
+	handlerFor(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("код %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		`# HELP sermersys_test_requests_total Тестовый счётчик.\nВторая строка с \\.`,
+		`# TYPE sermersys_test_requests_total counter`,
+		`sermersys_test_requests_total{route="/a\"b\\c\nd"} 1`,
+		`sermersys_test_requests_total{route="/plain"} 2`,
+		`# TYPE sermersys_test_jobs gauge`,
+		`sermersys_test_jobs{state="queued"} 2`,
+		`# TYPE sermersys_test_duration_seconds histogram`,
+		`sermersys_test_duration_seconds_bucket{step="places",le="0.1"} 1`,
+		`sermersys_test_duration_seconds_bucket{step="places",le="1"} 2`,
+		`sermersys_test_duration_seconds_bucket{step="places",le="+Inf"} 3`,
+		`sermersys_test_duration_seconds_sum{step="places"} 5.55`,
+		`sermersys_test_duration_seconds_count{step="places"} 3`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("нет строки %q в выводе:\n%s", line, body)
+		}
+	}
+}
+
+func TestHandlerServesServiceMetrics(t *testing.T) {
+	Analyses.Inc("no_places")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "# TYPE sermersys_analyses_total counter\n") || !strings.Contains(body, `sermersys_analyses_total{status="no_places"} `) {
+		t.Errorf("метрики сервиса не выводятся:\n%s", body)
+	}
+}
@@ -0,0 +1,95 @@
+// sermersys/metrics/sermersys.go
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+)
+
+// =================== Метрики сервиса ===================
+
+var (
+	HTTPRequests = NewCounter("sermersys_http_requests_total",
+		"Запросы к серверу по шаблону маршрута, методу и коду ответа.", "route", "method", "code")
+	HTTPDuration = NewHistogram("sermersys_http_request_duration_seconds",
+		"Время ответа сервера по шаблону маршрута.", DefaultBuckets, "route")
+
+	// Итог анализа: succeeded, no_places, timeout, canceled, failed
+	Analyses = NewCounter("sermersys_analyses_total",
+		"Завершённые анализы по итогу.", "status")
+	StepDuration = NewHistogram("sermersys_step_duration_seconds",
+		"Длительность шагов анализа.", DefaultBuckets, "step")
+
+	// Статус - поле status ответа Places API (OK, ZERO_RESULTS, OVER_QUERY_LIMIT...),
+	// для Custom Search - HTTP-код; error, timeout и canceled - ответ не получен
+	UpstreamRequests = NewCounter("sermersys_upstream_requests_total",
+		"Запросы к Google API по API и статусу ответа.", "api", "status")
+	UpstreamDuration = NewHistogram("sermersys_upstream_request_duration_seconds",
+		"Время ответа Google API.", DefaultBuckets, "api")
+
+	// Результат: hit, miss, store
+	CacheRequests = NewCounter("sermersys_cache_requests_total",
+		"Обращения к сохранённым ответам Google API.", "cache", "result")
+
+	// Правило - как в разборе выдачи: accepted, title_mismatch, domain_mismatch, duplicate, not_found...
+	MatchDecisions = NewCounter("sermersys_match_decisions_total",
+		"Решения по результатам выдачи CSE по платформе и правилу.", "platform", "rule")
+
+	// Состояние: queued или running
+	Jobs = NewGauge("sermersys_jobs",
+		"Анализы API v1 в очереди и выполняемые.", "state")
+
+	AuthRejections = NewCounter("sermersys_auth_rejections_total",
+		"Запросы, отклонённые проверкой ключа API, по причине.", "reason")
+)
+
+// Шаги анализа для StepDuration
+const (
+	StepPlaces           = "places"             // уточнение объекта через Google Places целиком
+	StepPlacesTextSearch = "places_text_search" // одна страница Text Search
+	StepPlacesDetails    = "places_details"     // Place Details одного объекта
+	StepSearch           = "search"             // поиск по платформам целиком
+	StepRating           = "rating"             // рейтинг и отзывы (Find Place + Details)
+	StepCSEBatch         = "cse_batch"          // один поисковый запрос CSE со всеми страницами
+	StepAnalysis         = "analysis"           // весь анализ
+)
+
+// Since записывает в StepDuration время шага, начатого в start
+func Since(step string, start time.Time) {
+	StepDuration.Observe(time.Since(start).Seconds(), step)
+}
+
+// ObserveUpstream учитывает запрос к Google API, начатый в start
+func ObserveUpstream(api, status string, start time.Time) {
+	UpstreamRequests.Inc(api, status)
+	UpstreamDuration.Observe(time.Since(start).Seconds(), api)
+}
+
+// UpstreamStatus возвращает статус ответа Google API для UpstreamRequests:
+// поле status из JSON-тела, если оно есть, иначе HTTP-код
+func UpstreamStatus(code int, body []byte) string {
+	var parsed struct {
+		Status string `json:"status"`
+	}
+	if json.Unmarshal(body, &parsed) == nil && parsed.Status != "" {
+		return parsed.Status
+	}
+	return strconv.Itoa(code)
+}
+
+// ErrorStatus возвращает статус запроса, на который не получен ответ: canceled, timeout или error
+func ErrorStatus(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,257 @@
+// sermersys/model/model.go
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Типизированная модель результата анализа, общая для mapsearchg, googlesearch, pipeline,
+// отчётов и API. JSON-представление совпадает с прежним (строки map[string]string у ссылок
+// платформ), поэтому сохранённые анализы читаются, а ответы API v1 не меняются.
+
+// =================== Объект ===================
+
+// Place - объект Google Places (отель, кафе) с контактами и рейтингом
+type Place struct {
+	Timestamp        string  `json:"timestamp"`
+	Name             string  `json:"name"`
+	FormattedAddress string  `json:"formatted_address"`
+	Lat              float64 `json:"lat"`
+	Lng              float64 `json:"lng"`
+	PlaceID          string  `json:"place_id"`
+	Website          string  `json:"website"`
+	Phone            string  `json:"phone"`
+	Rating           float64 `json:"rating"`
+	UserRatingsTotal int     `json:"user_ratings_total"`
+}
+
+// Map возвращает объект в прежнем виде строки map[string]string (mapsearchg.FetchData)
+func (p Place) Map() map[string]string {
+	return map[string]string{
+		"timestamp":          p.Timestamp,
+		"name":               p.Name,
+		"formatted_address":  p.FormattedAddress,
+		"lat":                fmt.Sprintf("%f", p.Lat),
+		"lng":                fmt.Sprintf("%f", p.Lng),
+		"place_id":           p.PlaceID,
+		"website":            p.Website,
+		"phone":              p.Phone,
+		"rating":             fmt.Sprintf("%.2f", p.Rating),
+		"user_ratings_total": strconv.Itoa(p.UserRatingsTotal),
+	}
+}
+
+// PlaceFromMap разбирает объект из строки map[string]string; неверные числа считаются нулями
+func PlaceFromMap(m map[string]string) Place {
+	return Place{
+		Timestamp:        m["timestamp"],
+		Name:             m["name"],
+		FormattedAddress: m["formatted_address"],
+		Lat:              atof(m["lat"]),
+		Lng:              atof(m["lng"]),
+		PlaceID:          m["place_id"],
+		Website:          m["website"],
+		Phone:            m["phone"],
+		Rating:           atof(m["rating"]),
+		UserRatingsTotal: atoi(m["user_ratings_total"]),
+	}
+}
+
+// =================== Рейтинги и отзывы ===================
+
+// SourceGooglePlaces - источник рейтингов и отзывов из Google Places
+const SourceGooglePlaces = "google_places"
+
+// RatingSnapshot - рейтинг объекта в источнике на момент проверки
+type RatingSnapshot struct {
+	Source           string    `json:"source"`
+	Rating           float64   `json:"rating"`
+	UserRatingsTotal int       `json:"user_ratings_total"`
+	CheckedAt        time.Time `json:"checked_at,omitempty"`
+}
+
+// Review - отзыв об объекте. Оценка в JSON - строкой, как в ответах API v1.
+type Review struct {
+	Author string `json:"author"`
+	Rating int    `json:"rating,string"`
+	Text   string `json:"text"`
+}
+
+// =================== Ссылки на платформах ===================
+
+// Listing - страница объекта на платформе, найденная в выдаче Google CSE.
+// Рейтинг и отзыв берутся из Google Places и одинаковы у всех ссылок одного анализа.
+type Listing struct {
+	Platform   string
+	Title      string
+	Link       string
+	Page       int     // номер страницы выдачи, начиная с 1
+	Position   int     // абсолютная позиция в выдаче, начиная с 1
+	MatchScore float64 // оценка совпадения заголовка с названием объекта
+	Rating     *RatingSnapshot
+	Review     *Review
+}
+
+// Map возвращает ссылку в прежнем виде строки map[string]string (googlesearch.FetchData):
+// числа - строками, отсутствующие рейтинг и отзыв - пустыми строками
+func (l Listing) Map() map[string]string {
+	m := map[string]string{
+		"platform":      l.Platform,
+		"title":         l.Title,
+		"link":          l.Link,
+		"page":          strconv.Itoa(l.Page),
+		"position":      strconv.Itoa(l.Position),
+		"match_score":   fmt.Sprintf("%.2f", l.MatchScore),
+		"rating":        "",
+		"user_ratings":  "",
+		"review_author": "",
+		"review_rating": "",
+		"review_text":   "",
+	}
+	if l.Rating != nil {
+		m["rating"] = fmt.Sprintf("%.1f", l.Rating.Rating)
+		m["user_ratings"] = strconv.Itoa(l.Rating.UserRatingsTotal)
+	}
+	if l.Review != nil {
+		m["review_author"] = l.Review.Author
+		m["review_rating"] = strconv.Itoa(l.Review.Rating)
+		m["review_text"] = l.Review.Text
+	}
+	return m
+}
+
+// ListingFromMap разбирает ссылку из строки map[string]string. Пустой rating означает, что
+// рейтинг не получен; источник рейтинга в строке не хранится и считается Google Places.
+func ListingFromMap(m map[string]string) Listing {
+	l := Listing{
+		Platform:   m["platform"],
+		Title:      m["title"],
+		Link:       m["link"],
+		Page:       atoi(m["page"]),
+		Position:   atoi(m["position"]),
+		MatchScore: atof(m["match_score"]),
+	}
+	if strings.TrimSpace(m["rating"]) != "" {
+		l.Rating = &RatingSnapshot{Source: SourceGooglePlaces, Rating: atof(m["rating"]), UserRatingsTotal: atoi(m["user_ratings"])}
+	}
+	if m["review_author"] != "" || m["review_text"] != "" {
+		l.Review = &Review{Author: m["review_author"], Rating: atoi(m["review_rating"]), Text: m["review_text"]}
+	}
+	return l
+}
+
+// MarshalJSON записывает ссылку в прежнем виде (см. Map)
+func (l Listing) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Map())
+}
+
+// UnmarshalJSON читает ссылку в прежнем виде (см. ListingFromMap)
+func (l *Listing) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*l = ListingFromMap(m)
+	return nil
+}
+
+// =================== Видимость и разбор выдачи ===================
+
+// VisibilityWeek - видимость платформы в выдаче за ISO-неделю
+type VisibilityWeek struct {
+	Week          string  `json:"week"` // ISO-неделя, например 2025-W07
+	Checks        int     `json:"checks"`
+	Appearances   int     `json:"appearances"`
+	BestPosition  int     `json:"best_position,omitempty"`
+	AvgPosition   float64 `json:"avg_position,omitempty"`
+	Visibility    float64 `json:"visibility"`               // доля проверок, в которых платформа найдена
+	PositionDelta int     `json:"position_delta,omitempty"` // изменение лучшей позиции к прошлой неделе (минус - рост)
+}
+
+// PlatformVisibility - динамика видимости одной платформы
+type PlatformVisibility struct {
+	Platform string           `json:"platform"`
+	Weeks    []VisibilityWeek `json:"weeks"`
+}
+
+// Explanation - разбор одного результата выдачи (или страницы без результатов)
+type Explanation struct {
+	Query    string  `json:"query"`
+	Platform string  `json:"platform,omitempty"`
+	Page     int     `json:"page,omitempty"`
+	Index    int     `json:"index,omitempty"`
+	Position int     `json:"position,omitempty"`
+	Title    string  `json:"title,omitempty"`
+	Link     string  `json:"link,omitempty"`
+	Rule     string  `json:"rule"`
+	Score    float64 `json:"score"`
+	Detail   string  `json:"detail,omitempty"`
+}
+
+// =================== Результат анализа ===================
+
+// AnalysisResult - итог полного анализа: уточнение через mapsearchg и поиск по платформам через googlesearch
+type AnalysisResult struct {
+	RefinedHotelName string               `json:"refined_hotel_name"`
+	RefinedAddress   string               `json:"refined_address"`
+	Places           []Place              `json:"places,omitempty"`
+	SearchResults    []Listing            `json:"search_results"`
+	Platforms        []string             `json:"platforms,omitempty"`  // платформы, по которым шёл поиск
+	Visibility       []PlatformVisibility `json:"visibility,omitempty"` // понедельная видимость платформ по истории выдачи
+	ExecutionSteps   []string             `json:"execution_steps"`
+	Explanations     []Explanation        `json:"explanations,omitempty"`
+	Filename         string               `json:"filename,omitempty"`
+	ExplainFilename  string               `json:"explain_filename,omitempty"`
+	Duration         time.Duration        `json:"duration"`
+	AnalyzedAt       time.Time            `json:"analyzed_at"`
+}
+
+// Place возвращает объект анализа - первый кандидат Google Places, или nil, если поиск
+// по Google Places не выполнялся
+func (r *AnalysisResult) Place() *Place {
+	if len(r.Places) == 0 {
+		return nil
+	}
+	return &r.Places[0]
+}
+
+// Reviews возвращает уникальные отзывы со всех ссылок в порядке появления
+func (r *AnalysisResult) Reviews() []Review {
+	reviews := []Review{}
+	seen := make(map[string]bool)
+	for _, listing := range r.SearchResults {
+		if listing.Review == nil {
+			continue
+		}
+		key := listing.Review.Author + "\x00" + listing.Review.Text
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		reviews = append(reviews, *listing.Review)
+	}
+	return reviews
+}
+
+// ListingMaps возвращает ссылки в прежнем виде строк map[string]string
+func ListingMaps(listings []Listing) []map[string]string {
+	maps := make([]map[string]string, 0, len(listings))
+	for _, l := range listings {
+		maps = append(maps, l.Map())
+	}
+	return maps
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
+
+func atof(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return f
+}
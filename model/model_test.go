@@ -0,0 +1,125 @@
+// sermersys/model/model_test.go
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlaceMapRoundTrip(t *testing.T) {
+	place := Place{
+		Timestamp:        "2025-02-14T10:30:00Z",
+		Name:             "Hotel Adriatic",
+		FormattedAddress: "Slovenska obala 1, Budva",
+		Lat:              42.286400,
+		Lng:              18.840000,
+		PlaceID:          "ChIJ-adriatic",
+		Website:          "https://adriatic.example.com",
+		Phone:            "+382 33 000 000",
+		Rating:           4.6,
+		UserRatingsTotal: 812,
+	}
+	m := place.Map()
+	if m["lat"] != "42.286400" || m["rating"] != "4.60" || m["user_ratings_total"] != "812" {
+		t.Errorf("Map: %v", m)
+	}
+	if got := PlaceFromMap(m); got != place {
+		t.Errorf("PlaceFromMap(Map()) = %+v", got)
+	}
+	// Неверные числа считаются нулями
+	if got := PlaceFromMap(map[string]string{"name": "X", "lat": "north", "user_ratings_total": " 7 "}); got.Lat != 0 || got.UserRatingsTotal != 7 {
+		t.Errorf("PlaceFromMap с неверными числами: %+v", got)
+	}
+}
+
+func TestListingJSON(t *testing.T) {
+	listing := Listing{
+		Platform:   "booking.com",
+		Title:      "Hotel Adriatic",
+		Link:       "https://www.booking.com/hotel/me/adriatic.html",
+		Page:       1,
+		Position:   3,
+		MatchScore: 0.93,
+		Rating:     &RatingSnapshot{Source: SourceGooglePlaces, Rating: 4.6, UserRatingsTotal: 812},
+		Review:     &Review{Author: "Анна", Rating: 5, Text: "Отлично"},
+	}
+	data, err := json.Marshal(listing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Прежний вид: все значения - строки
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("ссылка не в виде map[string]string: %s", data)
+	}
+	if m["position"] != "3" || m["match_score"] != "0.93" || m["rating"] != "4.6" || m["review_rating"] != "5" {
+		t.Errorf("JSON ссылки: %v", m)
+	}
+
+	var back Listing
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, listing) {
+		t.Errorf("после JSON: %+v, ожидалось %+v", back, listing)
+	}
+
+	// Пустые рейтинг и отзыв - пустые строки, при чтении - nil
+	bare := Listing{Platform: "expedia.com", Link: "https://expedia.com/h1", Page: 1, Position: 1}
+	m = bare.Map()
+	if m["rating"] != "" || m["review_author"] != "" {
+		t.Errorf("Map без рейтинга: %v", m)
+	}
+	if got := ListingFromMap(m); got.Rating != nil || got.Review != nil {
+		t.Errorf("ListingFromMap без рейтинга: %+v", got)
+	}
+}
+
+func TestAnalysisResultJSON(t *testing.T) {
+	result := AnalysisResult{
+		RefinedHotelName: "Hotel Adriatic",
+		SearchResults:    []Listing{{Platform: "booking.com", Link: "https://booking.com/a", Page: 1, Position: 1}},
+		ExecutionSteps:   []string{"Поиск"},
+		AnalyzedAt:       time.Date(2025, 2, 14, 10, 30, 0, 0, time.UTC),
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"places", "platforms", "visibility", "explanations", "filename"} {
+		if _, ok := raw[key]; ok {
+			t.Errorf("пустое поле %s записано", key)
+		}
+	}
+	listings, _ := raw["search_results"].([]interface{})
+	if first, _ := listings[0].(map[string]interface{}); first["position"] != "1" {
+		t.Errorf("search_results: %v", raw["search_results"])
+	}
+	if result.Place() != nil {
+		t.Errorf("Place() без кандидатов Google Places")
+	}
+}
+
+func TestReviews(t *testing.T) {
+	shared := &Review{Author: "Анна", Rating: 5, Text: "Отлично"}
+	result := AnalysisResult{SearchResults: []Listing{
+		{Platform: "booking.com", Review: shared},
+		{Platform: "expedia.com"},
+		{Platform: "tripadvisor.com", Review: &Review{Author: "Анна", Rating: 5, Text: "Отлично"}},
+		{Platform: "agoda.com", Review: &Review{Author: "Marko", Rating: 4, Text: "Good"}},
+	}}
+	want := []Review{*shared, {Author: "Marko", Rating: 4, Text: "Good"}}
+	if got := result.Reviews(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reviews() = %+v, ожидалось %+v", got, want)
+	}
+	// Без отзывов - пустой срез, а не nil (в JSON - [])
+	if got := (&AnalysisResult{}).Reviews(); got == nil || len(got) != 0 {
+		t.Errorf("Reviews() без отзывов: %#v", got)
+	}
+}
@@ -0,0 +1,182 @@
+// sermersys/pipeline/pipeline.go
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"sermersys/config"
+	"sermersys/googlesearch"
+	"sermersys/i18n"
+	"sermersys/mapsearchg"
+	"sermersys/metrics"
+	"sermersys/model"
+	"sermersys/tracing"
+)
+
+// Result - итог полного анализа: уточнение через mapsearchg и поиск по платформам через googlesearch
+type Result = model.AnalysisResult
+
+// ErrNoPlaces - mapsearchg не нашёл ни одного объекта
+var ErrNoPlaces = fmt.Errorf("нет результатов в mapsearchg")
+
+// SearchRequest строит запрос для googlesearch из исходного запроса и уточнённого объекта
+func SearchRequest(data mapsearchg.RequestData, place model.Place) googlesearch.RequestData {
+	return googlesearch.RequestData{
+		HotelName:          place.Name,
+		Address:            place.FormattedAddress,
+		City:               data.City,
+		Country:            data.Country,
+		PlatformsFile:      data.PlatformsFile,
+		Language:           data.Language,
+		QueryTemplatesFile: data.QueryTemplatesFile,
+		MatchThreshold:     data.MatchThreshold,
+		Transliterate:      data.Transliterate,
+		TranslitSchemes:    data.TranslitSchemes,
+		Explain:            data.Explain,
+		Locale:             data.Locale,
+	}
+}
+
+// Analyze выполняет полный анализ объекта.
+// Если провайдер places выключен, поиск по платформам идёт по названию из запроса;
+// если выключен cse, возвращаются только данные Google Places.
+func Analyze(cfg *config.Config, data mapsearchg.RequestData) (*Result, error) {
+	return AnalyzeContext(context.Background(), cfg, data)
+}
+
+// AnalyzeContext - как Analyze, но прерывается при отмене ctx (например, когда клиент
+// закрыл соединение). Весь анализ ограничен таймаутом cfg.Timeouts.Analysis.
+// Шаги анализа описываются на языке data.Locale, а если он не задан - на языке из ctx.
+func AnalyzeContext(ctx context.Context, cfg *config.Config, data mapsearchg.RequestData) (*Result, error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "analysis", trace.WithAttributes(
+		attribute.String("object_name", data.ObjectName),
+		attribute.String("city", data.City),
+		attribute.String("country", data.Country),
+	))
+	result, err := analyze(ctx, cfg, data)
+	metrics.Since(metrics.StepAnalysis, start)
+	status := outcome(err)
+	metrics.Analyses.Inc(status)
+	span.SetAttributes(attribute.String("analysis.outcome", status))
+	if result != nil {
+		span.SetAttributes(
+			attribute.Int("places.count", len(result.Places)),
+			attribute.Int("listings.count", len(result.SearchResults)))
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+// outcome возвращает итог анализа для метрик
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return "succeeded"
+	case errors.Is(err, ErrNoPlaces):
+		return "no_places"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "failed"
+	}
+}
+
+func analyze(ctx context.Context, cfg *config.Config, data mapsearchg.RequestData) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeouts.Analysis.Std())
+	defer cancel()
+
+	startTime := time.Now()
+	if data.PlatformsFile == "" {
+		data.PlatformsFile = cfg.PlatformsFile
+	}
+
+	// 1️⃣ Запрашиваем данные у mapsearchg
+	place := model.Place{Name: data.ObjectName, FormattedAddress: data.Address}
+	var places []model.Place
+	if cfg.Providers.Places {
+		var err error
+		stepStart := time.Now()
+		stepCtx, span := tracing.Start(ctx, "places")
+		places, err = mapsearchg.SearchGooglePlacesContext(stepCtx, cfg, data)
+		metrics.Since(metrics.StepPlaces, stepStart)
+		span.SetAttributes(attribute.Int("result.count", len(places)))
+		if len(places) > 0 {
+			span.SetAttributes(attribute.String("place_id", places[0].PlaceID))
+		}
+		tracing.End(span, err)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка в mapsearchg.SearchGooglePlaces: %w", err)
+		}
+		if len(places) == 0 {
+			return nil, ErrNoPlaces
+		}
+		place = places[0]
+	}
+
+	// Обновляем запрос с уточнённым именем и адресом
+	updatedRequest := SearchRequest(data, place)
+	slog.InfoContext(ctx, "объект уточнён через mapsearchg", "name", updatedRequest.HotelName, "address", updatedRequest.Address)
+
+	// 2️⃣ Запускаем googlesearch с уточнёнными данными
+	report := &googlesearch.FetchReport{}
+	if cfg.Providers.CSE {
+		var err error
+		stepStart := time.Now()
+		stepCtx, span := tracing.Start(ctx, "search")
+		report, err = googlesearch.FetchDataExplainContext(stepCtx, cfg, updatedRequest)
+		metrics.Since(metrics.StepSearch, stepStart)
+		if report != nil {
+			span.SetAttributes(attribute.Int("result.count", len(report.Results)))
+		}
+		tracing.End(span, err)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка в googlesearch.FetchData: %w", err)
+		}
+	}
+
+	// 3️⃣ Динамика видимости платформ для отчётов; без истории анализ не прерывается
+	var visibility []model.PlatformVisibility
+	if cfg.Providers.CSE {
+		serp, err := googlesearch.BuildSERPReport(cfg, updatedRequest.HotelName, updatedRequest.City)
+		if err != nil {
+			slog.WarnContext(ctx, "не удалось прочитать историю выдачи", "error", err)
+		} else {
+			visibility = serp.Platforms
+		}
+	}
+
+	executionTime := time.Since(startTime)
+	lang := i18n.Resolve(data.Locale, i18n.FromContext(ctx))
+	slog.InfoContext(ctx, "анализ завершён", "duration_ms", executionTime.Milliseconds(), "listings", len(report.Results))
+
+	return &Result{
+		RefinedHotelName: updatedRequest.HotelName,
+		RefinedAddress:   updatedRequest.Address,
+		Places:           places,
+		SearchResults:    report.Results,
+		Platforms:        report.Platforms,
+		Visibility:       visibility,
+		ExecutionSteps: []string{
+			i18n.T(lang, "step.places_request"),
+			i18n.T(lang, "step.places_received"),
+			i18n.T(lang, "step.search_request"),
+			i18n.T(lang, "step.done"),
+			i18n.T(lang, "step.duration", executionTime),
+		},
+		Explanations:    report.Explanations,
+		Filename:        report.Filename,
+		ExplainFilename: report.ExplainFilename,
+		Duration:        executionTime,
+		AnalyzedAt:      startTime,
+	}, nil
+}
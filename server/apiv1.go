@@ -0,0 +1,422 @@
+// sermersys/server/apiv1.go
+package server
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sermersys/auth"
+	"sermersys/export"
+	"sermersys/i18n"
+	"sermersys/model"
+)
+
+// Версионированный REST API: /api/v1. Описание - в openapi.json, который отдаётся по /api/v1/openapi.json.
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// Коды ошибок состояния анализа
+const (
+	CodeNotReady = "analysis_not_ready" // у анализа нет результата: он в очереди, выполняется или завершился с ошибкой
+	CodeFinished = "analysis_finished"  // анализ уже завершён и не может быть отменён
+)
+
+// maxRequestBody - ограничение размера тела запроса API v1
+const maxRequestBody = 1 << 20
+
+// maxWait - наибольшее время ожидания результата в POST /api/v1/analyses?wait=
+const maxWait = 5 * time.Minute
+
+// waitWriteMargin - запас времени на отправку ответа после ожидания результата
+const waitWriteMargin = time.Minute
+
+// itemsResponse - ответ со списком ресурсов
+type itemsResponse struct {
+	Items interface{} `json:"items"`
+	Count int         `json:"count"`
+}
+
+// Review - отзыв об объекте, найденный при анализе
+type Review = model.Review
+
+// apiRoute - маршрут API v1: шаблон пути ServeMux и обработчики по методам.
+// Таблица маршрутов сверяется с openapi.json в тестах.
+type apiRoute struct {
+	pattern  string
+	handlers map[string]http.HandlerFunc
+}
+
+// apiv1Routes возвращает маршруты API v1
+func (s *Server) apiv1Routes() []apiRoute {
+	read := func(h func(w http.ResponseWriter, r *http.Request, sc *scope)) http.HandlerFunc {
+		return s.requireKey(auth.RoleRead, false, s.withScope(h))
+	}
+	run := func(analysis bool, h func(w http.ResponseWriter, r *http.Request, sc *scope)) http.HandlerFunc {
+		return s.requireKey(auth.RoleRun, analysis, s.withScope(h))
+	}
+
+	routes := []apiRoute{
+		{"/api/v1/openapi.json", map[string]http.HandlerFunc{
+			http.MethodGet: s.openAPIHandler,
+		}},
+		{"/api/v1/analyses", map[string]http.HandlerFunc{
+			http.MethodGet:  read(s.listAnalysesHandler),
+			http.MethodPost: run(true, s.createAnalysisHandler),
+		}},
+		{"/api/v1/analyses/{id}", map[string]http.HandlerFunc{
+			http.MethodGet:    read(s.getAnalysisHandler),
+			http.MethodDelete: run(false, s.cancelAnalysisHandler),
+		}},
+	}
+	for _, sub := range []struct {
+		resource string
+		handler  func(w http.ResponseWriter, r *http.Request, sc *scope)
+	}{
+		{"places", s.placesHandler},
+		{"listings", s.listingsHandler},
+		{"reviews", s.reviewsHandler},
+		{"exports", s.exportsHandler},
+	} {
+		routes = append(routes, apiRoute{"/api/v1/analyses/{id}/" + sub.resource, map[string]http.HandlerFunc{
+			http.MethodGet: read(sub.handler),
+		}})
+	}
+	return append(routes,
+		apiRoute{"/api/v1/batches", map[string]http.HandlerFunc{
+			http.MethodPost: run(true, s.createBatchHandler),
+		}},
+		apiRoute{"/api/v1/batches/{id}", map[string]http.HandlerFunc{
+			http.MethodGet: read(s.getBatchHandler),
+		}},
+		apiRoute{"/api/v1/exports/{id}", map[string]http.HandlerFunc{
+			http.MethodGet: read(s.exportHandler),
+		}},
+		apiRoute{"/api/v1/workspace", map[string]http.HandlerFunc{
+			http.MethodGet: read(s.workspaceHandler),
+		}},
+		apiRoute{"/api/v1/serp-report", map[string]http.HandlerFunc{
+			http.MethodGet: read(s.serpReportHandler),
+		}},
+	)
+}
+
+// registerAPIv1 регистрирует маршруты API v1
+func (s *Server) registerAPIv1(mux *http.ServeMux) {
+	for _, route := range s.apiv1Routes() {
+		mux.HandleFunc(route.pattern, s.methods(route.handlers))
+	}
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		s.fail(w, r, http.StatusNotFound, CodeNotFound, "error.resource_not_found", nil)
+	})
+}
+
+// methods выбирает обработчик по методу запроса и отвечает 405 в формате API v1
+func (s *Server) methods(handlers map[string]http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := handlers[r.Method]; ok {
+			h(w, r)
+			return
+		}
+		if h, ok := handlers[http.MethodGet]; ok && r.Method == http.MethodHead {
+			h(w, r)
+			return
+		}
+		var allowed []string
+		for method := range handlers {
+			allowed = append(allowed, method)
+		}
+		w.Header().Set("Allow", joinSorted(allowed))
+		s.fail(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "error.method_not_allowed", map[string]interface{}{"allowed": allowed})
+	}
+}
+
+// =================== Анализы ===================
+
+func (s *Server) createAnalysisHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	var request AnalysisRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, "error.invalid_json", map[string]interface{}{"reason": err.Error()})
+		return
+	}
+	r = withLocale(w, r, request.Locale)
+	if message, details := requestProblem(r.Context(), sc, &request); message != "" {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, message, details)
+		return
+	}
+
+	var wait time.Duration
+	if value := r.URL.Query().Get("wait"); value != "" {
+		var err error
+		if wait, err = parseWait(value); err != nil {
+			s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, "error.invalid_wait", map[string]interface{}{"parameter": "wait"})
+			return
+		}
+	}
+
+	refund, ok := s.chargeAnalyses(w, r, 1)
+	if !ok {
+		return
+	}
+	j, err := s.jobs.Submit(r.Context(), sc, request)
+	if err != nil {
+		refund()
+	}
+	if errors.Is(err, errQueueFull) {
+		w.Header().Set("Retry-After", "30")
+		s.fail(w, r, http.StatusServiceUnavailable, CodeQueueFull, errorKey(err), nil)
+		return
+	}
+	if errors.Is(err, errShuttingDown) {
+		w.Header().Set("Retry-After", "30")
+		s.fail(w, r, http.StatusServiceUnavailable, CodeShuttingDown, errorKey(err), nil)
+		return
+	}
+	if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, CodeInternal, err.Error(), nil)
+		return
+	}
+	slog.InfoContext(r.Context(), "анализ поставлен в очередь", "job_id", j.analysis.ID, "object_name", request.ObjectName, "city", request.City)
+
+	if wait > 0 {
+		// Ожидание может быть дольше таймаута записи сервера (cfg.Timeouts.WriteTimeout):
+		// без продления соединение оборвалось бы раньше, чем клиент получит ответ
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + waitWriteMargin)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.WarnContext(r.Context(), "не удалось продлить таймаут записи ответа", "error", err)
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-j.done:
+		case <-timer.C:
+		case <-r.Context().Done():
+		}
+	}
+
+	a := j.snapshot()
+	w.Header().Set("Location", "/api/v1/analyses/"+a.ID)
+	status := http.StatusAccepted
+	if a.Finished() {
+		status = http.StatusOK
+	}
+	writeJSONStatus(w, status, a)
+}
+
+// requestProblem проверяет запрос анализа и приводит его к виду для очереди. Если запрос
+// неверен, возвращает сообщение (ключ каталога или готовый текст на языке запроса) и подробности.
+func requestProblem(ctx context.Context, sc *scope, request *AnalysisRequest) (string, map[string]interface{}) {
+	lang := i18n.FromContext(ctx)
+	if _, ok := i18n.Parse(request.Locale); request.Locale != "" && !ok {
+		return i18n.T(lang, "error.invalid_locale", request.Locale),
+			map[string]interface{}{"locale": request.Locale, "supported": i18n.Languages()}
+	}
+	var missing []string
+	if request.ObjectName == "" {
+		missing = append(missing, "object_name")
+	}
+	if request.City == "" {
+		missing = append(missing, "city")
+	}
+	if len(missing) > 0 {
+		return "error.required_fields", map[string]interface{}{"fields": missing}
+	}
+	if !sc.cfg.AllowsPlatformsFile(request.PlatformsFile) {
+		return "error.platforms_not_allowed", map[string]interface{}{"platforms_file": request.PlatformsFile}
+	}
+	if !sc.cfg.AllowsQueryTemplatesFile(request.QueryTemplatesFile) {
+		return "error.templates_not_allowed", map[string]interface{}{"query_templates_file": request.QueryTemplatesFile}
+	}
+	if message, details := recipientsProblem(ctx, sc, request); message != "" {
+		return message, details
+	}
+	return "", nil
+}
+
+// parseWait разбирает ?wait=true (ждать до maxWait) или ?wait=30s
+func parseWait(value string) (time.Duration, error) {
+	if b, err := strconv.ParseBool(value); err == nil {
+		if b {
+			return maxWait, nil
+		}
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("wait: ожидается true, false или длительность вида 30s")
+	}
+	return min(d, maxWait), nil
+}
+
+func (s *Server) listAnalysesHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	list, err := listAnalyses(sc.cfg.ResultsDir)
+	if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, CodeInternal, "error.analysis_list", nil)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, "error.invalid_limit", map[string]interface{}{"parameter": "limit"})
+			return
+		}
+		limit = n
+	}
+
+	items := []*Analysis{}
+	for _, a := range list {
+		if status != "" && a.Status != status {
+			continue
+		}
+		// В списке - только сводка, результат доступен по ссылке на анализ
+		a.Result = nil
+		items = append(items, a)
+		if len(items) == limit {
+			break
+		}
+	}
+	writeJSONStatus(w, http.StatusOK, itemsResponse{Items: items, Count: len(items)})
+}
+
+func (s *Server) getAnalysisHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	if a := s.findAnalysis(w, r, sc); a != nil {
+		writeJSONStatus(w, http.StatusOK, a)
+	}
+}
+
+func (s *Server) cancelAnalysisHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	id := r.PathValue("id")
+	if j := s.jobs.Get(sc.workspace.ID, id); j != nil {
+		s.jobs.Cancel(j)
+		writeJSONStatus(w, http.StatusOK, j.snapshot())
+		return
+	}
+	if a := s.findAnalysis(w, r, sc); a != nil {
+		s.fail(w, r, http.StatusConflict, CodeFinished, "error.analysis_finished", map[string]interface{}{"status": a.Status})
+	}
+}
+
+// findAnalysis возвращает анализ пространства или отвечает ошибкой и возвращает nil
+func (s *Server) findAnalysis(w http.ResponseWriter, r *http.Request, sc *scope) *Analysis {
+	id := r.PathValue("id")
+	if j := s.jobs.Get(sc.workspace.ID, id); j != nil {
+		return j.snapshot()
+	}
+	a, err := loadAnalysis(sc.cfg.ResultsDir, id)
+	if errors.Is(err, errAnalysisNotFound) {
+		s.fail(w, r, http.StatusNotFound, CodeNotFound, "error.analysis_not_found", map[string]interface{}{"id": id})
+		return nil
+	}
+	if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, CodeInternal, "error.analysis_read", nil)
+		return nil
+	}
+	return a
+}
+
+// finishedAnalysis - как findAnalysis, но требует успешно завершённый анализ
+func (s *Server) finishedAnalysis(w http.ResponseWriter, r *http.Request, sc *scope) *Analysis {
+	a := s.findAnalysis(w, r, sc)
+	if a == nil {
+		return nil
+	}
+	if a.Status != StatusSucceeded || a.Result == nil {
+		s.fail(w, r, http.StatusConflict, CodeNotReady, "error.analysis_not_ready", map[string]interface{}{"status": a.Status})
+		return nil
+	}
+	return a
+}
+
+// =================== Ресурсы анализа ===================
+
+func (s *Server) placesHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	if a := s.finishedAnalysis(w, r, sc); a != nil {
+		places := a.Result.Places
+		if places == nil {
+			places = []model.Place{}
+		}
+		writeJSONStatus(w, http.StatusOK, itemsResponse{Items: places, Count: len(places)})
+	}
+}
+
+func (s *Server) listingsHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	if a := s.finishedAnalysis(w, r, sc); a != nil {
+		listings := a.Result.SearchResults
+		if listings == nil {
+			listings = []model.Listing{}
+		}
+		writeJSONStatus(w, http.StatusOK, itemsResponse{Items: listings, Count: len(listings)})
+	}
+}
+
+func (s *Server) reviewsHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	if a := s.finishedAnalysis(w, r, sc); a != nil {
+		reviews := a.Result.Reviews()
+		writeJSONStatus(w, http.StatusOK, itemsResponse{Items: reviews, Count: len(reviews)})
+	}
+}
+
+func (s *Server) exportsHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	if a := s.finishedAnalysis(w, r, sc); a != nil {
+		exports := a.Exports
+		if exports == nil {
+			exports = []Export{}
+		}
+		writeJSONStatus(w, http.StatusOK, itemsResponse{Items: exports, Count: len(exports)})
+	}
+}
+
+// exportHandler отдаёт файл результата по ID пространства ключа.
+// Ключ API уже проверен, поэтому подпись ссылки не требуется.
+func (s *Server) exportHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	id := r.PathValue("id")
+	filePath, err := sc.downloads.ResolveID(id)
+	if err != nil {
+		s.fail(w, r, http.StatusNotFound, CodeNotFound, "error.file_not_found", map[string]interface{}{"id": id})
+		return
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(filePath)}))
+	w.Header().Set("Content-Type", export.ContentType(filePath))
+	http.ServeFile(w, r, filePath)
+}
+
+// =================== Документация ===================
+
+func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeContent(w, r, "openapi.json", time.Time{}, bytes.NewReader(openAPISpec))
+}
+
+// =================== Вспомогательные функции ===================
+
+// writeJSONStatus отправляет JSON-ответ с заданным статусом
+func writeJSONStatus(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("ошибка при отправке ответа", "error", err)
+	}
+}
+
+// joinSorted возвращает значения через запятую в алфавитном порядке
+func joinSorted(values []string) string {
+	sort.Strings(values)
+	return strings.Join(values, ", ")
+}
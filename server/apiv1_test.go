@@ -0,0 +1,59 @@
+// sermersys/server/apiv1_test.go
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWait(t *testing.T) {
+	cases := []struct {
+		value string
+		want  time.Duration
+		err   bool
+	}{
+		{"true", maxWait, false},
+		{"false", 0, false},
+		{"30s", 30 * time.Second, false},
+		{"1h", maxWait, false},
+		{"-1s", 0, true},
+		{"soon", 0, true},
+	}
+	for _, c := range cases {
+		got, err := parseWait(c.value)
+		if (err != nil) != c.err || got != c.want {
+			t.Errorf("parseWait(%q) = %v, %v; ожидалось %v, ошибка %v", c.value, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestWaitOutlivesWriteTimeout(t *testing.T) {
+	hold := make(chan struct{})
+	defer close(hold)
+	s := newTestServer(t, newFakeGoogle(t, hold), nil)
+
+	// Таймаут записи короче ожидания: без продления дедлайна клиент получил бы обрыв соединения
+	ts := httptest.NewUnstartedServer(s.Handler())
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	start := time.Now()
+	resp, err := ts.Client().Post(ts.URL+"/api/v1/analyses?wait=500ms", "application/json",
+		strings.NewReader(`{"object_name":"Slow Hotel","city":"Budva"}`))
+	if err != nil {
+		t.Fatalf("ответ не получен: %v", err)
+	}
+	defer resp.Body.Close()
+	var a Analysis
+	if resp.StatusCode != http.StatusAccepted || json.NewDecoder(resp.Body).Decode(&a) != nil || a.ID == "" {
+		t.Fatalf("ожидался 202 с анализом, получен %d", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("ответ пришёл через %v, раньше окончания ожидания", elapsed)
+	}
+}
@@ -0,0 +1,118 @@
+// sermersys/server/auth.go
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sermersys/auth"
+	"sermersys/metrics"
+)
+
+// usageFlushInterval - как часто статистика использования ключей записывается в файл ключей
+const usageFlushInterval = 30 * time.Second
+
+// requireKey оборачивает обработчик проверкой ключа API. Ключ передаётся в заголовке
+// X-API-Key или Authorization: Bearer. Если авторизация выключена, обработчик вызывается как есть.
+// analysis = true означает, что запрос запускает анализ: при исчерпанной дневной квоте ключа он
+// отклоняется сразу, а сама квота списывается обработчиком после проверки запроса (см. chargeAnalyses).
+func (s *Server) requireKey(role string, analysis bool, next http.HandlerFunc) http.HandlerFunc {
+	if s.keys == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := requestToken(r)
+		if token == "" {
+			metrics.AuthRejections.Inc("missing_key")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="sermersys"`)
+			s.fail(w, r, http.StatusUnauthorized, CodeUnauthorized, "error.api_key_required", nil)
+			return
+		}
+
+		key, wait, err := s.keys.Authorize(token, role, analysis)
+		if err != nil {
+			metrics.AuthRejections.Inc(rejectionReason(err))
+		}
+		switch {
+		case errors.Is(err, auth.ErrUnknownKey), errors.Is(err, auth.ErrRevoked):
+			w.Header().Set("WWW-Authenticate", `Bearer realm="sermersys", error="invalid_token"`)
+			s.fail(w, r, http.StatusUnauthorized, CodeUnauthorized, errorKey(err), nil)
+			return
+		case errors.Is(err, auth.ErrForbidden):
+			s.fail(w, r, http.StatusForbidden, CodeForbidden, errorKey(err), map[string]interface{}{"required_role": role})
+			return
+		case errors.Is(err, auth.ErrRateLimited):
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			s.fail(w, r, http.StatusTooManyRequests, CodeRateLimited, errorKey(err), map[string]interface{}{"retry_after": retryAfter})
+			return
+		case errors.Is(err, auth.ErrQuotaExceeded):
+			s.fail(w, r, http.StatusTooManyRequests, CodeQuotaExceeded, errorKey(err), nil)
+			return
+		case err != nil:
+			s.fail(w, r, http.StatusInternalServerError, CodeInternal, "error.auth_failed", nil)
+			return
+		}
+
+		next(w, r.WithContext(auth.WithKey(r.Context(), key)))
+	}
+}
+
+// chargeAnalyses списывает n анализов с дневной квоты ключа запроса. Вызывается, когда запрос
+// уже проверен; если анализы не удалось поставить в очередь, квота возвращается вызовом refund.
+// При исчерпанной квоте отвечает ошибкой и возвращает ok = false. Без авторизации ничего не списывает.
+func (s *Server) chargeAnalyses(w http.ResponseWriter, r *http.Request, n int) (refund func(), ok bool) {
+	key := auth.FromContext(r.Context())
+	if key == nil || s.keys == nil {
+		return func() {}, true
+	}
+	err := s.keys.ChargeAnalyses(key.ID, n)
+	if errors.Is(err, auth.ErrQuotaExceeded) {
+		metrics.AuthRejections.Inc(rejectionReason(err))
+		s.fail(w, r, http.StatusTooManyRequests, CodeQuotaExceeded, errorKey(err), map[string]interface{}{"items": n})
+		return nil, false
+	}
+	if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, CodeInternal, "error.auth_failed", nil)
+		return nil, false
+	}
+	return func() {
+		if err := s.keys.ChargeAnalyses(key.ID, -n); err != nil {
+			slog.WarnContext(r.Context(), "не удалось вернуть анализы в квоту ключа", "key", key.ID, "error", err)
+		}
+	}, true
+}
+
+// rejectionReason возвращает причину отказа для метрик
+func rejectionReason(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrUnknownKey):
+		return "unknown_key"
+	case errors.Is(err, auth.ErrRevoked):
+		return "revoked"
+	case errors.Is(err, auth.ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, auth.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, auth.ErrQuotaExceeded):
+		return "quota_exceeded"
+	default:
+		return "error"
+	}
+}
+
+// requestToken извлекает ключ API из заголовков запроса
+func requestToken(r *http.Request) string {
+	if token := r.Header.Get("X-API-Key"); token != "" {
+		return strings.TrimSpace(token)
+	}
+	if header := r.Header.Get("Authorization"); len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+// sermersys/server/auth_test.go
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"sermersys/auth"
+	"sermersys/config"
+)
+
+// usedAnalyses возвращает число анализов, списанных с квоты ключа
+func usedAnalyses(t *testing.T, s *Server, id string) int64 {
+	t.Helper()
+	for _, k := range s.keys.List() {
+		if k.ID == id {
+			return k.Usage.Analyses
+		}
+	}
+	t.Fatalf("ключ %s не найден", id)
+	return 0
+}
+
+func TestQuotaChargedOnlyForQueuedAnalyses(t *testing.T) {
+	google := newFakeGoogle(t, nil)
+	s := newTestServer(t, google, func(cfg *config.Config) {
+		cfg.Auth.Enabled = true
+		cfg.Downloads.SigningKey = "test-signing-key"
+	})
+	h := s.Handler()
+	token, key, err := s.keys.Create(auth.CreateOptions{Name: "ci", Role: auth.RoleRun, DailyQuota: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rejected := []struct {
+		target, body string
+	}{
+		{"/api/v1/analyses", `{"object_name":`},
+		{"/api/v1/analyses", `{"object_name":"Hotel Adriatic"}`},
+		{"/api/v1/analyses?wait=forever", `{"object_name":"Hotel Adriatic","city":"Budva"}`},
+		{"/api/v1/batches", `{"requests":[]}`},
+		{"/api/v1/batches", `{"requests":[{"object_name":"Hotel Adriatic","city":"Budva"},{"object_name":"Hotel Mogren"}]}`},
+		{"/process", `not json`},
+	}
+	for _, c := range rejected {
+		if resp := do(t, h, http.MethodPost, c.target, c.body, token); resp.Code != http.StatusBadRequest {
+			t.Fatalf("%s %s: код %d, ожидался 400", c.target, c.body, resp.Code)
+		}
+	}
+	if n := usedAnalyses(t, s, key.ID); n != 0 {
+		t.Fatalf("неверные запросы списали с квоты %d анализов", n)
+	}
+
+	if resp := do(t, h, http.MethodPost, "/api/v1/analyses", `{"object_name":"Hotel Adriatic","city":"Budva"}`, token); resp.Code != http.StatusAccepted {
+		t.Fatalf("анализ: код %d: %s", resp.Code, resp.Body)
+	}
+	// Пакет из трёх объектов не помещается в оставшуюся квоту и не списывает ничего
+	resp := do(t, h, http.MethodPost, "/api/v1/batches", `{"requests":[{"object_name":"A","city":"Budva"},{"object_name":"B","city":"Budva"},{"object_name":"C","city":"Budva"}]}`, token)
+	if resp.Code != http.StatusTooManyRequests || !strings.Contains(resp.Body.String(), CodeQuotaExceeded) {
+		t.Fatalf("пакет сверх квоты: код %d: %s", resp.Code, resp.Body)
+	}
+	if resp := do(t, h, http.MethodPost, "/api/v1/batches", `{"requests":[{"object_name":"A","city":"Budva"},{"object_name":"B","city":"Budva"}]}`, token); resp.Code != http.StatusAccepted {
+		t.Fatalf("пакет: код %d: %s", resp.Code, resp.Body)
+	}
+	if n := usedAnalyses(t, s, key.ID); n != 3 {
+		t.Fatalf("списано %d анализов, ожидалось 3", n)
+	}
+	resp = do(t, h, http.MethodPost, "/api/v1/analyses", `{"object_name":"Hotel Adriatic","city":"Budva"}`, token)
+	if resp.Code != http.StatusTooManyRequests || !strings.Contains(resp.Body.String(), CodeQuotaExceeded) {
+		t.Fatalf("анализ сверх квоты: код %d: %s", resp.Code, resp.Body)
+	}
+}
+
+func TestQuotaRefundedWhenQueueFull(t *testing.T) {
+	hold := make(chan struct{})
+	defer close(hold)
+	google := newFakeGoogle(t, hold)
+	s := newTestServer(t, google, func(cfg *config.Config) {
+		cfg.Auth.Enabled = true
+		cfg.Downloads.SigningKey = "test-signing-key"
+		cfg.Jobs.QueueSize = 1
+	})
+	h := s.Handler()
+	token, key, err := s.keys.Create(auth.CreateOptions{Name: "ci", Role: auth.RoleRun, DailyQuota: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Исполнитель занят медленным анализом, второй анализ занимает единственное место в очереди
+	do(t, h, http.MethodPost, "/api/v1/analyses", `{"object_name":"Slow Hotel","city":"Budva"}`, token)
+	for len(s.jobs.queue) != 0 {
+		// ждём, пока исполнитель заберёт медленный анализ из очереди
+		time.Sleep(time.Millisecond)
+	}
+	do(t, h, http.MethodPost, "/api/v1/analyses", `{"object_name":"Hotel Adriatic","city":"Budva"}`, token)
+
+	if resp := do(t, h, http.MethodPost, "/api/v1/analyses", `{"object_name":"Hotel Mogren","city":"Budva"}`, token); resp.Code != http.StatusServiceUnavailable {
+		t.Fatalf("анализ при заполненной очереди: код %d: %s", resp.Code, resp.Body)
+	}
+	if resp := do(t, h, http.MethodPost, "/api/v1/batches", `{"requests":[{"object_name":"A","city":"Budva"},{"object_name":"B","city":"Budva"}]}`, token); resp.Code != http.StatusServiceUnavailable {
+		t.Fatalf("пакет при заполненной очереди: код %d: %s", resp.Code, resp.Body)
+	}
+	if n := usedAnalyses(t, s, key.ID); n != 2 {
+		t.Errorf("списано %d анализов, ожидалось 2 (отклонённые очередью возвращены в квоту)", n)
+	}
+}
+
+func TestEmailToRequiresKeyAndAllowedDomain(t *testing.T) {
+	google := newFakeGoogle(t, nil)
+	mailConfig := func(cfg *config.Config) {
+		cfg.Mail.Host = "127.0.0.1"
+		cfg.Mail.Port = 1
+		cfg.Mail.From = "reports@example.com"
+		cfg.Mail.AllowedDomains = []string{"example.com"}
+	}
+
+	// Без авторизации сервер не отправляет письма ни на какие адреса
+	open := newTestServer(t, google, mailConfig)
+	resp := do(t, open.Handler(), http.MethodPost, "/api/v1/analyses", `{"object_name":"Hotel Adriatic","city":"Budva","email_to":["owner@example.com"]}`, "")
+	if resp.Code != http.StatusBadRequest || !strings.Contains(resp.Body.String(), "ключом API") {
+		t.Fatalf("email_to без ключа: код %d: %s", resp.Code, resp.Body)
+	}
+	resp = do(t, open.Handler(), http.MethodPost, "/process", `{"object_name":"Hotel Adriatic","city":"Budva","email_to":["owner@example.com"]}`, "")
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("/process с email_to без ключа: код %d: %s", resp.Code, resp.Body)
+	}
+
+	s := newTestServer(t, google, func(cfg *config.Config) {
+		mailConfig(cfg)
+		cfg.Auth.Enabled = true
+		cfg.Downloads.SigningKey = "test-signing-key"
+	})
+	h := s.Handler()
+	token, _, err := s.keys.Create(auth.CreateOptions{Name: "ci", Role: auth.RoleRun})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = do(t, h, http.MethodPost, "/api/v1/analyses", `{"object_name":"Hotel Adriatic","city":"Budva","email_to":["owner@example.com","victim@gmail.com"]}`, token)
+	if resp.Code != http.StatusBadRequest || !strings.Contains(resp.Body.String(), "victim@gmail.com") {
+		t.Fatalf("email_to вне allowed_domains: код %d: %s", resp.Code, resp.Body)
+	}
+	resp = do(t, h, http.MethodPost, "/api/v1/batches", `{"requests":[{"object_name":"Hotel Adriatic","city":"Budva","email_to":["victim@gmail.com"]}]}`, token)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("пакет с email_to вне allowed_domains: код %d: %s", resp.Code, resp.Body)
+	}
+	resp = do(t, h, http.MethodPost, "/api/v1/analyses", `{"object_name":"Hotel Adriatic","city":"Budva","email_to":["Owner <owner@sales.example.com>"]}`, token)
+	if resp.Code != http.StatusAccepted || !strings.Contains(resp.Body.String(), `"owner@sales.example.com"`) {
+		t.Fatalf("email_to в разрешённом домене: код %d: %s", resp.Code, resp.Body)
+	}
+}
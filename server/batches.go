@@ -0,0 +1,316 @@
+// sermersys/server/batches.go
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"sermersys/export"
+)
+
+// Пакеты анализов API v1: все объекты пакета ставятся в общую очередь анализов, а результаты
+// по мере готовности дописываются в поток JSON Lines (см. export.JSONLWriter), который можно
+// скачивать, не дожидаясь конца пакета.
+
+// batchesDir - подкаталог каталога результатов с пакетами и их потоками JSON Lines
+const batchesDir = "batches"
+
+// StatusPartial - статус пакета, часть анализов которого завершилась ошибкой
+const StatusPartial = "partial"
+
+var errBatchNotFound = errors.New("пакет не найден")
+
+// Batch - пакет анализов, запущенный через API v1
+type Batch struct {
+	ID         string     `json:"id"`
+	Workspace  string     `json:"workspace"`
+	Status     string     `json:"status"` // running, пока есть незавершённые анализы, затем succeeded, partial или failed
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Total      int        `json:"total"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"` // завершились ошибкой или отменены
+	Analyses   []string   `json:"analyses"`
+	Export     *Export    `json:"export,omitempty"` // поток JSON Lines (kind batch_jsonl)
+}
+
+// batchRequest - тело POST /api/v1/batches
+type batchRequest struct {
+	Requests []AnalysisRequest `json:"requests"`
+}
+
+// batch - выполняемый пакет: состояние и открытый поток результатов
+type batch struct {
+	id string
+	sc *scope
+
+	mu     sync.Mutex
+	info   Batch
+	file   *os.File
+	stream *export.JSONLWriter
+}
+
+// newBatch создаёт пакет из total анализов и файл его потока
+func newBatch(sc *scope, total int) (*batch, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(sc.cfg.ResultsDir, batchesDir), os.ModePerm); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(batchStreamFile(sc.cfg.ResultsDir, id))
+	if err != nil {
+		return nil, err
+	}
+	return &batch{
+		id:     id,
+		sc:     sc,
+		file:   file,
+		stream: export.NewJSONLWriter(file, id),
+		info: Batch{
+			ID:        id,
+			Workspace: sc.workspace.ID,
+			Status:    StatusRunning,
+			CreatedAt: time.Now().UTC(),
+			Total:     total,
+		},
+	}, nil
+}
+
+// start записывает ID поставленных анализов и ссылку на поток и сохраняет пакет
+func (b *batch) start(ctx context.Context, jobs []*job) {
+	id, name, link := registerDownload(ctx, b.sc, b.file.Name())
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, j := range jobs {
+		b.info.Analyses = append(b.info.Analyses, j.analysis.ID)
+	}
+	if id != "" {
+		b.info.Export = &Export{ID: id, Kind: "batch_jsonl", Filename: name, DownloadURL: link}
+	}
+	b.saveLocked(ctx)
+}
+
+// discard удаляет пакет, анализы которого не удалось поставить в очередь
+func (b *batch) discard() {
+	b.file.Close()
+	os.Remove(b.file.Name())
+}
+
+// record дописывает в поток итог анализа item; после последнего анализа поток закрывается
+func (b *batch) record(ctx context.Context, item int, a *Analysis) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var err error
+	if a.Status == StatusSucceeded && a.Result != nil {
+		b.info.Succeeded++
+		err = b.stream.WriteResult(item, a.Request.RequestData, a.Result)
+	} else {
+		b.info.Failed++
+		message := a.Status
+		if a.Error != nil {
+			message = a.Error.Message
+		}
+		err = b.stream.WriteError(item, a.Request.RequestData, message)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "не удалось записать результат в поток пакета", "batch", b.id, "error", err)
+	}
+	if b.info.Succeeded+b.info.Failed == b.info.Total {
+		now := time.Now().UTC()
+		b.info.Status = batchStatus(b.info.Succeeded, b.info.Failed)
+		b.info.FinishedAt = &now
+		if err := b.file.Close(); err != nil {
+			slog.ErrorContext(ctx, "не удалось закрыть поток пакета", "batch", b.id, "error", err)
+		}
+		slog.InfoContext(ctx, "пакет завершён", "batch", b.id, "succeeded", b.info.Succeeded, "failed", b.info.Failed)
+	}
+	b.saveLocked(ctx)
+}
+
+// batchStatus - итоговый статус пакета по числу успешных и неудачных анализов
+func batchStatus(succeeded, failed int) string {
+	switch {
+	case failed == 0:
+		return StatusSucceeded
+	case succeeded == 0:
+		return StatusFailed
+	default:
+		return StatusPartial
+	}
+}
+
+// saveLocked сохраняет состояние пакета; вызывается под b.mu
+func (b *batch) saveLocked(ctx context.Context) {
+	if err := saveBatch(b.sc.cfg.ResultsDir, &b.info); err != nil {
+		slog.ErrorContext(ctx, "не удалось сохранить пакет", "batch", b.id, "error", err)
+	}
+}
+
+// =================== Обработчики ===================
+
+func (s *Server) createBatchHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	var body batchRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, "error.invalid_json", map[string]interface{}{"reason": err.Error()})
+		return
+	}
+	if len(body.Requests) == 0 {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, "error.batch_empty", nil)
+		return
+	}
+	for i := range body.Requests {
+		if message, details := requestProblem(r.Context(), sc, &body.Requests[i]); message != "" {
+			if details == nil {
+				details = make(map[string]interface{})
+			}
+			details["item"] = i + 1
+			s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, message, details)
+			return
+		}
+	}
+
+	refund, ok := s.chargeAnalyses(w, r, len(body.Requests))
+	if !ok {
+		return
+	}
+	b, err := newBatch(sc, len(body.Requests))
+	if err != nil {
+		refund()
+		slog.ErrorContext(r.Context(), "не удалось создать пакет", "error", err)
+		s.fail(w, r, http.StatusInternalServerError, CodeInternal, "error.internal", nil)
+		return
+	}
+	jobs, err := s.jobs.SubmitAll(r.Context(), sc, body.Requests, b)
+	if err != nil {
+		b.discard()
+		refund()
+	}
+	switch {
+	case errors.Is(err, errQueueFull):
+		w.Header().Set("Retry-After", "30")
+		s.fail(w, r, http.StatusServiceUnavailable, CodeQueueFull, "error.batch_queue_full",
+			map[string]interface{}{"items": len(body.Requests), "queue_size": sc.cfg.Jobs.QueueSize})
+		return
+	case errors.Is(err, errShuttingDown):
+		w.Header().Set("Retry-After", "30")
+		s.fail(w, r, http.StatusServiceUnavailable, CodeShuttingDown, errorKey(err), nil)
+		return
+	case err != nil:
+		s.fail(w, r, http.StatusInternalServerError, CodeInternal, err.Error(), nil)
+		return
+	}
+	b.start(r.Context(), jobs)
+	slog.InfoContext(r.Context(), "пакет поставлен в очередь", "batch", b.id, "items", len(jobs))
+
+	b.mu.Lock()
+	info := b.info
+	b.mu.Unlock()
+	w.Header().Set("Location", "/api/v1/batches/"+info.ID)
+	writeJSONStatus(w, http.StatusAccepted, &info)
+}
+
+func (s *Server) getBatchHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	id := r.PathValue("id")
+	b, err := loadBatch(sc.cfg.ResultsDir, id)
+	if errors.Is(err, errBatchNotFound) {
+		s.fail(w, r, http.StatusNotFound, CodeNotFound, "error.batch_not_found", map[string]interface{}{"id": id})
+		return
+	}
+	if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, CodeInternal, "error.batch_read", nil)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, b)
+}
+
+// =================== Хранение ===================
+
+func batchFile(resultsDir, id string) string {
+	return filepath.Join(resultsDir, batchesDir, id+".json")
+}
+
+func batchStreamFile(resultsDir, id string) string {
+	return filepath.Join(resultsDir, batchesDir, id+".jsonl")
+}
+
+// saveBatch сохраняет пакет в каталог результатов пространства
+func saveBatch(resultsDir string, b *Batch) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := batchFile(resultsDir, b.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, batchFile(resultsDir, b.ID))
+}
+
+// loadBatch читает сохранённый пакет; ID проверяется, как и у анализов
+func loadBatch(resultsDir, id string) (*Batch, error) {
+	if !validAnalysisID(id) {
+		return nil, errBatchNotFound
+	}
+	data, err := os.ReadFile(batchFile(resultsDir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var b Batch
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга пакета %s: %v", id, err)
+	}
+	return &b, nil
+}
+
+// failInterruptedBatches помечает неудачными пакеты, оставшиеся в статусе running после
+// остановки сервера: их незавершённые анализы уже не будут записаны в поток.
+// Вызывается при первом обращении к пространству, пока в нём нет выполняемых пакетов.
+func failInterruptedBatches(resultsDir string) {
+	entries, err := os.ReadDir(filepath.Join(resultsDir, batchesDir))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("не удалось проверить прерванные пакеты", "dir", resultsDir, "error", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		b, err := loadBatch(resultsDir, id)
+		if err != nil {
+			slog.Warn("пропущен пакет", "file", entry.Name(), "error", err)
+			continue
+		}
+		if b.Status != StatusRunning {
+			continue
+		}
+		now := time.Now().UTC()
+		b.Status = StatusFailed
+		b.FinishedAt = &now
+		b.Failed = b.Total - b.Succeeded
+		if err := saveBatch(resultsDir, b); err != nil {
+			slog.Error("не удалось сохранить прерванный пакет", "batch", b.ID, "error", err)
+			continue
+		}
+		slog.Warn("пакет прерван остановкой сервера", "batch", b.ID, "succeeded", b.Succeeded, "failed", b.Failed)
+	}
+}
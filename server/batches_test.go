@@ -0,0 +1,111 @@
+// sermersys/server/batches_test.go
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sermersys/config"
+	"sermersys/workspace"
+)
+
+func TestBatchStatus(t *testing.T) {
+	cases := []struct {
+		succeeded, failed int
+		want              string
+	}{
+		{3, 0, StatusSucceeded},
+		{2, 1, StatusPartial},
+		{0, 3, StatusFailed},
+	}
+	for _, c := range cases {
+		if got := batchStatus(c.succeeded, c.failed); got != c.want {
+			t.Errorf("batchStatus(%d, %d) = %s, ожидалось %s", c.succeeded, c.failed, got, c.want)
+		}
+	}
+}
+
+func TestBatchFinalStatus(t *testing.T) {
+	h := newTestServer(t, newFakeGoogle(t, nil), nil).Handler()
+	cases := []struct {
+		name     string
+		requests string
+		status   string
+	}{
+		{"все успешны", `[{"object_name":"Hotel Adriatic","city":"Budva"}]`, StatusSucceeded},
+		{"часть с ошибкой", `[{"object_name":"Hotel Adriatic","city":"Budva"},{"object_name":"Missing Hotel","city":"Budva"}]`, StatusPartial},
+		{"все с ошибкой", `[{"object_name":"Missing Hotel","city":"Budva"},{"object_name":"Missing Hotel","city":"Kotor"}]`, StatusFailed},
+	}
+	for _, c := range cases {
+		resp := do(t, h, http.MethodPost, "/api/v1/batches", `{"requests":`+c.requests+`}`, "")
+		if resp.Code != http.StatusAccepted {
+			t.Fatalf("%s: код %d: %s", c.name, resp.Code, resp.Body)
+		}
+		var b Batch
+		if err := json.Unmarshal(resp.Body.Bytes(), &b); err != nil {
+			t.Fatal(err)
+		}
+		b = waitBatch(t, h, b.ID)
+		if b.Status != c.status || b.FinishedAt == nil || b.Succeeded+b.Failed != b.Total {
+			t.Errorf("%s: статус %s (успешно %d, с ошибкой %d из %d), ожидался %s", c.name, b.Status, b.Succeeded, b.Failed, b.Total, c.status)
+		}
+	}
+}
+
+func TestInterruptedBatchMarkedFailed(t *testing.T) {
+	created := time.Now().UTC().Add(-time.Hour)
+	running := Batch{ID: "00000000000000aa", Workspace: workspace.DefaultID, Status: StatusRunning, CreatedAt: created, Total: 3, Succeeded: 1}
+	done := Batch{ID: "00000000000000bb", Workspace: workspace.DefaultID, Status: StatusSucceeded, CreatedAt: created, FinishedAt: &created, Total: 1, Succeeded: 1}
+
+	s := newTestServer(t, newFakeGoogle(t, nil), func(cfg *config.Config) {
+		if err := os.MkdirAll(filepath.Join(cfg.ResultsDir, batchesDir), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range []*Batch{&running, &done} {
+			if err := saveBatch(cfg.ResultsDir, b); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	b := getBatch(t, s.Handler(), running.ID)
+	if b.Status != StatusFailed || b.FinishedAt == nil || b.Succeeded != 1 || b.Failed != 2 {
+		t.Errorf("прерванный пакет: %+v", b)
+	}
+	if b := getBatch(t, s.Handler(), done.ID); b.Status != StatusSucceeded || !b.FinishedAt.Equal(created) {
+		t.Errorf("завершённый пакет изменён: %+v", b)
+	}
+}
+
+// waitBatch ждёт завершения пакета
+func waitBatch(t *testing.T, h http.Handler, id string) Batch {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		b := getBatch(t, h, id)
+		if b.Status != StatusRunning {
+			return b
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("пакет %s не завершился: %+v", id, b)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func getBatch(t *testing.T, h http.Handler, id string) Batch {
+	t.Helper()
+	resp := do(t, h, http.MethodGet, "/api/v1/batches/"+id, "", "")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("GET пакета %s: код %d: %s", id, resp.Code, resp.Body)
+	}
+	var b Batch
+	if err := json.Unmarshal(resp.Body.Bytes(), &b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,183 @@
+// sermersys/server/downloads.go
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sermersys/workspace"
+)
+
+// downloadsIndexFile - файл в каталоге результатов, где хранится соответствие ID и файлов
+const downloadsIndexFile = "downloads.json"
+
+var (
+	errUnknownDownload = errors.New("результат не найден")
+	errBadSignature    = errors.New("неверная подпись ссылки")
+	errLinkExpired     = errors.New("срок действия ссылки истёк")
+)
+
+// downloadEntry - файл результата, доступный для скачивания
+type downloadEntry struct {
+	File    string    `json:"file"` // путь относительно каталога результатов
+	Created time.Time `json:"created"`
+}
+
+// downloadStore сопоставляет непрозрачные ID результатов с файлами внутри каталога результатов.
+// Клиент никогда не передаёт путь к файлу, поэтому скачать можно только зарегистрированные результаты.
+type downloadStore struct {
+	workspace  string // ID рабочего пространства, входит в подпись ссылки
+	dir        string
+	signingKey []byte
+	linkTTL    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]downloadEntry
+}
+
+// newDownloadStore создаёт хранилище и загружает ранее выданные ID
+func newDownloadStore(workspaceID, dir, signingKey string, linkTTL time.Duration) (*downloadStore, error) {
+	s := &downloadStore{
+		workspace:  workspaceID,
+		dir:        dir,
+		signingKey: []byte(signingKey),
+		linkTTL:    linkTTL,
+		entries:    make(map[string]downloadEntry),
+	}
+	data, err := os.ReadFile(filepath.Join(dir, downloadsIndexFile))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &s.entries); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга %s: %v", downloadsIndexFile, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("ошибка чтения %s: %v", downloadsIndexFile, err)
+	}
+	return s, nil
+}
+
+// Register выдаёт ID для файла результата. Файлы вне каталога результатов не регистрируются.
+func (s *downloadStore) Register(file string) (string, error) {
+	rel, err := s.relative(file)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("ошибка генерации ID: %v", err)
+	}
+	id := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[id] = downloadEntry{File: rel, Created: time.Now().UTC()}
+	if err := s.saveLocked(); err != nil {
+		delete(s.entries, id)
+		return "", err
+	}
+	return id, nil
+}
+
+// URL возвращает ссылку на скачивание. Если задан ключ подписи, ссылка подписана и ограничена по времени.
+func (s *downloadStore) URL(id string) string {
+	q := url.Values{}
+	q.Set("id", id)
+	if s.workspace != workspace.DefaultID {
+		q.Set("ws", s.workspace)
+	}
+	if len(s.signingKey) > 0 {
+		expires := strconv.FormatInt(time.Now().Add(s.linkTTL).Unix(), 10)
+		q.Set("expires", expires)
+		q.Set("sig", s.sign(id, expires))
+	}
+	return "/download?" + q.Encode()
+}
+
+// Resolve проверяет параметры ссылки и возвращает абсолютный путь к файлу
+func (s *downloadStore) Resolve(q url.Values) (string, error) {
+	id := q.Get("id")
+	if len(s.signingKey) > 0 {
+		expires := q.Get("expires")
+		if !hmac.Equal([]byte(q.Get("sig")), []byte(s.sign(id, expires))) {
+			return "", errBadSignature
+		}
+		unix, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return "", errBadSignature
+		}
+		if time.Now().Unix() > unix {
+			return "", errLinkExpired
+		}
+	}
+	return s.ResolveID(id)
+}
+
+// ResolveID возвращает абсолютный путь к файлу по ID без проверки подписи.
+// Только для маршрутов, где доступ уже проверен ключом API (GET /api/v1/exports/{id}).
+func (s *downloadStore) ResolveID(id string) (string, error) {
+	s.mu.Lock()
+	entry, ok := s.entries[id]
+	s.mu.Unlock()
+	if !ok {
+		return "", errUnknownDownload
+	}
+
+	// Индекс мог быть изменён вручную - повторно проверяем, что файл внутри каталога
+	path := filepath.Join(s.dir, entry.File)
+	if _, err := s.relative(path); err != nil {
+		return "", errUnknownDownload
+	}
+	return filepath.Abs(path)
+}
+
+// relative возвращает путь файла относительно каталога результатов или ошибку, если файл вне его
+func (s *downloadStore) relative(file string) (string, error) {
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("файл %s вне каталога результатов", file)
+	}
+	return rel, nil
+}
+
+func (s *downloadStore) sign(id, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(s.workspace + "|" + id + "|" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// saveLocked записывает индекс; вызывается под s.mu
+func (s *downloadStore) saveLocked() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("ошибка создания каталога результатов: %v", err)
+	}
+	tmp := filepath.Join(s.dir, downloadsIndexFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("ошибка записи %s: %v", downloadsIndexFile, err)
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, downloadsIndexFile))
+}
@@ -0,0 +1,113 @@
+// sermersys/server/downloads_test.go
+package server
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"sermersys/workspace"
+)
+
+func TestDownloadStoreStaysInsideResultsDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "results")
+	secret := filepath.Join(root, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := newDownloadStore(workspace.DefaultID, dir, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{secret, filepath.Join(dir, "..", "secret.txt"), dir, filepath.Join(dir, "..")} {
+		if _, err := store.Register(file); err == nil {
+			t.Errorf("зарегистрирован файл вне каталога результатов: %s", file)
+		}
+	}
+	id, err := store.Register(filepath.Join(dir, "sub", "results.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := store.ResolveID(id)
+	if err != nil || path != filepath.Join(dir, "sub", "results.csv") {
+		t.Errorf("ResolveID: %s, %v", path, err)
+	}
+
+	// ID - не путь: обход каталогов через параметр id не работает
+	for _, bad := range []string{"../secret.txt", secret, "sub/results.csv", ""} {
+		if _, err := store.Resolve(url.Values{"id": {bad}}); !errors.Is(err, errUnknownDownload) {
+			t.Errorf("id %q: ожидалась errUnknownDownload, получено %v", bad, err)
+		}
+	}
+
+	// Индекс, изменённый вручную, не выводит за пределы каталога
+	index := `{"tampered":{"file":"../secret.txt","created":"2025-01-01T00:00:00Z"}}`
+	if err := os.WriteFile(filepath.Join(dir, downloadsIndexFile), []byte(index), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := newDownloadStore(workspace.DefaultID, dir, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.ResolveID("tampered"); !errors.Is(err, errUnknownDownload) {
+		t.Errorf("запись с ../ в индексе: получено %v", err)
+	}
+}
+
+func TestDownloadStoreSignedLinks(t *testing.T) {
+	dir := t.TempDir()
+	store, err := newDownloadStore("acme", dir, "signing-key", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.Register(filepath.Join(dir, "report.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := url.Parse(store.URL(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := link.Query()
+	if q.Get("ws") != "acme" || q.Get("sig") == "" || q.Get("expires") == "" {
+		t.Fatalf("ссылка без подписи или пространства: %s", link)
+	}
+	if _, err := store.Resolve(q); err != nil {
+		t.Errorf("подписанная ссылка не принята: %v", err)
+	}
+
+	tampered := url.Values{"id": {id}, "expires": {strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10)}, "sig": {q.Get("sig")}}
+	if _, err := store.Resolve(tampered); !errors.Is(err, errBadSignature) {
+		t.Errorf("продлённая ссылка: получено %v", err)
+	}
+	// Подпись одного пространства не подходит к другому
+	other, err := newDownloadStore("other", dir, "signing-key", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Resolve(q); !errors.Is(err, errBadSignature) {
+		t.Errorf("ссылка чужого пространства: получено %v", err)
+	}
+
+	expired, err := newDownloadStore("acme", dir, "signing-key", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := url.Parse(expired.URL(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Resolve(old.Query()); !errors.Is(err, errLinkExpired) {
+		t.Errorf("просроченная ссылка: получено %v", err)
+	}
+	if strings.Contains(store.URL(id), "signing-key") {
+		t.Errorf("ключ подписи в ссылке")
+	}
+}
@@ -0,0 +1,128 @@
+// sermersys/server/errors.go
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"sermersys/auth"
+	"sermersys/i18n"
+	"sermersys/mapsearchg"
+	"sermersys/pipeline"
+	"sermersys/workspace"
+)
+
+// Коды ошибок API v1
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeRateLimited      = "rate_limited"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeQueueFull        = "queue_full"
+	CodeShuttingDown     = "shutting_down"
+	CodeNoPlaces         = "no_places"
+	CodeUpstream         = "upstream_error"
+	CodeTimeout          = "timeout"
+	CodeCanceled         = "canceled"
+	CodeInternal         = "internal"
+)
+
+// APIError - тело ошибки API v1
+type APIError struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// errorEnvelope - ответ API v1 с ошибкой
+type errorEnvelope struct {
+	Error APIError `json:"error"`
+}
+
+// isAPIRequest сообщает, что запрос относится к API v1 и ошибки отдаются в JSON
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+// fail отвечает ошибкой: для API v1 - JSON-конвертом, для прежних маршрутов - текстом.
+// message - ключ каталога сообщений (переводится на язык запроса) или уже готовый текст.
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, status int, code, message string, details map[string]interface{}) {
+	message = i18n.T(i18n.FromContext(r.Context()), message)
+	if !isAPIRequest(r) {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	envelope := errorEnvelope{Error: APIError{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: RequestID(r.Context()),
+	}}
+	if err := json.NewEncoder(w).Encode(envelope); err != nil {
+		slog.WarnContext(r.Context(), "ошибка при отправке ответа", "error", err)
+	}
+}
+
+// errorKeys - ключи каталога сообщений для ошибок других пакетов, которые отдаются клиенту
+var errorKeys = []struct {
+	err error
+	key string
+}{
+	{auth.ErrUnknownKey, "error.api_key_unknown"},
+	{auth.ErrRevoked, "error.api_key_revoked"},
+	{auth.ErrForbidden, "error.forbidden"},
+	{auth.ErrRateLimited, "error.rate_limited"},
+	{auth.ErrQuotaExceeded, "error.quota_exceeded"},
+	{workspace.ErrNotFound, "error.workspace_not_found"},
+	{errQueueFull, "error.queue_full"},
+	{errShuttingDown, "error.shutting_down"},
+	{errUnknownDownload, "error.result_not_found"},
+	{errBadSignature, "error.bad_signature"},
+	{errLinkExpired, "error.link_expired"},
+}
+
+// errorKey возвращает ключ сообщения для известной ошибки или её текст
+func errorKey(err error) string {
+	for _, e := range errorKeys {
+		if errors.Is(err, e.err) {
+			return e.key
+		}
+	}
+	return err.Error()
+}
+
+// analysisError сопоставляет ошибку анализа с HTTP-статусом и кодом API; сообщение - на языке lang
+func analysisError(lang i18n.Lang, err error) (int, *APIError) {
+	var placesErr *mapsearchg.APIError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, &APIError{Code: CodeTimeout, Message: i18n.T(lang, "error.timeout")}
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable, &APIError{Code: CodeCanceled, Message: i18n.T(lang, "error.canceled")}
+	case errors.Is(err, pipeline.ErrNoPlaces):
+		return http.StatusUnprocessableEntity, &APIError{Code: CodeNoPlaces, Message: i18n.T(lang, "error.no_places")}
+	case errors.As(err, &placesErr):
+		return http.StatusBadGateway, &APIError{
+			Code:    CodeUpstream,
+			Message: i18n.T(lang, "error.upstream_places"),
+			Details: map[string]interface{}{"api": placesErr.API, "status": placesErr.Status},
+		}
+	case errors.As(err, &netErr):
+		return http.StatusBadGateway, &APIError{Code: CodeUpstream, Message: i18n.T(lang, "error.upstream")}
+	default:
+		return http.StatusInternalServerError, &APIError{Code: CodeInternal, Message: i18n.T(lang, "error.analysis", err)}
+	}
+}
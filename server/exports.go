@@ -0,0 +1,167 @@
+// sermersys/server/exports.go
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sermersys/auth"
+	"sermersys/export"
+	"sermersys/i18n"
+	"sermersys/mailer"
+	"sermersys/pipeline"
+)
+
+// reportFormat - отчёт, который сохраняется рядом с CSV после каждого анализа
+type reportFormat struct {
+	kind  string // вид файла в списке exports
+	ext   string
+	write func(w io.Writer, lang i18n.Lang, results ...*pipeline.Result) error
+}
+
+var reportFormats = []reportFormat{
+	{"workbook_xlsx", ".xlsx", export.WriteXLSX},
+	{"places_geojson", ".geojson", export.WriteGeoJSON},
+	{"places_kml", ".kml", export.WriteKML},
+	{"report_html", ".html", export.WriteHTML},
+	{"report_pdf", ".pdf", export.WritePDF},
+}
+
+// registerExports сохраняет отчёты по результату анализа на языке lang и выдаёт ID для
+// скачивания всех файлов результата: CSV googlesearch, разбора и отчётов
+func registerExports(ctx context.Context, sc *scope, lang i18n.Lang, result *pipeline.Result) []Export {
+	files := []struct{ kind, path string }{
+		{"listings_csv", result.Filename},
+		{"explain_csv", result.ExplainFilename},
+	}
+	base := reportBase(sc.cfg.ResultsDir, result)
+	for _, format := range reportFormats {
+		path := base + format.ext
+		if err := saveReport(path, format, lang, result); err != nil {
+			slog.ErrorContext(ctx, "не удалось сохранить отчёт", "kind", format.kind, "error", err)
+			continue
+		}
+		files = append(files, struct{ kind, path string }{format.kind, path})
+	}
+
+	var exports []Export
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		id, name, link := registerDownload(ctx, sc, file.path)
+		if id != "" {
+			exports = append(exports, Export{ID: id, Kind: file.kind, Filename: name, DownloadURL: link})
+		}
+	}
+	return exports
+}
+
+// reportBase возвращает путь отчётов без расширения: рядом с CSV результата,
+// а если CSV нет (поиск по платформам выключен) - в каталоге результатов по времени и названию
+func reportBase(resultsDir string, result *pipeline.Result) string {
+	if result.Filename != "" {
+		return strings.TrimSuffix(result.Filename, filepath.Ext(result.Filename))
+	}
+	name := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, result.RefinedHotelName)
+	return filepath.Join(resultsDir, fmt.Sprintf("%s-%s", time.Now().Format("20060102150405"), name))
+}
+
+func saveReport(path string, format reportFormat, lang i18n.Lang, result *pipeline.Result) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := format.write(file, lang, result); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+// =================== Отправка отчёта по почте ===================
+
+// MailStatus - итог отправки PDF-отчёта получателям из запроса
+type MailStatus struct {
+	To     []string   `json:"to"`
+	SentAt *time.Time `json:"sent_at,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// checkRecipients проверяет получателей отчёта из запроса (см. recipientsProblem) и при ошибке отвечает 400
+func (s *Server) checkRecipients(w http.ResponseWriter, r *http.Request, sc *scope, request *AnalysisRequest) bool {
+	if message, details := recipientsProblem(r.Context(), sc, request); message != "" {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, message, details)
+		return false
+	}
+	return true
+}
+
+// recipientsProblem проверяет получателей отчёта: отправка почты должна быть настроена,
+// запрос - сделан с ключом API, адреса - корректны и в доменах mail.allowed_domains,
+// чтобы сервер не рассылал письма на произвольные адреса. Адреса приводятся к виду без имён.
+func recipientsProblem(ctx context.Context, sc *scope, request *AnalysisRequest) (string, map[string]interface{}) {
+	if len(request.EmailTo) == 0 {
+		return "", nil
+	}
+	if !sc.cfg.Mail.Enabled() {
+		return "error.mail_disabled", nil
+	}
+	if auth.FromContext(ctx) == nil {
+		return "error.mail_requires_auth", nil
+	}
+	lang := i18n.FromContext(ctx)
+	to, err := mailer.ParseRecipients(request.EmailTo)
+	if err != nil {
+		return i18n.T(lang, "error.invalid_recipients", err), map[string]interface{}{"email_to": request.EmailTo}
+	}
+	for _, address := range to {
+		if !sc.cfg.Mail.AllowsRecipient(address) {
+			return i18n.T(lang, "error.recipient_not_allowed", address),
+				map[string]interface{}{"email_to": address, "allowed_domains": sc.cfg.Mail.AllowedDomains}
+		}
+	}
+	request.EmailTo = to
+	return "", nil
+}
+
+// mailReport отправляет PDF-отчёт по результату анализа получателям to. Ошибка отправки
+// не отменяет результат анализа, а возвращается в статусе.
+func mailReport(ctx context.Context, sc *scope, lang i18n.Lang, result *pipeline.Result, to []string) *MailStatus {
+	if len(to) == 0 {
+		return nil
+	}
+	status := &MailStatus{To: to}
+	var pdf bytes.Buffer
+	err := export.WritePDF(&pdf, lang, result)
+	if err == nil {
+		filename := filepath.Base(reportBase(sc.cfg.ResultsDir, result)) + ".pdf"
+		err = mailer.Send(ctx, sc.cfg.Mail, mailer.Report(lang, to, result.RefinedHotelName, result.RefinedAddress, filename, pdf.Bytes()))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "не удалось отправить отчёт по почте", "recipients", len(to), "error", err)
+		status.Error = err.Error()
+		return status
+	}
+	now := time.Now().UTC()
+	status.SentAt = &now
+	slog.InfoContext(ctx, "отчёт отправлен по почте", "recipients", len(to))
+	return status
+}
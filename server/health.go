@@ -0,0 +1,62 @@
+// sermersys/server/health.go
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// healthResponse - ответ /healthz и /readyz
+type healthResponse struct {
+	Status string            `json:"status"`           // ok или unavailable
+	Checks map[string]string `json:"checks,omitempty"` // проверка - ok или описание проблемы
+}
+
+// healthzHandler отвечает, что процесс жив и обслуживает запросы (liveness)
+func (s *Server) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSONStatus(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// readyzHandler проверяет, что сервер может выполнять анализы (readiness): конфигурация верна,
+// файлы рабочих пространств и ключей читаются, в каталог результатов можно писать.
+// При остановке сервера отвечает 503, чтобы балансировщик перестал направлять запросы.
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]error{
+		"config":      s.cfg.Validate(),
+		"workspaces":  s.workspaces.Check(),
+		"results_dir": checkWritable(s.cfg.ResultsDir),
+	}
+	if s.keys != nil {
+		checks["auth_keys"] = s.keys.Check()
+	}
+	if s.draining.Load() {
+		checks["shutdown"] = errShuttingDown
+	}
+
+	resp := healthResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+	status := http.StatusOK
+	for name, err := range checks {
+		if err != nil {
+			resp.Checks[name] = err.Error()
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[name] = "ok"
+	}
+	writeJSONStatus(w, status, resp)
+}
+
+// checkWritable проверяет, что в каталоге можно создать файл; отсутствующий каталог создаётся
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("не удалось создать каталог: %v", err)
+	}
+	file, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("каталог недоступен для записи: %v", err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,481 @@
+// sermersys/server/jobs.go
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"sermersys/i18n"
+	"sermersys/logging"
+	"sermersys/mapsearchg"
+	"sermersys/metrics"
+	"sermersys/pipeline"
+	"sermersys/tracing"
+)
+
+// Статусы анализа
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCanceled  = "canceled"
+)
+
+// analysesDir - подкаталог каталога результатов с сохранёнными анализами API v1
+const analysesDir = "analyses"
+
+var (
+	errQueueFull        = errors.New("очередь анализов заполнена")
+	errShuttingDown     = errors.New("сервер останавливается")
+	errAnalysisNotFound = errors.New("анализ не найден")
+)
+
+// AnalysisRequest - запрос анализа к серверу: параметры анализа и доставка отчёта.
+// В JSON поля RequestData идут на одном уровне с email_to.
+type AnalysisRequest struct {
+	mapsearchg.RequestData
+	EmailTo []string `json:"email_to,omitempty"` // получатели PDF-отчёта; нужна настройка mail
+}
+
+// Analysis - анализ, запущенный через API v1
+type Analysis struct {
+	ID         string           `json:"id"`
+	Workspace  string           `json:"workspace"`
+	Status     string           `json:"status"`
+	Request    AnalysisRequest  `json:"request"`
+	CreatedAt  time.Time        `json:"created_at"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Error      *APIError        `json:"error,omitempty"`
+	Result     *pipeline.Result `json:"result,omitempty"`
+	Exports    []Export         `json:"exports,omitempty"`
+	Mail       *MailStatus      `json:"mail,omitempty"`  // отправка PDF-отчёта на request.email_to
+	Batch      string           `json:"batch,omitempty"` // ID пакета, если анализ поставлен пакетом
+}
+
+// Export - файл результата анализа, доступный для скачивания
+type Export struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"` // listings_csv, explain_csv, workbook_xlsx, places_geojson, places_kml, report_html, report_pdf или batch_jsonl
+	Filename    string `json:"filename"`
+	DownloadURL string `json:"download_url"`
+}
+
+// Finished сообщает, что анализ завершён (успешно или нет)
+func (a *Analysis) Finished() bool {
+	return a.Status == StatusSucceeded || a.Status == StatusFailed || a.Status == StatusCanceled
+}
+
+// job - анализ в очереди вместе с пространством, в котором он выполняется
+type job struct {
+	sc     *scope
+	lang   i18n.Lang         // язык шагов анализа и сообщений об ошибках
+	origin trace.SpanContext // спан запроса, поставившего анализ в очередь
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{} // закрывается по завершении анализа
+	batch  *batch        // пакет, в который входит анализ, или nil
+	item   int           // номер анализа в пакете, с 1
+	taken  bool          // анализ забран из очереди исполнителем или отменой; под jobQueue.mu
+
+	mu       sync.Mutex
+	analysis Analysis
+}
+
+// snapshot возвращает копию текущего состояния анализа
+func (j *job) snapshot() *Analysis {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	a := j.analysis
+	return &a
+}
+
+// update изменяет состояние анализа и сохраняет его в каталог пространства
+func (j *job) update(change func(a *Analysis)) {
+	j.mu.Lock()
+	change(&j.analysis)
+	a := j.analysis
+	j.mu.Unlock()
+	if err := saveAnalysis(j.sc.cfg.ResultsDir, &a); err != nil {
+		slog.ErrorContext(j.ctx, "не удалось сохранить анализ", "error", err)
+	}
+}
+
+// jobQueue - очередь анализов с фиксированным числом исполнителей
+type jobQueue struct {
+	queue  chan *job
+	ctx    context.Context // отменяется при остановке очереди
+	cancel context.CancelFunc
+	wg     sync.WaitGroup // исполнители
+	jobs   sync.WaitGroup // анализы в очереди и выполняемые
+
+	mu       sync.Mutex
+	active   map[string]*job // анализы в очереди и выполняемые
+	draining bool            // новые анализы не принимаются (см. Shutdown)
+}
+
+func newJobQueue(workers, size int) *jobQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &jobQueue{
+		queue:  make(chan *job, size),
+		ctx:    ctx,
+		cancel: cancel,
+		active: make(map[string]*job),
+	}
+	// Серии измерителя видны в /metrics сразу, а не после первого анализа
+	metrics.Jobs.Add(0, "queued")
+	metrics.Jobs.Add(0, "running")
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	return q
+}
+
+// Submit ставит анализ в очередь; при заполненной очереди возвращает errQueueFull,
+// при остановке сервера - errShuttingDown.
+// Из контекста запроса rctx берутся язык сообщений, ID запроса для журнала и спан для связи
+// с трассировкой анализа; сам анализ выполняется в контексте очереди и не прерывается
+// по завершении запроса.
+func (q *jobQueue) Submit(rctx context.Context, sc *scope, request AnalysisRequest) (*job, error) {
+	jobs, err := q.SubmitAll(rctx, sc, []AnalysisRequest{request}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return jobs[0], nil
+}
+
+// SubmitAll ставит в очередь анализы пакета b (nil - без пакета): либо все, либо ни одного,
+// если в очереди не хватает места. Ошибки - как у Submit.
+func (q *jobQueue) SubmitAll(rctx context.Context, sc *scope, requests []AnalysisRequest, b *batch) ([]*job, error) {
+	jobs := make([]*job, 0, len(requests))
+	discard := func() {
+		for _, j := range jobs {
+			j.cancel()
+			os.Remove(analysisFile(sc.cfg.ResultsDir, j.analysis.ID))
+		}
+	}
+	for i, request := range requests {
+		id, err := newID()
+		if err != nil {
+			discard()
+			return nil, err
+		}
+		ctx, cancel := context.WithCancel(logging.With(q.ctx,
+			"request_id", RequestID(rctx), "workspace", sc.workspace.ID, "job_id", id))
+		j := &job{
+			sc:     sc,
+			lang:   i18n.FromContext(rctx),
+			origin: trace.SpanContextFromContext(rctx),
+			ctx:    ctx,
+			cancel: cancel,
+			done:   make(chan struct{}),
+			batch:  b,
+			item:   i + 1,
+			analysis: Analysis{
+				ID:        id,
+				Workspace: sc.workspace.ID,
+				Status:    StatusQueued,
+				Request:   request,
+				CreatedAt: time.Now().UTC(),
+			},
+		}
+		if b != nil {
+			j.analysis.Batch = b.id
+		}
+		// Сохраняем до постановки в очередь, чтобы исполнитель не опередил запись статуса queued
+		j.update(func(a *Analysis) {})
+		jobs = append(jobs, j)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.draining {
+		discard()
+		return nil, errShuttingDown
+	}
+	// Пакет ставится в очередь целиком; место проверяется под q.mu, как и в других вызовах
+	if cap(q.queue)-len(q.queue) < len(jobs) {
+		discard()
+		return nil, errQueueFull
+	}
+	for _, j := range jobs {
+		q.queue <- j
+		q.active[j.analysis.ID] = j
+		q.jobs.Add(1)
+		metrics.Jobs.Add(1, "queued")
+	}
+	return jobs, nil
+}
+
+// Get возвращает анализ из очереди или выполняемый анализ пространства
+func (q *jobQueue) Get(workspaceID, id string) *job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.active[id]
+	if !ok || j.sc.workspace.ID != workspaceID {
+		return nil
+	}
+	return j
+}
+
+// Cancel отменяет анализ. Анализ, ждущий в очереди, завершается сразу, не дожидаясь
+// исполнителя; выполняемый анализ прерывается, и Cancel ждёт, пока он запишет итог.
+func (q *jobQueue) Cancel(j *job) {
+	if !q.take(j) {
+		j.cancel()
+		<-j.done
+		return
+	}
+	metrics.Jobs.Add(-1, "queued")
+	j.cancel()
+	j.finish(nil, context.Canceled)
+	q.release(j)
+}
+
+// Len возвращает число анализов в очереди и выполняемых
+func (q *jobQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.active)
+}
+
+// Close останавливает очередь: выполняемые и ожидающие анализы отменяются
+func (q *jobQueue) Close() {
+	q.cancel()
+	q.wg.Wait()
+}
+
+// Shutdown перестаёт принимать анализы и ждёт, пока выполнятся уже поставленные в очередь,
+// чтобы их результаты и файлы были записаны целиком. Если ctx истекает раньше,
+// оставшиеся анализы отменяются и возвращается ошибка ctx.
+func (q *jobQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	q.draining = true
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.jobs.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		slog.Warn("анализы не завершились за отведённое время и будут отменены", "remaining", q.Len())
+	}
+	q.Close()
+	return err
+}
+
+func (q *jobQueue) worker() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			q.cancelPending()
+			return
+		case j := <-q.queue:
+			q.run(j)
+		}
+	}
+}
+
+// run выполняет анализ и записывает результат
+func (q *jobQueue) run(j *job) {
+	if !q.take(j) {
+		// Анализ отменён, пока ждал в очереди, и уже завершён (см. Cancel)
+		return
+	}
+	defer q.release(j)
+	metrics.Jobs.Add(-1, "queued")
+
+	if err := j.ctx.Err(); err != nil {
+		j.finish(nil, err)
+		return
+	}
+	metrics.Jobs.Add(1, "running")
+	defer metrics.Jobs.Add(-1, "running")
+	j.update(func(a *Analysis) {
+		now := time.Now().UTC()
+		a.Status = StatusRunning
+		a.StartedAt = &now
+	})
+	// Анализ - отдельная трассировка: запрос, поставивший его в очередь, давно завершён
+	ctx, span := tracing.Start(j.ctx, "job",
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.Link{SpanContext: j.origin}),
+		trace.WithAttributes(
+			attribute.String("job_id", j.analysis.ID),
+			attribute.String("workspace", j.sc.workspace.ID),
+		))
+	result, err := pipeline.AnalyzeContext(i18n.WithLang(ctx, j.lang), j.sc.cfg, j.snapshot().Request.RequestData)
+	tracing.End(span, err)
+	j.finish(result, err)
+}
+
+// take отмечает, что анализ забран из очереди; false - его уже забрал исполнитель или отмена
+func (q *jobQueue) take(j *job) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if j.taken {
+		return false
+	}
+	j.taken = true
+	return true
+}
+
+// release убирает завершённый анализ из активных и сообщает о его завершении
+func (q *jobQueue) release(j *job) {
+	q.mu.Lock()
+	delete(q.active, j.analysis.ID)
+	q.mu.Unlock()
+	j.cancel()
+	close(j.done)
+	q.jobs.Done()
+}
+
+// cancelPending отменяет анализы, оставшиеся в очереди при остановке
+func (q *jobQueue) cancelPending() {
+	for {
+		select {
+		case j := <-q.queue:
+			q.run(j)
+		default:
+			return
+		}
+	}
+}
+
+// finish записывает итог анализа, сохраняет отчёты и регистрирует файлы для скачивания
+func (j *job) finish(result *pipeline.Result, err error) {
+	var exports []Export
+	var mail *MailStatus
+	if err == nil {
+		exports = registerExports(j.ctx, j.sc, j.lang, result)
+		mail = mailReport(j.ctx, j.sc, j.lang, result, j.snapshot().Request.EmailTo)
+	}
+
+	j.update(func(a *Analysis) {
+		now := time.Now().UTC()
+		a.FinishedAt = &now
+		switch {
+		case err == nil:
+			a.Status = StatusSucceeded
+			a.Result = result
+			a.Exports = exports
+			a.Mail = mail
+		case errors.Is(err, context.Canceled):
+			a.Status = StatusCanceled
+			_, a.Error = analysisError(j.lang, err)
+		default:
+			a.Status = StatusFailed
+			_, a.Error = analysisError(j.lang, err)
+		}
+	})
+	if j.batch != nil {
+		j.batch.record(j.ctx, j.item, j.snapshot())
+	}
+}
+
+// =================== Хранение ===================
+
+func analysisFile(resultsDir, id string) string {
+	return filepath.Join(resultsDir, analysesDir, id+".json")
+}
+
+// saveAnalysis сохраняет анализ в каталог результатов пространства
+func saveAnalysis(resultsDir string, a *Analysis) error {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(resultsDir, analysesDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	tmp := analysisFile(resultsDir, a.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, analysisFile(resultsDir, a.ID))
+}
+
+// loadAnalysis читает сохранённый анализ; ID проверяется, чтобы исключить выход за пределы каталога
+func loadAnalysis(resultsDir, id string) (*Analysis, error) {
+	if !validAnalysisID(id) {
+		return nil, errAnalysisNotFound
+	}
+	data, err := os.ReadFile(analysisFile(resultsDir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errAnalysisNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var a Analysis
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга анализа %s: %v", id, err)
+	}
+	return &a, nil
+}
+
+// listAnalyses возвращает сохранённые анализы пространства, новые первыми
+func listAnalyses(resultsDir string) ([]*Analysis, error) {
+	entries, err := os.ReadDir(filepath.Join(resultsDir, analysesDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*Analysis
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		a, err := loadAnalysis(resultsDir, id)
+		if err != nil {
+			slog.Warn("пропущен анализ", "file", entry.Name(), "error", err)
+			continue
+		}
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list, nil
+}
+
+// newID возвращает случайный ID анализа или пакета
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("ошибка генерации ID: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func validAnalysisID(id string) bool {
+	if len(id) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
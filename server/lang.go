@@ -0,0 +1,29 @@
+// sermersys/server/lang.go
+package server
+
+import (
+	"net/http"
+
+	"sermersys/i18n"
+)
+
+// withLang определяет язык ответа: параметр ?lang=, затем Accept-Language, иначе i18n.Default.
+// Язык сохраняется в контексте запроса и возвращается в заголовке Content-Language.
+func withLang(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := i18n.Resolve(r.URL.Query().Get("lang"), i18n.Negotiate(r.Header.Get("Accept-Language"), i18n.Default))
+		w.Header().Set("Content-Language", string(lang))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(i18n.WithLang(r.Context(), lang)))
+	})
+}
+
+// withLocale переключает язык запроса на поле locale из тела, если оно задано и поддерживается
+func withLocale(w http.ResponseWriter, r *http.Request, locale string) *http.Request {
+	lang, ok := i18n.Parse(locale)
+	if !ok {
+		return r
+	}
+	w.Header().Set("Content-Language", string(lang))
+	return r.WithContext(i18n.WithLang(r.Context(), lang))
+}
@@ -0,0 +1,515 @@
+// sermersys/server/openapi_test.go
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"sermersys/auth"
+	"sermersys/config"
+)
+
+// Контрактный тест API v1: каждая операция из openapi.json вызывается через Server.Handler(),
+// код ответа должен быть объявлен в спецификации, а тело - соответствовать схеме ответа.
+
+// =================== Поддельный Google ===================
+
+// newFakeGoogle поднимает поддельные Places и Custom Search. Пока канал hold не закрыт,
+// Text Search для объекта «Slow Hotel» не отвечает - так тест держит исполнителя очереди занятым.
+// Объект «Missing Hotel» не находится, и его анализ завершается ошибкой.
+func newFakeGoogle(t *testing.T, hold <-chan struct{}) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/textsearch", func(w http.ResponseWriter, r *http.Request) {
+		if hold != nil && strings.Contains(r.URL.Query().Get("query"), "Slow Hotel") {
+			select {
+			case <-hold:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if strings.Contains(r.URL.Query().Get("query"), "Missing Hotel") {
+			fmt.Fprint(w, `{"status":"ZERO_RESULTS","results":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"OK","results":[{"place_id":"P1","name":"Hotel Adriatic"}]}`)
+	})
+	mux.HandleFunc("/details", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"OK","result":{"place_id":"P1","name":"Hotel Adriatic","formatted_address":"Slovenska obala 1, Budva, Montenegro",`+
+			`"geometry":{"location":{"lat":42.28,"lng":18.84}},"rating":4.4,"user_ratings_total":812,`+
+			`"reviews":[{"author_name":"Ana","rating":5,"text":"Great"}]}}`)
+	})
+	mux.HandleFunc("/findplace", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"OK","candidates":[{"place_id":"P1"}]}`)
+	})
+	mux.HandleFunc("/customsearch", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"items":[{"title":"Hotel Adriatic Budva - Booking.com","link":"https://www.booking.com/hotel/me/adriatic.html"}]}`)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestServer создаёт сервер с каталогом результатов во временном каталоге
+func newTestServer(t *testing.T, google *httptest.Server, change func(cfg *config.Config)) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.ResultsDir = filepath.Join(dir, "results")
+	cfg.WorkspacesFile = filepath.Join(dir, "workspaces.json")
+	cfg.Auth.KeysFile = filepath.Join(dir, "api_keys.json")
+	cfg.PlatformsFile = "../platform2.txt"
+	cfg.GoogleAPIKey = "test-key"
+	cfg.GoogleCX = "test-cx"
+	cfg.Endpoints = config.Endpoints{
+		PlacesTextSearch: google.URL + "/textsearch",
+		PlacesDetails:    google.URL + "/details",
+		PlacesFindPlace:  google.URL + "/findplace",
+		CustomSearch:     google.URL + "/customsearch",
+	}
+	cfg.Jobs.Workers = 1
+	if change != nil {
+		change(cfg)
+	}
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// =================== Контракт ===================
+
+// contractCase - запрос к операции спецификации и ожидаемый код ответа
+type contractCase struct {
+	method string
+	path   string // путь из спецификации без /api/v1
+	id     string // значение {id}: ключ ids или готовое значение
+	query  string
+	body   string
+	key    string // X-API-Key
+	status int
+}
+
+func TestOpenAPIContract(t *testing.T) {
+	spec := loadSpec(t)
+	google := newFakeGoogle(t, nil)
+	s := newTestServer(t, google, nil)
+	h := s.Handler()
+
+	// Готовые анализы: успешный (через ?wait) и завершившийся ошибкой
+	ids := map[string]string{"unknown": "ffffffffffffffff"}
+	var done Analysis
+	resp := do(t, h, http.MethodPost, "/api/v1/analyses?wait=1m", `{"object_name":"Hotel Adriatic","city":"Budva","country":"Montenegro"}`, "")
+	if resp.Code != http.StatusOK || json.Unmarshal(resp.Body.Bytes(), &done) != nil || done.Status != StatusSucceeded || len(done.Exports) == 0 {
+		t.Fatalf("анализ не завершился успешно: %d %s", resp.Code, resp.Body)
+	}
+	ids["succeeded"] = done.ID
+	for _, e := range done.Exports {
+		if e.Kind == "listings_csv" {
+			ids["export"] = e.ID
+		}
+	}
+	failed := &Analysis{ID: "00000000000000fa", Workspace: "default", Status: StatusFailed, CreatedAt: time.Now().UTC(),
+		Error: &APIError{Code: CodeUpstream, Message: "Google Places вернул статус REQUEST_DENIED"}}
+	if err := saveAnalysis(s.cfg.ResultsDir, failed); err != nil {
+		t.Fatal(err)
+	}
+	ids["failed"] = failed.ID
+	var b Batch
+	resp = do(t, h, http.MethodPost, "/api/v1/batches", `{"requests":[{"object_name":"Hotel Adriatic","city":"Budva"}]}`, "")
+	if resp.Code != http.StatusAccepted || json.Unmarshal(resp.Body.Bytes(), &b) != nil {
+		t.Fatalf("пакет не поставлен: %d %s", resp.Code, resp.Body)
+	}
+	ids["batch"] = b.ID
+
+	cases := []contractCase{
+		{method: "GET", path: "/openapi.json", status: 200},
+
+		{method: "GET", path: "/analyses", status: 200},
+		{method: "GET", path: "/analyses", query: "status=failed&limit=1", status: 200},
+		{method: "GET", path: "/analyses", query: "limit=0", status: 400},
+		{method: "POST", path: "/analyses", body: `{"object_name":"Hotel Adriatic","city":"Budva"}`, status: 202},
+		{method: "POST", path: "/analyses", query: "wait=1m", body: `{"object_name":"Hotel Adriatic","city":"Budva","explain":true}`, status: 200},
+		{method: "POST", path: "/analyses", body: `{"object_name":`, status: 400},
+		{method: "POST", path: "/analyses", body: `{"object_name":"Hotel Adriatic"}`, status: 400},
+		{method: "POST", path: "/analyses", body: `{"object_name":"Hotel Adriatic","city":"Budva","platforms_file":"/etc/passwd"}`, status: 400},
+		{method: "POST", path: "/analyses", query: "wait=forever", body: `{"object_name":"Hotel Adriatic","city":"Budva"}`, status: 400},
+
+		{method: "GET", path: "/analyses/{id}", id: "succeeded", status: 200},
+		{method: "GET", path: "/analyses/{id}", id: "unknown", status: 404},
+		{method: "GET", path: "/analyses/{id}", id: "not-an-id", status: 404},
+		{method: "DELETE", path: "/analyses/{id}", id: "succeeded", status: 409},
+		{method: "DELETE", path: "/analyses/{id}", id: "unknown", status: 404},
+
+		{method: "GET", path: "/analyses/{id}/places", id: "succeeded", status: 200},
+		{method: "GET", path: "/analyses/{id}/places", id: "failed", status: 409},
+		{method: "GET", path: "/analyses/{id}/places", id: "unknown", status: 404},
+		{method: "GET", path: "/analyses/{id}/listings", id: "succeeded", status: 200},
+		{method: "GET", path: "/analyses/{id}/listings", id: "failed", status: 409},
+		{method: "GET", path: "/analyses/{id}/listings", id: "unknown", status: 404},
+		{method: "GET", path: "/analyses/{id}/reviews", id: "succeeded", status: 200},
+		{method: "GET", path: "/analyses/{id}/reviews", id: "failed", status: 409},
+		{method: "GET", path: "/analyses/{id}/reviews", id: "unknown", status: 404},
+		{method: "GET", path: "/analyses/{id}/exports", id: "succeeded", status: 200},
+		{method: "GET", path: "/analyses/{id}/exports", id: "failed", status: 409},
+		{method: "GET", path: "/analyses/{id}/exports", id: "unknown", status: 404},
+
+		{method: "POST", path: "/batches", body: `{"requests":[{"object_name":"Hotel Adriatic","city":"Budva"},{"object_name":"Hotel Mogren","city":"Budva"}]}`, status: 202},
+		{method: "POST", path: "/batches", body: `{"requests":[]}`, status: 400},
+		{method: "POST", path: "/batches", body: `{"requests":[{"object_name":"Hotel Adriatic"}]}`, status: 400},
+		{method: "GET", path: "/batches/{id}", id: "batch", status: 200},
+		{method: "GET", path: "/batches/{id}", id: "unknown", status: 404},
+
+		{method: "GET", path: "/exports/{id}", id: "export", status: 200},
+		{method: "GET", path: "/exports/{id}", id: "unknown", status: 404},
+
+		{method: "GET", path: "/workspace", status: 200},
+		{method: "GET", path: "/serp-report", query: "hotel_name=Hotel+Adriatic&city=Budva", status: 200},
+		{method: "GET", path: "/serp-report", status: 400},
+	}
+	covered := runContract(t, spec, h, cases, ids)
+	checkCoverage(t, spec, covered)
+}
+
+// TestOpenAPIContractAuth проверяет ответы 401 и 403 при включённой авторизации,
+// а также то, что файл результата скачивается по ключу API при подписанных ссылках
+func TestOpenAPIContractAuth(t *testing.T) {
+	spec := loadSpec(t)
+	google := newFakeGoogle(t, nil)
+	s := newTestServer(t, google, func(cfg *config.Config) {
+		cfg.Auth.Enabled = true
+		cfg.Downloads.SigningKey = "test-signing-key"
+	})
+	h := s.Handler()
+	run, _, err := s.keys.Create(auth.CreateOptions{Name: "run", Role: auth.RoleRun})
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, _, err := s.keys.Create(auth.CreateOptions{Name: "read", Role: auth.RoleRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var done Analysis
+	resp := do(t, h, http.MethodPost, "/api/v1/analyses?wait=1m", `{"object_name":"Hotel Adriatic","city":"Budva"}`, run)
+	if resp.Code != http.StatusOK || json.Unmarshal(resp.Body.Bytes(), &done) != nil || len(done.Exports) == 0 {
+		t.Fatalf("анализ не завершился успешно: %d %s", resp.Code, resp.Body)
+	}
+	ids := map[string]string{"succeeded": done.ID, "export": done.Exports[0].ID}
+
+	cases := []contractCase{
+		{method: "GET", path: "/openapi.json", status: 200},
+		{method: "GET", path: "/analyses", status: 401},
+		{method: "GET", path: "/analyses", key: "sk_unknown", status: 401},
+		{method: "GET", path: "/analyses", key: read, status: 200},
+		{method: "POST", path: "/analyses", body: `{"object_name":"Hotel Adriatic","city":"Budva"}`, status: 401},
+		{method: "POST", path: "/analyses", key: read, body: `{"object_name":"Hotel Adriatic","city":"Budva"}`, status: 403},
+		{method: "POST", path: "/batches", key: read, body: `{"requests":[{"object_name":"Hotel Adriatic","city":"Budva"}]}`, status: 403},
+		{method: "GET", path: "/analyses/{id}", id: "succeeded", key: read, status: 200},
+		{method: "DELETE", path: "/analyses/{id}", id: "succeeded", key: read, status: 403},
+		{method: "GET", path: "/exports/{id}", id: "export", key: read, status: 200},
+		{method: "GET", path: "/exports/{id}", id: "export", status: 401},
+	}
+	runContract(t, spec, h, cases, ids)
+}
+
+// TestCancelQueuedAnalysis проверяет, что отмена анализа в очереди не ждёт исполнителя
+func TestCancelQueuedAnalysis(t *testing.T) {
+	spec := loadSpec(t)
+	hold := make(chan struct{})
+	defer close(hold)
+	google := newFakeGoogle(t, hold)
+	s := newTestServer(t, google, nil)
+	h := s.Handler()
+
+	// Единственный исполнитель занят анализом, ждущим ответа Google
+	resp := do(t, h, http.MethodPost, "/api/v1/analyses", `{"object_name":"Slow Hotel","city":"Budva"}`, "")
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("анализ не поставлен: %d %s", resp.Code, resp.Body)
+	}
+	var queued Analysis
+	resp = do(t, h, http.MethodPost, "/api/v1/analyses", `{"object_name":"Hotel Adriatic","city":"Budva"}`, "")
+	if resp.Code != http.StatusAccepted || json.Unmarshal(resp.Body.Bytes(), &queued) != nil || queued.Status != StatusQueued {
+		t.Fatalf("второй анализ должен ждать в очереди: %d %s", resp.Code, resp.Body)
+	}
+
+	canceled := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		canceled <- do(t, h, http.MethodDelete, "/api/v1/analyses/"+queued.ID, "", "")
+	}()
+	select {
+	case resp = <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("DELETE анализа в очереди ждёт исполнителя")
+	}
+	checkResponse(t, spec, "DELETE", "/analyses/{id}", resp)
+	var a Analysis
+	if resp.Code != http.StatusOK || json.Unmarshal(resp.Body.Bytes(), &a) != nil || a.Status != StatusCanceled || a.FinishedAt == nil {
+		t.Fatalf("ожидался отменённый анализ: %d %s", resp.Code, resp.Body)
+	}
+	// Отменённый анализ больше не в очереди, но сохранён
+	resp = do(t, h, http.MethodGet, "/api/v1/analyses/"+queued.ID, "", "")
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"status": "canceled"`) && !strings.Contains(resp.Body.String(), `"status":"canceled"`) {
+		t.Errorf("отменённый анализ не найден: %d %s", resp.Code, resp.Body)
+	}
+	if n := s.jobs.Len(); n != 1 {
+		t.Errorf("активных анализов %d, ожидался 1 (выполняемый)", n)
+	}
+}
+
+// runContract выполняет запросы и проверяет ответы по спецификации; возвращает покрытые операции
+func runContract(t *testing.T, spec map[string]interface{}, h http.Handler, cases []contractCase, ids map[string]string) map[string]bool {
+	t.Helper()
+	covered := make(map[string]bool)
+	for _, c := range cases {
+		path := c.path
+		if c.id != "" {
+			id, ok := ids[c.id]
+			if !ok {
+				id = c.id
+			}
+			path = strings.Replace(path, "{id}", id, 1)
+		}
+		target := "/api/v1" + path
+		if c.query != "" {
+			target += "?" + c.query
+		}
+		name := fmt.Sprintf("%s %s %d", c.method, target, c.status)
+		t.Run(name, func(t *testing.T) {
+			resp := do(t, h, c.method, target, c.body, c.key)
+			if resp.Code != c.status {
+				t.Fatalf("код %d, ожидался %d: %s", resp.Code, c.status, resp.Body)
+			}
+			checkResponse(t, spec, c.method, c.path, resp)
+		})
+		covered[strings.ToLower(c.method)+" "+c.path] = true
+	}
+	return covered
+}
+
+// checkCoverage требует хотя бы один запрос к каждой операции спецификации
+func checkCoverage(t *testing.T, spec map[string]interface{}, covered map[string]bool) {
+	t.Helper()
+	var missing []string
+	for path, item := range spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			if method == "parameters" {
+				continue
+			}
+			if !covered[method+" "+path] {
+				missing = append(missing, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("операции спецификации без проверок: %v", missing)
+	}
+}
+
+// checkResponse проверяет, что код ответа объявлен для операции, а тело соответствует схеме
+func checkResponse(t *testing.T, spec map[string]interface{}, method, path string, resp *httptest.ResponseRecorder) {
+	t.Helper()
+	item, ok := lookup(spec, "paths", path).(map[string]interface{})
+	if !ok {
+		t.Fatalf("путь %s не описан в спецификации", path)
+	}
+	op, ok := item[strings.ToLower(method)].(map[string]interface{})
+	if !ok {
+		t.Fatalf("операция %s %s не описана в спецификации", method, path)
+	}
+	response, ok := lookup(op, "responses", fmt.Sprint(resp.Code)).(map[string]interface{})
+	if !ok {
+		t.Fatalf("код %d не объявлен для %s %s: %s", resp.Code, method, path, resp.Body)
+	}
+	response = resolve(t, spec, response)
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header().Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("неверный Content-Type %q: %v", resp.Header().Get("Content-Type"), err)
+	}
+	media, ok := content[mediaType].(map[string]interface{})
+	if !ok {
+		if strings.HasPrefix(path, "/exports/") {
+			// Файлы результата бывают разных типов; спецификация описывает CSV как пример
+			return
+		}
+		t.Fatalf("Content-Type %s не объявлен для %s %s %d", mediaType, method, path, resp.Code)
+	}
+	schema, ok := media["schema"].(map[string]interface{})
+	if !ok || mediaType != "application/json" {
+		return
+	}
+	var body interface{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatalf("тело ответа не JSON: %v", err)
+	}
+	for _, problem := range validate(t, spec, schema, body, "$") {
+		t.Errorf("%s %s %d: %s", method, path, resp.Code, problem)
+	}
+}
+
+// validate проверяет значение по подмножеству JSON Schema, которое использует спецификация:
+// type, nullable, required, properties, items, enum, format date-time. Свойства, не описанные
+// в схеме со списком properties, считаются расхождением, если не разрешены additionalProperties.
+func validate(t *testing.T, spec, schema map[string]interface{}, value interface{}, at string) []string {
+	schema = resolve(t, spec, schema)
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{at + ": null"}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if allowed == value {
+				found = true
+			}
+		}
+		if !found {
+			return []string{fmt.Sprintf("%s: %v нет в enum %v", at, value, enum)}
+		}
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: ожидался объект, получено %T", at, value)}
+		}
+		for _, name := range asStrings(schema["required"]) {
+			if _, ok := obj[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: нет обязательного свойства %s", at, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, v := range obj {
+			prop, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if properties != nil && schema["additionalProperties"] != true {
+					problems = append(problems, fmt.Sprintf("%s: свойство %s не описано в схеме", at, name))
+				}
+				continue
+			}
+			problems = append(problems, validate(t, spec, prop, v, at+"."+name)...)
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: ожидался массив, получено %T", at, value)}
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, v := range list {
+			if items != nil {
+				problems = append(problems, validate(t, spec, items, v, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: ожидалась строка, получено %T", at, value)}
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q не date-time", at, s))
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return []string{fmt.Sprintf("%s: ожидалось целое, получено %v", at, value)}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s: ожидалось число, получено %T", at, value)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: ожидалось логическое значение, получено %T", at, value)}
+		}
+	}
+	return problems
+}
+
+// =================== Вспомогательные функции ===================
+
+func loadSpec(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var spec map[string]interface{}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	return spec
+}
+
+// resolve заменяет {"$ref": "#/components/..."} описанием из спецификации
+func resolve(t *testing.T, spec, node map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		target, ok := lookup(spec, strings.Split(strings.TrimPrefix(ref, "#/"), "/")...).(map[string]interface{})
+		if !ok {
+			t.Fatalf("ссылка %s не найдена в спецификации", ref)
+		}
+		node = target
+	}
+}
+
+func lookup(node interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[key]
+	}
+	return node
+}
+
+func asStrings(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		out = append(out, fmt.Sprint(item))
+	}
+	return out
+}
+
+// do выполняет запрос к обработчику сервера
+func do(t *testing.T, h http.Handler, method, target, body, key string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	return resp
+}
@@ -0,0 +1,231 @@
+// sermersys/server/openapi_types_test.go
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"sermersys/export"
+	"sermersys/model"
+	"sermersys/pipeline"
+	"sermersys/workspace"
+)
+
+// Сверка openapi.json с кодом: каждый маршрут API v1 описан в спецификации и наоборот,
+// а свойства схем совпадают с полями JSON типов запросов и ответов. Новое поле или маршрут
+// без описания в спецификации (или описание удалённого) роняет тест.
+
+// =================== Маршруты ===================
+
+func TestOpenAPIRoutes(t *testing.T) {
+	spec := loadSpec(t)
+	s := newTestServer(t, newFakeGoogle(t, nil), nil)
+
+	routes := make(map[string]bool)
+	for _, route := range s.apiv1Routes() {
+		path := strings.TrimPrefix(route.pattern, "/api/v1")
+		for method := range route.handlers {
+			routes[strings.ToLower(method)+" "+path] = true
+		}
+	}
+	documented := make(map[string]bool)
+	for path, item := range spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			if method != "parameters" {
+				documented[method+" "+path] = true
+			}
+		}
+	}
+	for _, op := range sortedKeys(routes) {
+		if !documented[op] {
+			t.Errorf("маршрут %s не описан в openapi.json", op)
+		}
+	}
+	for _, op := range sortedKeys(documented) {
+		if !routes[op] {
+			t.Errorf("операция %s из openapi.json не зарегистрирована", op)
+		}
+	}
+}
+
+// =================== Поля схем ===================
+
+func TestOpenAPISchemasMatchTypes(t *testing.T) {
+	spec := loadSpec(t)
+
+	var analysis Analysis
+	fill(reflect.ValueOf(&analysis).Elem())
+	var result pipeline.Result
+	fill(reflect.ValueOf(&result).Elem())
+	var ws workspace.Workspace
+	fill(reflect.ValueOf(&ws).Elem())
+
+	cases := map[string]interface{}{
+		"ErrorEnvelope":   filled[errorEnvelope](),
+		"APIError":        filled[APIError](),
+		"AnalysisRequest": filled[AnalysisRequest](),
+		"Analysis":        analysis,
+		"BatchRequest":    filled[batchRequest](),
+		"Batch":           filled[Batch](),
+		"MailStatus":      filled[MailStatus](),
+		"AnalysisList":    itemsResponse{Items: []Analysis{analysis}, Count: 1},
+		"AnalysisResult":  result,
+		"Place":           filled[model.Place](),
+		"PlaceList":       itemsResponse{Items: result.Places, Count: 1},
+		"Listing":         filled[model.Listing](),
+		"ListingList":     itemsResponse{Items: result.SearchResults, Count: 1},
+		"Review":          filled[Review](),
+		"ReviewList":      itemsResponse{Items: result.Reviews(), Count: 1},
+		"Export":          filled[Export](),
+		"ExportList":      itemsResponse{Items: analysis.Exports, Count: 1},
+		"Workspace":       ws.Public(),
+	}
+
+	// Строки потока JSON Lines формирует export.JSONLWriter
+	var stream bytes.Buffer
+	writer := export.NewJSONLWriter(&stream, "batch")
+	if err := writer.WriteResult(1, analysis.Request.RequestData, &result); err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(&stream)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		switch line["type"] {
+		case "property":
+			// error заполняется только у неудавшихся анализов
+			line["error"] = "x"
+			cases["JSONLProperty"] = line
+		case "listing":
+			cases["JSONLListing"] = line
+		}
+	}
+
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for name, schema := range schemas {
+		if schema.(map[string]interface{})["type"] != "object" {
+			continue
+		}
+		value, ok := cases[name]
+		if !ok {
+			t.Errorf("для схемы %s нет типа в тесте", name)
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		for _, problem := range compareProperties(t, spec, schema.(map[string]interface{}), decoded, name) {
+			t.Error(problem)
+		}
+	}
+}
+
+// compareProperties сравнивает свойства объектов схемы и JSON-значения в обе стороны
+func compareProperties(t *testing.T, spec, schema map[string]interface{}, value interface{}, at string) []string {
+	schema = resolve(t, spec, schema)
+	var problems []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if properties == nil {
+			return nil
+		}
+		for name, field := range v {
+			prop, ok := properties[name].(map[string]interface{})
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: поле не описано в openapi.json", at, name))
+				continue
+			}
+			problems = append(problems, compareProperties(t, spec, prop, field, at+"."+name)...)
+		}
+		for name := range properties {
+			if _, ok := v[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: свойства из openapi.json нет в типе", at, name))
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for _, item := range v {
+				problems = append(problems, compareProperties(t, spec, items, item, at+"[]")...)
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// filled возвращает значение типа T, в котором заполнены все поля (см. fill)
+func filled[T any]() T {
+	var v T
+	fill(reflect.ValueOf(&v).Elem())
+	return v
+}
+
+// fill заполняет значение ненулевыми данными, чтобы в JSON попали и поля с omitempty:
+// срезы и словари получают по одному элементу, указатели - заполненное значение
+func fill(v reflect.Value) {
+	switch v.Interface().(type) {
+	case time.Time:
+		v.Set(reflect.ValueOf(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.Pointer:
+		p := reflect.New(v.Type().Elem())
+		fill(p.Elem())
+		v.Set(p)
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 1, 1)
+		fill(s.Index(0))
+		v.Set(s)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if elem.Kind() == reflect.Interface {
+			elem.Set(reflect.ValueOf("x"))
+		} else {
+			fill(elem)
+		}
+		m.SetMapIndex(reflect.ValueOf("k").Convert(v.Type().Key()), elem)
+		v.Set(m)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i))
+			}
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,39 @@
+// sermersys/server/requestid.go
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"sermersys/logging"
+)
+
+type requestIDKey struct{}
+
+// validRequestID - допустимый ID, переданный клиентом в X-Request-ID
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// withRequestID присваивает запросу ID (берёт X-Request-ID клиента или создаёт новый)
+// и возвращает его в заголовке ответа
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(id) {
+			buf := make([]byte, 8)
+			rand.Read(buf)
+			id = hex.EncodeToString(buf)
+		}
+		w.Header().Set("X-Request-ID", id)
+		ctx := logging.With(context.WithValue(r.Context(), requestIDKey{}, id), "request_id", id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestID возвращает ID запроса из контекста
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
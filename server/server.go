@@ -0,0 +1,366 @@
+// sermersys/server/server.go
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"sermersys/auth"
+	"sermersys/config"
+	"sermersys/export"
+	"sermersys/googlesearch"
+	"sermersys/i18n"
+	"sermersys/metrics"
+	"sermersys/model"
+	"sermersys/pipeline"
+	"sermersys/workspace"
+)
+
+// Структура ответа API
+type APIResponse struct {
+	RefinedHotelName string                     `json:"refined_hotel_name"` // Добавлено уточнённое имя
+	RefinedAddress   string                     `json:"refined_address"`
+	SearchResults    []model.Listing            `json:"search_results"`
+	ExecutionSteps   []string                   `json:"execution_steps"`
+	Explanations     []googlesearch.Explanation `json:"explanations,omitempty"` // при "explain": true
+	ResultID         string                     `json:"result_id,omitempty"`    // ID CSV с результатами для /download
+	Filename         string                     `json:"filename,omitempty"`     // имя CSV без каталога
+	DownloadURL      string                     `json:"download_url,omitempty"`
+	ExplainResultID  string                     `json:"explain_result_id,omitempty"` // ID CSV с разбором
+	ExplainFilename  string                     `json:"explain_filename,omitempty"`
+	ExplainURL       string                     `json:"explain_download_url,omitempty"`
+	Exports          []Export                   `json:"exports,omitempty"` // все файлы результата, включая отчёты
+	Mail             *MailStatus                `json:"mail,omitempty"`    // отправка PDF-отчёта на email_to
+	Error            string                     `json:"error,omitempty"`
+}
+
+// Server - HTTP-сервер с веб-интерфейсом и API
+type Server struct {
+	cfg        *config.Config
+	keys       *auth.Store // nil, если авторизация выключена
+	workspaces *workspace.Store
+	jobs       *jobQueue   // асинхронные анализы API v1
+	draining   atomic.Bool // сервер останавливается, /readyz отвечает 503
+	stopUsage  func()      // останавливает запись статистики ключей и записывает её последний раз
+
+	mu             sync.Mutex
+	downloadStores map[string]*downloadStore // по ID рабочего пространства
+}
+
+// New создаёт сервер с заданной конфигурацией
+func New(cfg *config.Config) (*Server, error) {
+	workspaces, err := workspace.Open(cfg.WorkspacesFile)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{cfg: cfg, workspaces: workspaces, downloadStores: make(map[string]*downloadStore)}
+	if _, err := s.scopeFor(workspace.DefaultID); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.Enabled {
+		if s.keys, err = auth.Open(cfg.Auth.KeysFile); err != nil {
+			return nil, err
+		}
+		if s.keys.Empty() {
+			slog.Warn("авторизация включена, но нет ни одного ключа: создайте его командой sermersys keys create")
+		}
+		// Статистика ключей копится в памяти, чтобы запросы не ждали записи файла ключей
+		ctx, cancel := context.WithCancel(context.Background())
+		flushed := make(chan struct{})
+		go func() {
+			defer close(flushed)
+			s.keys.FlushEvery(ctx, usageFlushInterval)
+		}()
+		s.stopUsage = func() {
+			cancel()
+			<-flushed
+		}
+	}
+	s.jobs = newJobQueue(cfg.Jobs.Workers, cfg.Jobs.QueueSize)
+	return s, nil
+}
+
+// Close останавливает очередь анализов (незавершённые анализы отменяются)
+// и записывает статистику ключей
+func (s *Server) Close() {
+	s.jobs.Close()
+	if s.stopUsage != nil {
+		s.stopUsage()
+	}
+}
+
+// =================== API-Обработчик ===================
+func (s *Server) handler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	if r.Method != http.MethodPost {
+		s.fail(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "error.method_not_allowed", nil)
+		return
+	}
+
+	// Читаем JSON-запрос
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, "error.read_body", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	var requestData AnalysisRequest
+	err = json.Unmarshal(body, &requestData)
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, "error.invalid_json", nil)
+		return
+	}
+	r = withLocale(w, r, requestData.Locale)
+
+	// Каталоги платформ и шаблоны принадлежат пространству: клиент выбирает только из разрешённых
+	if !sc.cfg.AllowsPlatformsFile(requestData.PlatformsFile) {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, "error.platforms_not_allowed", nil)
+		return
+	}
+	if !sc.cfg.AllowsQueryTemplatesFile(requestData.QueryTemplatesFile) {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, "error.templates_not_allowed", nil)
+		return
+	}
+	if !s.checkRecipients(w, r, sc, &requestData) {
+		return
+	}
+	if _, ok := s.chargeAnalyses(w, r, 1); !ok {
+		return
+	}
+
+	// Запрос целиком не записывается: в журнал попадают только поля, нужные для разбора
+	slog.InfoContext(r.Context(), "получен запрос на анализ",
+		"object_name", requestData.ObjectName, "city", requestData.City, "country", requestData.Country,
+		"platforms_file", requestData.PlatformsFile, "explain", requestData.Explain)
+
+	result, err := pipeline.AnalyzeContext(r.Context(), sc.cfg, requestData.RequestData)
+	if errors.Is(err, context.Canceled) {
+		// Клиент отключился - отвечать некому, запросы к Google уже остановлены
+		slog.InfoContext(r.Context(), "анализ отменён: клиент закрыл соединение")
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		s.fail(w, r, http.StatusGatewayTimeout, CodeTimeout, "error.timeout", nil)
+		return
+	}
+	if errors.Is(err, pipeline.ErrNoPlaces) {
+		s.fail(w, r, http.StatusNotFound, CodeNoPlaces, "error.no_places_legacy", nil)
+		return
+	}
+	if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, CodeInternal, i18n.T(i18n.FromContext(r.Context()), "error.analysis", err), nil)
+		return
+	}
+
+	// Формируем финальный ответ
+	response := APIResponse{
+		RefinedHotelName: result.RefinedHotelName,
+		RefinedAddress:   result.RefinedAddress,
+		SearchResults:    result.SearchResults,
+		ExecutionSteps:   result.ExecutionSteps,
+		Explanations:     result.Explanations,
+	}
+	response.Exports = registerExports(r.Context(), sc, i18n.FromContext(r.Context()), result)
+	response.Mail = mailReport(r.Context(), sc, i18n.FromContext(r.Context()), result, requestData.EmailTo)
+	for _, e := range response.Exports {
+		switch e.Kind {
+		case "listings_csv":
+			response.ResultID, response.Filename, response.DownloadURL = e.ID, e.Filename, e.DownloadURL
+		case "explain_csv":
+			response.ExplainResultID, response.ExplainFilename, response.ExplainURL = e.ID, e.Filename, e.DownloadURL
+		}
+	}
+
+	// Отправляем JSON-ответ
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		slog.WarnContext(r.Context(), "ошибка при отправке ответа", "error", err)
+	}
+}
+
+// =================== Обработчик HTML ===================
+
+// indexPage - данные шаблона index.html
+type indexPage struct {
+	Lang      i18n.Lang
+	Languages []i18n.Lang
+	Messages  map[string]string // строки интерфейса для скриптов страницы
+}
+
+// Шаблон читается при каждом запросе, чтобы правки index.html применялись без перезапуска
+func (s *Server) homeHandler(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.FromContext(r.Context())
+	tmpl, err := template.New("index.html").Funcs(template.FuncMap{
+		"t": func(key string) string { return i18n.T(lang, key) },
+	}).ParseFiles("index.html")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "ошибка загрузки index.html", "error", err)
+		s.fail(w, r, http.StatusInternalServerError, CodeInternal, "error.internal", nil)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	page := indexPage{Lang: lang, Languages: i18n.Languages(), Messages: i18n.Strings(lang, "ui.")}
+	if err := tmpl.Execute(w, page); err != nil {
+		slog.WarnContext(r.Context(), "ошибка при отправке страницы", "error", err)
+	}
+}
+
+// =================== Обработчик скачивания ===================
+
+// Файлы отдаются только по ID, выданному при анализе в том же рабочем пространстве;
+// путь из запроса не используется
+func (s *Server) downloadHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	if r.URL.Query().Get("id") == "" {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, i18n.T(i18n.FromContext(r.Context()), "error.missing_parameter", "id"), nil)
+		return
+	}
+
+	filePath, err := sc.downloads.Resolve(r.URL.Query())
+	switch {
+	case errors.Is(err, errBadSignature), errors.Is(err, errLinkExpired):
+		s.fail(w, r, http.StatusForbidden, CodeForbidden, errorKey(err), nil)
+		return
+	case err != nil:
+		s.fail(w, r, http.StatusNotFound, CodeNotFound, "error.result_not_found", nil)
+		return
+	}
+
+	// Устанавливаем заголовки для скачивания
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(filePath)}))
+	w.Header().Set("Content-Type", export.ContentType(filePath))
+	http.ServeFile(w, r, filePath)
+}
+
+// requireDownloadKey пропускает подписанные ссылки без ключа API: подпись выдаётся только
+// авторизованному запросу, включает пространство (параметр ws) и проверяется в downloadStore.Resolve.
+// Без подписи нужен ключ с ролью read, и файл ищется в пространстве ключа.
+func (s *Server) requireDownloadKey(next func(w http.ResponseWriter, r *http.Request, sc *scope)) http.HandlerFunc {
+	withKey := s.requireKey(auth.RoleRead, false, s.withScope(next))
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.Downloads.SigningKey != "" && r.URL.Query().Get("sig") != "" {
+			sc, err := s.scopeFor(r.URL.Query().Get("ws"))
+			if err != nil {
+				s.fail(w, r, http.StatusNotFound, CodeNotFound, "error.result_not_found", nil)
+				return
+			}
+			next(w, r, sc)
+			return
+		}
+		withKey(w, r)
+	}
+}
+
+// registerDownload выдаёт ID для файла результата и возвращает ID, имя файла и ссылку
+func registerDownload(ctx context.Context, sc *scope, file string) (id, name, link string) {
+	id, err := sc.downloads.Register(file)
+	if err != nil {
+		slog.ErrorContext(ctx, "не удалось зарегистрировать файл для скачивания", "file", file, "error", err)
+		return "", filepath.Base(file), ""
+	}
+	return id, filepath.Base(file), sc.downloads.URL(id)
+}
+
+// =================== Обработчик отчёта о позициях ===================
+func (s *Server) serpReportHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	hotelName := r.URL.Query().Get("hotel_name")
+	if hotelName == "" {
+		s.fail(w, r, http.StatusBadRequest, CodeInvalidRequest, i18n.T(i18n.FromContext(r.Context()), "error.missing_parameter", "hotel_name"), map[string]interface{}{"parameter": "hotel_name"})
+		return
+	}
+	city := r.URL.Query().Get("city")
+
+	report, err := googlesearch.BuildSERPReport(sc.cfg, hotelName, city)
+	if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, CodeInternal, i18n.T(i18n.FromContext(r.Context()), "error.serp_report", err), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		slog.WarnContext(r.Context(), "ошибка при отправке ответа", "error", err)
+	}
+}
+
+// =================== Маршруты и запуск ===================
+
+// Handler регистрирует все маршруты сервера
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.homeHandler)                                                                   // Загружаем HTML-страницу
+	mux.HandleFunc("GET /healthz", s.healthzHandler)                                                     // Процесс жив
+	mux.HandleFunc("GET /readyz", s.readyzHandler)                                                       // Сервер готов выполнять анализы
+	mux.HandleFunc("/process", s.requireKey(auth.RoleRun, true, s.withScope(s.handler)))                 // API-обработчик
+	mux.HandleFunc("/download", s.requireDownloadKey(s.downloadHandler))                                 // Маршрут для скачивания
+	mux.HandleFunc("/serp-report", s.requireKey(auth.RoleRead, false, s.withScope(s.serpReportHandler))) // Динамика позиций платформ в выдаче
+	mux.HandleFunc("/workspace", s.requireKey(auth.RoleRead, false, s.withScope(s.workspaceHandler)))    // Каталоги и отслеживаемые объекты
+	s.registerAPIv1(mux)                                                                                 // Версионированный API
+	if s.cfg.Metrics.Enabled {
+		mux.HandleFunc("/metrics", s.requireKey(auth.RoleRead, false, metrics.Handler().ServeHTTP)) // Метрики Prometheus
+	}
+	return withRequestID(withLang(withTelemetry(mux)))
+}
+
+// ListenAndServe запускает сервер на порту из конфигурации и работает до отмены ctx
+// (например, по SIGTERM), после чего останавливается (см. shutdown)
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.cfg.Addr(),
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: s.cfg.Timeouts.Read.Std(),
+		ReadTimeout:       s.cfg.Timeouts.Read.Std(),
+		WriteTimeout:      s.cfg.Timeouts.WriteTimeout(),
+		IdleTimeout:       s.cfg.Timeouts.Idle.Std(),
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	slog.Info("сервер запущен", "addr", s.cfg.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	return s.shutdown(srv)
+}
+
+// shutdown перестаёт принимать соединения и ждёт завершения начатых запросов (в том числе
+// синхронных анализов /process) и анализов API v1 из очереди, чтобы файлы результатов
+// не остались записанными наполовину. Всё ограничено таймаутом cfg.Timeouts.Shutdown;
+// по его истечении соединения закрываются, а оставшиеся анализы отменяются.
+func (s *Server) shutdown(srv *http.Server) error {
+	s.draining.Store(true)
+	slog.Info("остановка сервера: ожидаем завершения запросов и анализов",
+		"timeout", s.cfg.Timeouts.Shutdown.Std().String(), "jobs", s.jobs.Len())
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeouts.Shutdown.Std())
+	defer cancel()
+
+	jobsErr := make(chan error, 1)
+	go func() {
+		jobsErr <- s.jobs.Shutdown(ctx)
+	}()
+	httpErr := srv.Shutdown(ctx)
+	if httpErr != nil {
+		srv.Close()
+		httpErr = fmt.Errorf("запросы не завершились при остановке: %w", httpErr)
+	}
+	err := errors.Join(httpErr, <-jobsErr)
+	if err == nil {
+		slog.Info("сервер остановлен")
+	}
+	return err
+}
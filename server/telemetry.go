@@ -0,0 +1,91 @@
+// sermersys/server/telemetry.go
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"sermersys/metrics"
+	"sermersys/tracing"
+)
+
+// statusRecorder запоминает код ответа для метрик и трассировки
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withTelemetry учитывает запросы в метриках по шаблону маршрута ServeMux, а не по пути,
+// чтобы ID анализов и файлов не порождали отдельные серии, открывает серверный спан
+// (продолжая трассировку клиента из заголовка traceparent) и записывает каждый запрос в журнал.
+// Оборачивает mux напрямую: шаблон записывается в r.Pattern того же запроса, который получает mux.
+func withTelemetry(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("request_id", RequestID(ctx)),
+			))
+		defer span.End()
+		r = r.WithContext(ctx)
+
+		rec := &statusRecorder{ResponseWriter: w}
+		mux.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		elapsed := time.Since(start)
+		metrics.HTTPRequests.Inc(route, r.Method, strconv.Itoa(rec.status))
+		metrics.HTTPDuration.Observe(elapsed.Seconds(), route)
+
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", rec.status))
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelWarn
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+		slog.LogAttrs(ctx, level, "HTTP-запрос",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", route),
+			slog.Int("status", rec.status),
+			slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000))
+	})
+}
@@ -0,0 +1,83 @@
+// sermersys/server/workspace.go
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"sermersys/auth"
+	"sermersys/config"
+	"sermersys/logging"
+	"sermersys/workspace"
+)
+
+// scope - рабочее пространство, в котором выполняется запрос
+type scope struct {
+	workspace *workspace.Workspace
+	cfg       *config.Config // конфигурация с ключами Google и каталогом результатов пространства
+	downloads *downloadStore
+}
+
+// scopeFor возвращает пространство по ID вместе с его конфигурацией и ссылками на скачивание
+func (s *Server) scopeFor(id string) (*scope, error) {
+	ws, err := s.workspaces.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	cfg := ws.Config(s.cfg)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	downloads, ok := s.downloadStores[ws.ID]
+	if !ok {
+		downloads, err = newDownloadStore(ws.ID, cfg.ResultsDir, cfg.Downloads.SigningKey, cfg.Downloads.LinkTTL.Std())
+		if err != nil {
+			return nil, err
+		}
+		s.downloadStores[ws.ID] = downloads
+		failInterruptedBatches(cfg.ResultsDir)
+	}
+	return &scope{workspace: ws, cfg: cfg, downloads: downloads}, nil
+}
+
+// requestScope определяет пространство запроса. При включённой авторизации оно задаётся
+// ключом API и не может быть изменено клиентом; без авторизации - заголовком X-Workspace.
+func (s *Server) requestScope(r *http.Request) (*scope, error) {
+	if s.keys != nil {
+		key := auth.FromContext(r.Context())
+		if key == nil {
+			return s.scopeFor(workspace.DefaultID)
+		}
+		return s.scopeFor(key.Workspace)
+	}
+	return s.scopeFor(r.Header.Get("X-Workspace"))
+}
+
+// withScope вызывает обработчик с пространством запроса
+func (s *Server) withScope(next func(w http.ResponseWriter, r *http.Request, sc *scope)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sc, err := s.requestScope(r)
+		if errors.Is(err, workspace.ErrNotFound) {
+			s.fail(w, r, http.StatusNotFound, CodeNotFound, errorKey(err), nil)
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "ошибка загрузки рабочего пространства", "error", err)
+			s.fail(w, r, http.StatusInternalServerError, CodeInternal, "error.workspace_load", nil)
+			return
+		}
+		next(w, r.WithContext(logging.With(r.Context(), "workspace", sc.workspace.ID)), sc)
+	}
+}
+
+// =================== Обработчик рабочего пространства ===================
+
+// workspaceHandler возвращает пространство запроса без ключей Google: каталоги и отслеживаемые объекты
+func (s *Server) workspaceHandler(w http.ResponseWriter, r *http.Request, sc *scope) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sc.workspace.Public()); err != nil {
+		slog.WarnContext(r.Context(), "ошибка при отправке ответа", "error", err)
+	}
+}
@@ -0,0 +1,145 @@
+// sermersys/tracing/tracing.go
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"sermersys/logging"
+)
+
+// Экспортёры трассировки
+const (
+	ExporterOff    = "off"    // спаны не создаются (по умолчанию)
+	ExporterOTLP   = "otlp"   // OTLP/HTTP на адрес коллектора
+	ExporterStdout = "stdout" // JSON в stderr для локальной отладки (stdout занят выводом CLI)
+)
+
+// ServiceName - имя сервиса в трассировке
+const ServiceName = "sermersys"
+
+// ValidExporter сообщает, поддерживается ли экспортёр
+func ValidExporter(exporter string) bool {
+	switch exporter {
+	case "", ExporterOff, ExporterOTLP, ExporterStdout:
+		return true
+	}
+	return false
+}
+
+var (
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+)
+
+// Setup включает трассировку с экспортёром exporter. endpoint - адрес OTLP/HTTP-коллектора
+// (например http://localhost:4318); если пуст, используются переменные OTEL_EXPORTER_OTLP_*.
+// sampleRatio - доля трассировок, которые записываются (0..1); входящий traceparent учитывается.
+func Setup(exporter, endpoint string, sampleRatio float64) error {
+	if exporter == "" || exporter == ExporterOff {
+		return nil
+	}
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		spanExporter, err = otlptracehttp.New(context.Background(), opts...)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	default:
+		return fmt.Errorf("неизвестный экспортёр трассировки %q, допустимые: off, otlp, stdout", exporter)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка создания экспортёра трассировки: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return fmt.Errorf("ошибка описания ресурса трассировки: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if provider != nil {
+		provider.Shutdown(context.Background())
+	}
+	provider = tp
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return nil
+}
+
+// Shutdown отправляет накопленные спаны и выключает трассировку
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider == nil {
+		return nil
+	}
+	err := provider.Shutdown(ctx)
+	provider = nil
+	return err
+}
+
+// Start начинает спан с именем name; без Setup возвращается спан, который ничего не записывает
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(ServiceName).Start(ctx, name, opts...)
+}
+
+// End завершает спан, отмечая ошибку err, если она есть
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// =================== Запросы к внешним API ===================
+
+// StartUpstream начинает клиентский спан запроса к внешнему API api; адрес записывается без секретов
+func StartUpstream(ctx context.Context, api string, u *url.URL) (context.Context, trace.Span) {
+	return Start(ctx, api, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("api", api),
+		attribute.String("http.request.method", "GET"),
+		attribute.String("url.full", logging.RedactURL(u)),
+	))
+}
+
+// EndUpstream завершает спан запроса к внешнему API: status - поле status ответа или HTTP-код
+// (как в метриках), httpStatus - HTTP-код (0, если ответ не получен). Статусы, отличные от OK,
+// ZERO_RESULTS и 200, отмечаются как ошибка.
+func EndUpstream(span trace.Span, status string, httpStatus int, err error) {
+	span.SetAttributes(attribute.String("upstream.status", status))
+	if httpStatus != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", httpStatus))
+	}
+	if err == nil && status != "OK" && status != "ZERO_RESULTS" && status != "200" {
+		span.SetStatus(codes.Error, status)
+	}
+	End(span, err)
+}
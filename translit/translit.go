@@ -0,0 +1,232 @@
+// sermersys/translit/translit.go
+package translit
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Scheme - схема транслитерации кириллицы в латиницу
+type Scheme string
+
+const (
+	GOST Scheme = "gost" // ГОСТ 7.79-2000, система Б (без диакритики)
+	ISO9 Scheme = "iso9" // ISO 9:1995 (ГОСТ 7.79-2000, система А), с диакритикой
+	ICAO Scheme = "icao" // ICAO Doc 9303, как в загранпаспортах и на OTA
+)
+
+// DefaultSchemes - схемы, которые пробуются, если в запросе не указаны другие
+var DefaultSchemes = []Scheme{ICAO, GOST, ISO9}
+
+// =================== Таблицы кириллицы ===================
+
+// common - буквы, которые одинаково передаются всеми схемами
+var common = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'з': "z",
+	'и': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	// украинский и белорусский
+	'і': "i", 'ґ': "g",
+}
+
+var cyrillicTables = map[Scheme]map[rune]string{
+	GOST: {
+		'ё': "yo", 'ж': "zh", 'й': "j", 'х': "x", 'ц': "cz", 'ч': "ch", 'ш': "sh",
+		'щ': "shh", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+		'є': "ye", 'ї': "yi", 'ў': "u",
+	},
+	ISO9: {
+		'ё': "ë", 'ж': "ž", 'й': "j", 'х': "h", 'ц': "c", 'ч': "č", 'ш': "š",
+		'щ': "ŝ", 'ъ': "", 'ы': "y", 'ь': "", 'э': "è", 'ю': "û", 'я': "â",
+		'є': "ê", 'ї': "ï", 'ў': "ŭ",
+	},
+	ICAO: {
+		'ё': "e", 'ж': "zh", 'й': "i", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+		'щ': "shch", 'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+		'є': "ie", 'ї': "i", 'ў': "u",
+	},
+}
+
+// =================== Таблицы греческого (ELOT 743 / ISO 843) ===================
+
+var greekDigraphs = []struct{ from, to string }{
+	{"ου", "ou"}, {"αυ", "av"}, {"ευ", "ev"}, {"ηυ", "iv"},
+	{"γγ", "ng"}, {"γκ", "gk"}, {"γξ", "nx"}, {"γχ", "nch"}, {"μπ", "mp"}, {"ντ", "nt"},
+}
+
+var greek = map[rune]string{
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+	'ά': "a", 'έ': "e", 'ή': "i", 'ί': "i", 'ϊ': "i", 'ΐ': "i", 'ό': "o", 'ύ': "y",
+	'ϋ': "y", 'ΰ': "y", 'ώ': "o",
+}
+
+var greekTonos = map[rune]rune{
+	'ά': 'α', 'έ': 'ε', 'ή': 'η', 'ί': 'ι', 'ό': 'ο', 'ύ': 'υ', 'ώ': 'ω',
+}
+
+// =================== Обратная транслитерация ===================
+
+// latinToCyrillic - практическая обратная схема; длинные сочетания идут первыми
+var latinToCyrillic = []struct{ from, to string }{
+	{"shch", "щ"}, {"zh", "ж"}, {"kh", "х"}, {"ts", "ц"}, {"ch", "ч"}, {"sh", "ш"},
+	{"yu", "ю"}, {"ya", "я"}, {"yo", "ё"}, {"ye", "е"},
+	{"a", "а"}, {"b", "б"}, {"c", "к"}, {"d", "д"}, {"e", "е"}, {"f", "ф"}, {"g", "г"},
+	{"h", "х"}, {"i", "и"}, {"j", "й"}, {"k", "к"}, {"l", "л"}, {"m", "м"}, {"n", "н"},
+	{"o", "о"}, {"p", "п"}, {"q", "к"}, {"r", "р"}, {"s", "с"}, {"t", "т"}, {"u", "у"},
+	{"v", "в"}, {"w", "в"}, {"x", "кс"}, {"y", "ы"}, {"z", "з"},
+}
+
+// =================== Функции ===================
+
+// ToLatin транслитерирует кириллицу по схеме scheme и греческий по ELOT 743.
+// Остальные символы остаются без изменений.
+func ToLatin(s string, scheme Scheme) string {
+	table, ok := cyrillicTables[scheme]
+	if !ok {
+		table = cyrillicTables[GOST]
+	}
+
+	var b strings.Builder
+	src := []rune(s)
+	for i := 0; i < len(src); i++ {
+		r := src[i]
+		lower := unicode.ToLower(r)
+		upper := r != lower
+
+		if i+1 < len(src) {
+			pair := string([]rune{stripTonos(lower), stripTonos(unicode.ToLower(src[i+1]))})
+			if to, ok := lookupDigraph(pair); ok {
+				writeCased(&b, to, upper, i+1 < len(src) && unicode.IsUpper(src[i+1]))
+				i++
+				continue
+			}
+		}
+
+		to, ok := table[lower]
+		if !ok {
+			to, ok = common[lower]
+		}
+		if !ok {
+			to, ok = greek[lower]
+		}
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		// ГОСТ: «ц» перед i, e, y, j передаётся как c
+		if scheme == GOST && lower == 'ц' && i+1 < len(src) && strings.ContainsRune("иеыйі", unicode.ToLower(src[i+1])) {
+			to = "c"
+		}
+		writeCased(&b, to, upper, i+1 < len(src) && unicode.IsUpper(src[i+1]))
+	}
+	return b.String()
+}
+
+// ToCyrillic выполняет приближённую обратную транслитерацию латиницы в кириллицу.
+// Регистр приводится по одной букве: strings.ToLower может изменить число рун (İ → i + U+0307).
+func ToCyrillic(s string) string {
+	var b strings.Builder
+	src := []rune(s)
+	for i := 0; i < len(src); {
+		matched := false
+		for _, m := range latinToCyrillic {
+			if n, ok := matchLower(src[i:], m.from); ok {
+				writeCased(&b, m.to, unicode.IsUpper(src[i]), n > 1 && unicode.IsUpper(src[i+1]))
+				i += n
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteRune(src[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// matchLower проверяет, начинается ли src с prefix без учёта регистра, и возвращает число совпавших рун
+func matchLower(src []rune, prefix string) (int, bool) {
+	n := 0
+	for _, want := range prefix {
+		if n >= len(src) || unicode.ToLower(src[n]) != want {
+			return 0, false
+		}
+		n++
+	}
+	return n, true
+}
+
+// LatinVariants возвращает различные латинские варианты строки по схемам (без исходной строки)
+func LatinVariants(s string, schemes ...Scheme) []string {
+	if len(schemes) == 0 {
+		schemes = DefaultSchemes
+	}
+	seen := map[string]bool{s: true}
+	var variants []string
+	for _, scheme := range schemes {
+		v := ToLatin(s, scheme)
+		if !seen[v] {
+			seen[v] = true
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+// HasNonLatin проверяет, есть ли в строке кириллица или греческие буквы
+func HasNonLatin(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Cyrillic, r) || unicode.Is(unicode.Greek, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSchemes преобразует названия схем; неизвестные названия пропускаются
+func ParseSchemes(names []string) []Scheme {
+	var schemes []Scheme
+	for _, name := range names {
+		scheme := Scheme(strings.ToLower(strings.TrimSpace(name)))
+		if _, ok := cyrillicTables[scheme]; ok {
+			schemes = append(schemes, scheme)
+		}
+	}
+	return schemes
+}
+
+// lookupDigraph ищет греческое буквосочетание
+func lookupDigraph(pair string) (string, bool) {
+	for _, d := range greekDigraphs {
+		if d.from == pair {
+			return d.to, true
+		}
+	}
+	return "", false
+}
+
+// stripTonos убирает ударение у греческой гласной для поиска буквосочетаний
+func stripTonos(r rune) rune {
+	if plain, ok := greekTonos[r]; ok {
+		return plain
+	}
+	return r
+}
+
+// writeCased записывает замену, сохраняя регистр исходной буквы
+func writeCased(b *strings.Builder, to string, upper, nextUpper bool) {
+	if to == "" || !upper {
+		b.WriteString(to)
+		return
+	}
+	if nextUpper {
+		b.WriteString(strings.ToUpper(to))
+		return
+	}
+	runes := []rune(to)
+	b.WriteString(string(unicode.ToUpper(runes[0])) + string(runes[1:]))
+}
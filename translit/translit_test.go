@@ -0,0 +1,74 @@
+// sermersys/translit/translit_test.go
+package translit
+
+import "testing"
+
+func TestToCyrillic(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"Moskva", "Москва"},
+		{"SHCHUKA", "ЩУКА"},
+		{"Shchuka", "Щука"},
+		{"ZhUK", "ЖУК"},
+		{"sHeremetyevo", "шереметево"},
+		{"Hotel 7", "Хотел 7"},
+		// İ при strings.ToLower превращается в две руны; раньше здесь был выход за границы среза
+		{"İstanbul Palace", "Истанбул Палаке"},
+		{"Kİ", "КИ"},
+		{"SHİ", "ШИ"},
+		{"İ", "И"},
+	}
+	for _, c := range cases {
+		if got := ToCyrillic(c.in); got != c.want {
+			t.Errorf("ToCyrillic(%q) = %q, ожидалось %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestToLatin(t *testing.T) {
+	cases := []struct {
+		in     string
+		scheme Scheme
+		want   string
+	}{
+		{"Щука", GOST, "Shhuka"},
+		{"Щука", ICAO, "Shchuka"},
+		{"Щука", ISO9, "Ŝuka"},
+		{"ЖУК", ICAO, "ZHUK"},
+		{"Цирк", GOST, "Cirk"},
+		{"Царь", GOST, "Czar"},
+		{"Хотел Ядран", ICAO, "Khotel Iadran"},
+		{"Μπαλκόνι", GOST, "Mpalkoni"},
+		{"Αθήνα", GOST, "Athina"},
+		{"Hotel 7", GOST, "Hotel 7"},
+	}
+	for _, c := range cases {
+		if got := ToLatin(c.in, c.scheme); got != c.want {
+			t.Errorf("ToLatin(%q, %s) = %q, ожидалось %q", c.in, c.scheme, got, c.want)
+		}
+	}
+}
+
+func TestLatinVariants(t *testing.T) {
+	got := LatinVariants("Щука")
+	want := []string{"Shchuka", "Shhuka", "Ŝuka"}
+	if len(got) != len(want) {
+		t.Fatalf("LatinVariants = %q, ожидалось %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("LatinVariants = %q, ожидалось %q", got, want)
+		}
+	}
+	if v := LatinVariants("Hotel"); len(v) != 0 {
+		t.Errorf("для латиницы вариантов быть не должно: %q", v)
+	}
+}
+
+func TestParseSchemes(t *testing.T) {
+	got := ParseSchemes([]string{" ICAO ", "gost", "unknown"})
+	if len(got) != 2 || got[0] != ICAO || got[1] != GOST {
+		t.Errorf("ParseSchemes = %v", got)
+	}
+}
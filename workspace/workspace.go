@@ -0,0 +1,352 @@
+// sermersys/workspace/workspace.go
+package workspace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"sermersys/config"
+)
+
+// DefaultID - рабочее пространство, в котором работают ключи без привязки и CLI без -workspace.
+// Оно использует конфигурацию сервиса как есть.
+const DefaultID = "default"
+
+// workspacesDir - подкаталог results_dir, в котором лежат результаты рабочих пространств
+const workspacesDir = "workspaces"
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+var ErrNotFound = errors.New("рабочее пространство не найдено")
+
+// Workspace - рабочее пространство клиента агентства: свои ключи Google, каталоги платформ,
+// отслеживаемые объекты и результаты. Ключи HTTP API привязываются к нему через auth.Key.Workspace.
+type Workspace struct {
+	ID                 string     `json:"id"`
+	Name               string     `json:"name"`
+	GoogleAPIKey       string     `json:"google_api_key,omitempty"`
+	GoogleCX           string     `json:"google_cx,omitempty"`
+	InheritGoogleKeys  bool       `json:"inherit_google_keys,omitempty"` // работать на ключах Google сервиса вместо своих
+	PlatformsFile      string     `json:"platforms_file,omitempty"`      // каталог платформ по умолчанию
+	PlatformFiles      []string   `json:"platform_files,omitempty"`      // другие каталоги, которые можно выбрать в запросе
+	QueryTemplatesFile string     `json:"query_templates_file,omitempty"`
+	Properties         []Property `json:"properties,omitempty"` // отслеживаемые объекты
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// Property - отслеживаемый объект рабочего пространства
+type Property struct {
+	ID            string `json:"id"`
+	ObjectName    string `json:"object_name"`
+	Address       string `json:"address,omitempty"`
+	City          string `json:"city"`
+	Country       string `json:"country,omitempty"`
+	Language      string `json:"language,omitempty"`
+	PlatformsFile string `json:"platforms_file,omitempty"`
+	// Файл шаблонов запросов; пусто или файл пространства
+	QueryTemplatesFile string `json:"query_templates_file,omitempty"`
+}
+
+// Config возвращает конфигурацию для работы в рабочем пространстве: каталоги и шаблоны берутся
+// из пространства (если заданы), результаты пишутся в его собственный каталог. Ключи Google -
+// только собственные: ключи сервиса используются лишь при явном inherit_google_keys, чтобы
+// запросы клиентов не расходовали общую квоту незаметно.
+func (w *Workspace) Config(base *config.Config) *config.Config {
+	cfg := *base
+	if w.ID == DefaultID {
+		return &cfg
+	}
+	if !w.InheritGoogleKeys {
+		cfg.GoogleAPIKey = w.GoogleAPIKey
+		cfg.GoogleCX = w.GoogleCX
+	}
+	if w.PlatformsFile != "" {
+		cfg.PlatformsFile = w.PlatformsFile
+	}
+	cfg.PlatformFiles = append([]string(nil), w.PlatformFiles...)
+	if w.QueryTemplatesFile != "" {
+		cfg.QueryTemplatesFile = w.QueryTemplatesFile
+	}
+	// В пространстве шаблоны - только его собственные, другие файлы сервиса выбрать нельзя
+	cfg.QueryTemplateFiles = nil
+	cfg.ResultsDir = filepath.Join(base.ResultsDir, workspacesDir, w.ID)
+	return &cfg
+}
+
+// AllowsPlatformsFile сообщает, можно ли выбрать каталог платформ для объекта пространства.
+// Пустое значение означает каталог по умолчанию. Запросы к HTTP API проверяются по
+// конфигурации пространства (config.Config.AllowsPlatformsFile), в том числе в пространстве по умолчанию.
+func (w *Workspace) AllowsPlatformsFile(file string) bool {
+	return file == "" || config.AllowedFile(file, w.PlatformsFile, w.PlatformFiles)
+}
+
+// AllowsQueryTemplatesFile - как AllowsPlatformsFile, для файла шаблонов: в пространстве
+// можно выбрать только его собственный файл
+func (w *Workspace) AllowsQueryTemplatesFile(file string) bool {
+	return file == "" || config.AllowedFile(file, w.QueryTemplatesFile, nil)
+}
+
+// validate проверяет ключи Google пространства: свои или явно унаследованные от сервиса
+func (w *Workspace) validate() error {
+	switch {
+	case w.InheritGoogleKeys && (w.GoogleAPIKey != "" || w.GoogleCX != ""):
+		return fmt.Errorf("inherit_google_keys нельзя сочетать с собственными google_api_key и google_cx")
+	case w.InheritGoogleKeys:
+		return nil
+	case w.GoogleAPIKey == "":
+		return fmt.Errorf("пространству %s нужен свой google_api_key (или явный inherit_google_keys)", w.ID)
+	}
+	return nil
+}
+
+// Public возвращает копию пространства без ключей Google для ответов API
+func (w *Workspace) Public() *Workspace {
+	c := w.clone()
+	c.GoogleAPIKey = ""
+	c.GoogleCX = ""
+	return c
+}
+
+func (w *Workspace) clone() *Workspace {
+	c := *w
+	c.PlatformFiles = append([]string(nil), w.PlatformFiles...)
+	c.Properties = append([]Property(nil), w.Properties...)
+	return &c
+}
+
+// =================== Хранилище ===================
+
+// Store - рабочие пространства в JSON-файле
+type Store struct {
+	path string
+
+	mu         sync.Mutex
+	workspaces map[string]*Workspace
+	modTime    time.Time
+}
+
+// Open загружает пространства из файла; отсутствующий файл означает, что есть только пространство по умолчанию
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, workspaces: make(map[string]*Workspace)}
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get возвращает пространство по ID; пустой ID и DefaultID означают пространство по умолчанию
+func (s *Store) Get(id string) (*Workspace, error) {
+	if id == "" || id == DefaultID {
+		return &Workspace{ID: DefaultID, Name: "Default"}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refreshLocked(); err != nil {
+		return nil, err
+	}
+	w, ok := s.workspaces[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return w.clone(), nil
+}
+
+// List возвращает пространства, упорядоченные по ID
+func (s *Store) List() []*Workspace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]*Workspace, 0, len(s.workspaces))
+	for _, w := range s.workspaces {
+		list = append(list, w.clone())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Create добавляет пространство
+func (s *Store) Create(w Workspace) (*Workspace, error) {
+	if !idPattern.MatchString(w.ID) || w.ID == DefaultID {
+		return nil, fmt.Errorf("недопустимый ID %q: строчные латинские буквы, цифры, - и _", w.ID)
+	}
+	if err := w.validate(); err != nil {
+		return nil, err
+	}
+	if w.Name == "" {
+		w.Name = w.ID
+	}
+	w.CreatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refreshLocked(); err != nil {
+		return nil, err
+	}
+	if _, exists := s.workspaces[w.ID]; exists {
+		return nil, fmt.Errorf("рабочее пространство %s уже существует", w.ID)
+	}
+	s.workspaces[w.ID] = &w
+	if err := s.saveLocked(); err != nil {
+		delete(s.workspaces, w.ID)
+		return nil, err
+	}
+	return w.clone(), nil
+}
+
+// Update применяет change к пространству и сохраняет результат
+func (s *Store) Update(id string, change func(*Workspace) error) (*Workspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refreshLocked(); err != nil {
+		return nil, err
+	}
+	w, ok := s.workspaces[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	updated := w.clone()
+	if err := change(updated); err != nil {
+		return nil, err
+	}
+	if err := updated.validate(); err != nil {
+		return nil, err
+	}
+	s.workspaces[id] = updated
+	if err := s.saveLocked(); err != nil {
+		s.workspaces[id] = w
+		return nil, err
+	}
+	return updated.clone(), nil
+}
+
+// Delete удаляет пространство. Файлы результатов остаются на диске.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refreshLocked(); err != nil {
+		return err
+	}
+	w, ok := s.workspaces[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	delete(s.workspaces, id)
+	if err := s.saveLocked(); err != nil {
+		s.workspaces[id] = w
+		return err
+	}
+	return nil
+}
+
+// AddProperty добавляет отслеживаемый объект и возвращает его ID
+func AddProperty(w *Workspace, p Property) (Property, error) {
+	if p.ObjectName == "" || p.City == "" {
+		return Property{}, fmt.Errorf("для объекта нужны название и город")
+	}
+	if !w.AllowsPlatformsFile(p.PlatformsFile) {
+		return Property{}, fmt.Errorf("каталог платформ %s не разрешён в пространстве %s", p.PlatformsFile, w.ID)
+	}
+	if !w.AllowsQueryTemplatesFile(p.QueryTemplatesFile) {
+		return Property{}, fmt.Errorf("файл шаблонов %s не разрешён в пространстве %s", p.QueryTemplatesFile, w.ID)
+	}
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return Property{}, fmt.Errorf("ошибка генерации ID: %v", err)
+	}
+	p.ID = hex.EncodeToString(buf)
+	w.Properties = append(w.Properties, p)
+	return p, nil
+}
+
+// RemoveProperty удаляет отслеживаемый объект по ID
+func RemoveProperty(w *Workspace, id string) error {
+	for i, p := range w.Properties {
+		if p.ID == id {
+			w.Properties = append(w.Properties[:i], w.Properties[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("объект %s не найден в пространстве %s", id, w.ID)
+}
+
+// Check проверяет, что файл рабочих пространств доступен и читается (для проверки готовности сервера)
+func (s *Store) Check() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := os.Stat(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("файл рабочих пространств недоступен: %v", err)
+	}
+	return s.refreshLocked()
+}
+
+// loadLocked перечитывает файл; вызывается под s.mu (или до начала использования)
+func (s *Store) loadLocked() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла рабочих пространств: %v", err)
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла рабочих пространств: %v", err)
+	}
+	var list []*Workspace
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("ошибка парсинга файла рабочих пространств %s: %v", s.path, err)
+	}
+	s.workspaces = make(map[string]*Workspace, len(list))
+	for _, w := range list {
+		s.workspaces[w.ID] = w
+	}
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// refreshLocked перечитывает файл, если его изменил другой процесс; вызывается под s.mu
+func (s *Store) refreshLocked() error {
+	info, err := os.Stat(s.path)
+	if err != nil || info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+	return s.loadLocked()
+}
+
+// saveLocked записывает пространства в файл; вызывается под s.mu
+func (s *Store) saveLocked() error {
+	list := make([]*Workspace, 0, len(s.workspaces))
+	for _, w := range s.workspaces {
+		list = append(list, w)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return fmt.Errorf("ошибка создания каталога файла рабочих пространств: %v", err)
+		}
+	}
+	// Файл содержит ключи Google клиентов - доступен только владельцу
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("ошибка записи файла рабочих пространств: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("ошибка записи файла рабочих пространств: %v", err)
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
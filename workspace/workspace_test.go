@@ -0,0 +1,196 @@
+// sermersys/workspace/workspace_test.go
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sermersys/config"
+)
+
+func testBase() *config.Config {
+	cfg := config.Default()
+	cfg.GoogleAPIKey = "service-key"
+	cfg.GoogleCX = "service-cx"
+	cfg.ResultsDir = "/srv/results"
+	cfg.PlatformFiles = []string{"catalogs/service.txt"}
+	cfg.QueryTemplateFiles = []string{"templates/service.json"}
+	return cfg
+}
+
+func TestConfigGoogleKeys(t *testing.T) {
+	base := testBase()
+	cases := []struct {
+		name    string
+		ws      Workspace
+		key, cx string
+	}{
+		{"свои ключи", Workspace{ID: "acme", GoogleAPIKey: "acme-key", GoogleCX: "acme-cx"}, "acme-key", "acme-cx"},
+		{"свой ключ без CX", Workspace{ID: "acme", GoogleAPIKey: "acme-key"}, "acme-key", ""},
+		{"без ключей ключи сервиса не подставляются", Workspace{ID: "acme"}, "", ""},
+		{"явное наследование", Workspace{ID: "acme", InheritGoogleKeys: true}, "service-key", "service-cx"},
+		{"пространство по умолчанию", Workspace{ID: DefaultID}, "service-key", "service-cx"},
+	}
+	for _, c := range cases {
+		cfg := c.ws.Config(base)
+		if cfg.GoogleAPIKey != c.key || cfg.GoogleCX != c.cx {
+			t.Errorf("%s: ключ %q, CX %q; ожидалось %q, %q", c.name, cfg.GoogleAPIKey, cfg.GoogleCX, c.key, c.cx)
+		}
+	}
+}
+
+func TestConfigCatalogsAndResults(t *testing.T) {
+	base := testBase()
+	ws := Workspace{ID: "acme", GoogleAPIKey: "k", PlatformsFile: "catalogs/acme.txt", QueryTemplatesFile: "templates/acme.json"}
+	cfg := ws.Config(base)
+	if cfg.PlatformsFile != "catalogs/acme.txt" || len(cfg.PlatformFiles) != 0 {
+		t.Errorf("каталоги: %q %q", cfg.PlatformsFile, cfg.PlatformFiles)
+	}
+	if cfg.QueryTemplatesFile != "templates/acme.json" || cfg.QueryTemplateFiles != nil {
+		t.Errorf("шаблоны: %q %q", cfg.QueryTemplatesFile, cfg.QueryTemplateFiles)
+	}
+	if cfg.ResultsDir != filepath.Join("/srv/results", "workspaces", "acme") {
+		t.Errorf("каталог результатов %q", cfg.ResultsDir)
+	}
+	if base.ResultsDir != "/srv/results" || base.PlatformFiles[0] != "catalogs/service.txt" {
+		t.Errorf("конфигурация сервиса изменена: %+v", base)
+	}
+	if cfg.AllowsPlatformsFile("catalogs/service.txt") || cfg.AllowsQueryTemplatesFile("templates/service.json") {
+		t.Errorf("в пространстве доступны файлы сервиса")
+	}
+}
+
+func TestAllowsFiles(t *testing.T) {
+	ws := Workspace{ID: "acme", PlatformsFile: "catalog.json", PlatformFiles: []string{"catalogs/cafes.txt"}, QueryTemplatesFile: "templates/acme.json"}
+	cases := []struct {
+		file      string
+		platforms bool
+		templates bool
+	}{
+		{"", true, true},
+		{"catalog.json", true, false},
+		{"./catalog.json", true, false},
+		{"catalogs/../catalog.json", true, false},
+		{"catalogs/cafes.txt", true, false},
+		{"templates/acme.json", false, true},
+		{"./templates/acme.json", false, true},
+		{"/etc/passwd", false, false},
+		{"../catalog.json", false, false},
+	}
+	for _, c := range cases {
+		if got := ws.AllowsPlatformsFile(c.file); got != c.platforms {
+			t.Errorf("AllowsPlatformsFile(%q) = %v", c.file, got)
+		}
+		if got := ws.AllowsQueryTemplatesFile(c.file); got != c.templates {
+			t.Errorf("AllowsQueryTemplatesFile(%q) = %v", c.file, got)
+		}
+	}
+	// Проверка объекта пространства совпадает с проверкой запросов по его конфигурации
+	cfg := (&Workspace{ID: "acme", InheritGoogleKeys: true, PlatformsFile: "catalog.json"}).Config(testBase())
+	if !cfg.AllowsPlatformsFile("./catalog.json") {
+		t.Errorf("конфигурация пространства не принимает ./catalog.json")
+	}
+}
+
+func TestAddProperty(t *testing.T) {
+	ws := &Workspace{ID: "acme", PlatformsFile: "catalog.json", QueryTemplatesFile: "templates/acme.json"}
+	if _, err := AddProperty(ws, Property{ObjectName: "Hotel Adriatic"}); err == nil {
+		t.Errorf("объект без города принят")
+	}
+	if _, err := AddProperty(ws, Property{ObjectName: "Hotel Adriatic", City: "Budva", PlatformsFile: "/etc/passwd"}); err == nil {
+		t.Errorf("чужой каталог платформ принят")
+	}
+	if _, err := AddProperty(ws, Property{ObjectName: "Hotel Adriatic", City: "Budva", QueryTemplatesFile: "/etc/passwd"}); err == nil {
+		t.Errorf("чужой файл шаблонов принят")
+	}
+	p, err := AddProperty(ws, Property{ObjectName: "Hotel Adriatic", City: "Budva", PlatformsFile: "./catalog.json", QueryTemplatesFile: "templates/acme.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.ID) != 8 || len(ws.Properties) != 1 {
+		t.Errorf("объект %+v, всего %d", p, len(ws.Properties))
+	}
+	if err := RemoveProperty(ws, "missing"); err == nil {
+		t.Errorf("удалён несуществующий объект")
+	}
+	if err := RemoveProperty(ws, p.ID); err != nil || len(ws.Properties) != 0 {
+		t.Errorf("RemoveProperty: %v, осталось %d", err, len(ws.Properties))
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ws", "workspaces.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Create(Workspace{ID: "Bad ID", GoogleAPIKey: "k"}); err == nil {
+		t.Errorf("недопустимый ID принят")
+	}
+	if _, err := store.Create(Workspace{ID: DefaultID, GoogleAPIKey: "k"}); err == nil {
+		t.Errorf("пространство default создано")
+	}
+	if _, err := store.Create(Workspace{ID: "acme"}); err == nil || !strings.Contains(err.Error(), "google_api_key") {
+		t.Errorf("пространство без ключей принято: %v", err)
+	}
+	if _, err := store.Create(Workspace{ID: "acme", InheritGoogleKeys: true, GoogleAPIKey: "k"}); err == nil {
+		t.Errorf("inherit_google_keys вместе со своим ключом принят")
+	}
+	created, err := store.Create(Workspace{ID: "acme", GoogleAPIKey: "acme-key", GoogleCX: "acme-cx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Name != "acme" || created.CreatedAt.IsZero() {
+		t.Errorf("создано %+v", created)
+	}
+	if _, err := store.Create(Workspace{ID: "acme", GoogleAPIKey: "k"}); err == nil {
+		t.Errorf("повторное создание принято")
+	}
+	if _, err := store.Create(Workspace{ID: "shared", InheritGoogleKeys: true}); err != nil {
+		t.Errorf("пространство с явным наследованием ключей: %v", err)
+	}
+
+	// Файл с ключами Google клиентов доступен только владельцу
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("права файла: %v, %v", info.Mode().Perm(), err)
+	}
+
+	if _, err := store.Update("acme", func(w *Workspace) error { w.GoogleAPIKey = ""; return nil }); err == nil {
+		t.Errorf("обновление, удаляющее ключ, принято")
+	}
+	if w, _ := store.Get("acme"); w.GoogleAPIKey != "acme-key" {
+		t.Errorf("отклонённое обновление изменило пространство: %+v", w)
+	}
+	if _, err := store.Update("missing", func(w *Workspace) error { return nil }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update несуществующего: %v", err)
+	}
+
+	// Другой процесс видит изменения после перечитывания файла
+	other, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := other.List(); len(list) != 2 || list[0].ID != "acme" || list[1].ID != "shared" {
+		t.Errorf("List: %+v", list)
+	}
+	if w, err := other.Get(""); err != nil || w.ID != DefaultID {
+		t.Errorf("Get пространства по умолчанию: %+v, %v", w, err)
+	}
+	if public := created.Public(); public.GoogleAPIKey != "" || public.GoogleCX != "" || created.GoogleAPIKey == "" {
+		t.Errorf("Public: %+v", public)
+	}
+
+	if err := store.Delete("acme"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("acme"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("удалённое пространство найдено: %v", err)
+	}
+	if err := store.Check(); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+}